@@ -1,15 +1,20 @@
 package main
 
 import (
+	"YrestAPI/internal/auth"
 	"YrestAPI/internal/config"
 	"YrestAPI/internal/db"
 	"YrestAPI/internal/logger"
 	"YrestAPI/internal/model"
+	"YrestAPI/internal/resolver"
 	"YrestAPI/internal/router"
 	"flag"
 	"log"
 	"net/http"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"fmt"
 	"os"
@@ -52,6 +57,20 @@ func main() {
 		startupFatal("registry_init_failed", err)
 	}
 	model.SetAliasCacheMaxBytes(cfg.AliasCache.MaxBytes)
+	resolver.SetStreamChunkSize(cfg.Stream.ChunkSize)
+	resolver.SetCostBudget(resolver.CostBudget{
+		MaxJoins:    int(cfg.QueryCost.MaxJoins),
+		MaxTails:    int(cfg.QueryCost.MaxTails),
+		MaxDepth:    int(cfg.QueryCost.MaxDepth),
+		MaxFilters:  int(cfg.QueryCost.MaxFilters),
+		MaxLimit:    uint64(max(cfg.QueryCost.MaxLimit, 0)),
+		MaxPlanCost: cfg.QueryCost.MaxPlanCost,
+	})
+	resolver.SetQueryTimeout(time.Duration(cfg.Query.TimeoutMS) * time.Millisecond)
+	resolver.SetTailConcurrency(int(cfg.Query.TailConcurrency), int(cfg.Query.TailGlobalConcurrency))
+	resolver.SetTailChunkSize(int(cfg.Query.TailChunkSize))
+	db.SetSnapshotConcurrency(int(cfg.Query.SnapshotConcurrency))
+	auth.SetRolesClaim(cfg.Auth.RolesClaim)
 	logger.Info("models_initialized", nil)
 	// Load locales if available
 	// This is optional, so we handle errors gracefully
@@ -67,6 +86,8 @@ func main() {
 		logger.Error("router_init_failed", map[string]any{"error": err.Error()})
 		startupFatal("router_init_failed", err)
 	}
+	// Hot reload: SIGHUP and optional polling of MODELS_DIR
+	watchRegistryReload(cfg.ModelsDir, time.Duration(cfg.Reload.WatchIntervalSec)*time.Second)
 	// Start HTTP server
 	logger.Info("server_start", map[string]any{"port": cfg.Port})
 	log.Printf("🚀 Starting server on port %s", cfg.Port)
@@ -76,6 +97,26 @@ func main() {
 	}
 }
 
+// watchRegistryReload пересобирает реестр по SIGHUP и, если interval > 0,
+// при изменении YAML-файлов. По SIGHUP также перечитываются JWKS.
+// Ошибка сборки не останавливает сервис.
+func watchRegistryReload(dir string, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			logger.Info("registry_reload_signal", map[string]any{"dir": dir})
+			_ = model.ReloadRegistry(dir)
+			auth.ReloadKeySets()
+			auth.ReloadAPIKeys()
+		}
+	}()
+	if interval > 0 {
+		logger.Info("models_watch_started", map[string]any{"dir": dir, "interval": interval.String()})
+		go model.WatchModelsDir(dir, interval, nil)
+	}
+}
+
 func resolveModelsDir(configured string) string {
 	configured = filepath.Clean(configured)
 	if _, explicit := os.LookupEnv("MODELS_DIR"); explicit {
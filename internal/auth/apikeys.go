@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIKey — запись о ключе. Хранится только SHA-256 хеш ключа; Claims
+// кладутся в контекст запроса так же, как claims JWT.
+//
+//	keys:
+//	  - id: nightly-export
+//	    hash: sha256:<hex>
+//	    expires_at: 2027-01-01T00:00:00Z
+//	    claims:
+//	      sub: batch-export
+//	      roles: [reporter]
+//	      tenant: acme
+type APIKey struct {
+	ID        string         `yaml:"id"`
+	Hash      string         `yaml:"hash"`
+	Enabled   *bool          `yaml:"enabled"` // по умолчанию true
+	ExpiresAt *time.Time     `yaml:"expires_at"`
+	Claims    map[string]any `yaml:"claims"`
+}
+
+// APIKeyStore — ключи из YAML-файла и/или таблицы PostgreSQL, индексированные
+// по хешу. Reload подменяет набор целиком, при ошибке оставляя прежний.
+type APIKeyStore struct {
+	path      string
+	table     string
+	clockFunc func() time.Time
+
+	mu     sync.RWMutex
+	byHash map[string]*APIKey
+}
+
+var (
+	apiKeyStoresMu sync.Mutex
+	apiKeyStores   []*APIKeyStore
+)
+
+var tableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// HashAPIKey возвращает хеш ключа в формате, который хранится в файле и таблице.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKeyStore загружает ключи из path и/или table. Таблица читается через
+// db.Pool, поэтому store создаётся после подключения к PostgreSQL.
+func NewAPIKeyStore(path, table string) (*APIKeyStore, error) {
+	s := &APIKeyStore{
+		path:      strings.TrimSpace(path),
+		table:     strings.TrimSpace(table),
+		clockFunc: time.Now,
+	}
+	if s.path == "" && s.table == "" {
+		return nil, errors.New("api keys path or table is required")
+	}
+	if s.table != "" && !tableNameRe.MatchString(s.table) {
+		return nil, fmt.Errorf("invalid api keys table name: %s", s.table)
+	}
+	if err := s.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	apiKeyStoresMu.Lock()
+	apiKeyStores = append(apiKeyStores, s)
+	apiKeyStoresMu.Unlock()
+	return s, nil
+}
+
+// Reload перечитывает ключи. При ошибке текущие ключи сохраняются.
+func (s *APIKeyStore) Reload(ctx context.Context) error {
+	var keys []APIKey
+	if s.path != "" {
+		fromFile, err := loadAPIKeysFile(s.path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, fromFile...)
+	}
+	if s.table != "" {
+		fromTable, err := loadAPIKeysTable(ctx, s.table)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, fromTable...)
+	}
+
+	byHash := make(map[string]*APIKey, len(keys))
+	for i := range keys {
+		k := &keys[i]
+		hash := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(k.Hash), "sha256:"))
+		if len(hash) != sha256.Size*2 {
+			return fmt.Errorf("api key %q: hash must be a hex sha256 digest", k.ID)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return fmt.Errorf("api key %q: hash must be a hex sha256 digest", k.ID)
+		}
+		if _, dup := byHash[hash]; dup {
+			return fmt.Errorf("api key %q: duplicate hash", k.ID)
+		}
+		byHash[hash] = k
+	}
+
+	s.mu.Lock()
+	s.byHash = byHash
+	s.mu.Unlock()
+	return nil
+}
+
+// Authenticate проверяет ключ и возвращает копию его claims. Если в claims
+// нет "sub", подставляется "apikey:<id>".
+func (s *APIKeyStore) Authenticate(key string) (map[string]any, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, errors.New("api key is empty")
+	}
+	s.mu.RLock()
+	k := s.byHash[HashAPIKey(key)]
+	s.mu.RUnlock()
+	if k == nil {
+		return nil, errors.New("unknown api key")
+	}
+	if k.Enabled != nil && !*k.Enabled {
+		return nil, fmt.Errorf("api key %s is disabled", k.ID)
+	}
+	if k.ExpiresAt != nil && !s.clockFunc().Before(*k.ExpiresAt) {
+		return nil, fmt.Errorf("api key %s is expired", k.ID)
+	}
+
+	claims := make(map[string]any, len(k.Claims)+1)
+	for name, v := range k.Claims {
+		claims[name] = v
+	}
+	if _, ok := claims["sub"]; !ok {
+		claims["sub"] = "apikey:" + k.ID
+	}
+	return claims, nil
+}
+
+// Watch перечитывает ключи каждые interval до закрытия stop (nil — без
+// остановки): отключение или срок ключа в таблице действуют без SIGHUP.
+// Ошибки пишутся в лог, прежние ключи остаются.
+func (s *APIKeyStore) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.reloadLogged()
+		}
+	}
+}
+
+func (s *APIKeyStore) reloadLogged() {
+	if err := s.Reload(context.Background()); err != nil {
+		logger.Warn("api_keys_reload_failed", map[string]any{"error": err.Error()})
+		return
+	}
+	logger.Info("api_keys_reloaded", nil)
+}
+
+// ReloadAPIKeys перечитывает все хранилища ключей (вызывается по SIGHUP).
+func ReloadAPIKeys() {
+	apiKeyStoresMu.Lock()
+	list := append([]*APIKeyStore(nil), apiKeyStores...)
+	apiKeyStoresMu.Unlock()
+	for _, s := range list {
+		s.reloadLogged()
+	}
+}
+
+func loadAPIKeysFile(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read api keys: %w", err)
+	}
+	var doc struct {
+		Keys []APIKey `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("api keys %s: %w", path, err)
+	}
+	return doc.Keys, nil
+}
+
+// loadAPIKeysTable читает ключи из таблицы
+// (id text, key_hash text, claims jsonb, enabled bool, expires_at timestamptz).
+func loadAPIKeysTable(ctx context.Context, table string) ([]APIKey, error) {
+	if db.Pool == nil {
+		return nil, errors.New("api keys table requires a database connection")
+	}
+	rows, err := db.Pool.Query(ctx, "SELECT id, key_hash, claims, enabled, expires_at FROM "+table)
+	if err != nil {
+		return nil, fmt.Errorf("query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var (
+			k      APIKey
+			claims []byte
+		)
+		// enabled NULL — как отсутствующий enabled в YAML: ключ включён
+		if err := rows.Scan(&k.ID, &k.Hash, &claims, &k.Enabled, &k.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		if len(claims) > 0 {
+			if err := json.Unmarshal(claims, &k.Claims); err != nil {
+				return nil, fmt.Errorf("api key %q: invalid claims: %w", k.ID, err)
+			}
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read api keys: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeAPIKeys(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write api keys: %v", err)
+	}
+}
+
+func TestAPIKeyStoreAuthenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.yml")
+	writeAPIKeys(t, path, `
+keys:
+  - id: export
+    hash: sha256:`+HashAPIKey("export-secret")+`
+    claims:
+      sub: batch-export
+      roles: [reporter]
+      tenant: acme
+  - id: legacy
+    hash: `+HashAPIKey("legacy-secret")+`
+    enabled: false
+  - id: temp
+    hash: `+HashAPIKey("temp-secret")+`
+    expires_at: 2026-01-01T00:00:00Z
+`)
+	s, err := NewAPIKeyStore(path, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore failed: %v", err)
+	}
+	s.clockFunc = func() time.Time { return time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC) }
+
+	claims, err := s.Authenticate("export-secret")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if claims["sub"] != "batch-export" || claims["tenant"] != "acme" {
+		t.Fatalf("unexpected claims: %#v", claims)
+	}
+	ctx := WithClaims(context.Background(), claims)
+	if roles := RolesFromContext(ctx); len(roles) != 1 || roles[0] != "reporter" {
+		t.Fatalf("roles must be read like JWT claims, got %#v", roles)
+	}
+	// claims копируются: изменения в контексте не трогают хранилище
+	claims["sub"] = "changed"
+	if again, _ := s.Authenticate("export-secret"); again["sub"] != "batch-export" {
+		t.Fatalf("stored claims were modified: %#v", again)
+	}
+
+	tempClaims, err := s.Authenticate("temp-secret")
+	if err != nil {
+		t.Fatalf("temp key before expiry: %v", err)
+	}
+	if tempClaims["sub"] != "apikey:temp" {
+		t.Fatalf("expected default subject, got %#v", tempClaims["sub"])
+	}
+
+	s.clockFunc = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	cases := map[string]string{
+		"temp-secret":   "expired",
+		"legacy-secret": "disabled",
+		"wrong":         "unknown api key",
+		"":              "empty",
+	}
+	for key, want := range cases {
+		if _, err := s.Authenticate(key); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("key %q: expected %q error, got %v", key, want, err)
+		}
+	}
+}
+
+func TestAPIKeyStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.yml")
+	writeAPIKeys(t, path, "keys:\n  - id: a\n    hash: "+HashAPIKey("a-secret")+"\n")
+	s, err := NewAPIKeyStore(path, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore failed: %v", err)
+	}
+
+	writeAPIKeys(t, path, "keys:\n  - id: b\n    hash: "+HashAPIKey("b-secret")+"\n")
+	if err := s.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if _, err := s.Authenticate("a-secret"); err == nil {
+		t.Fatalf("removed key must be rejected after reload")
+	}
+	if _, err := s.Authenticate("b-secret"); err != nil {
+		t.Fatalf("new key after reload: %v", err)
+	}
+
+	writeAPIKeys(t, path, "keys:\n  - id: c\n    hash: not-a-digest\n")
+	if err := s.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "hex sha256") {
+		t.Fatalf("expected hash error, got %v", err)
+	}
+	if _, err := s.Authenticate("b-secret"); err != nil {
+		t.Fatalf("keys lost after failed reload: %v", err)
+	}
+
+	if _, err := NewAPIKeyStore("", "keys; DROP TABLE users"); err == nil || !strings.Contains(err.Error(), "invalid api keys table name") {
+		t.Fatalf("expected table name error, got %v", err)
+	}
+}
+
+func TestAPIKeyStoreWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.yml")
+	writeAPIKeys(t, path, "keys:\n  - id: a\n    hash: "+HashAPIKey("a-secret")+"\n")
+	s, err := NewAPIKeyStore(path, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore failed: %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Watch(10*time.Millisecond, stop)
+
+	writeAPIKeys(t, path, "keys:\n  - id: a\n    hash: "+HashAPIKey("a-secret")+"\n    enabled: false\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := s.Authenticate("a-secret"); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("disabled key must be rejected after a periodic reload")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
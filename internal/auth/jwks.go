@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"YrestAPI/internal/logger"
+)
+
+// jwksFetchTimeout ограничивает загрузку JWKS по URL.
+const jwksFetchTimeout = 10 * time.Second
+
+// jwksKey — ключ из JWKS: *rsa.PublicKey, *ecdsa.PublicKey или []byte (oct).
+type jwksKey struct {
+	kid string
+	alg string // алгоритм, для которого годится ключ (RS256 / ES256 / HS256)
+	key any
+}
+
+// KeySet — набор ключей проверки подписи из JWKS-файла и/или URL.
+// Ключи выбираются по kid из заголовка токена; одновременно может быть
+// активно несколько ключей (ротация). Reload подменяет набор целиком,
+// а при ошибке оставляет прежний.
+type KeySet struct {
+	path   string
+	url    string
+	client *http.Client
+
+	mu   sync.RWMutex
+	keys []jwksKey
+}
+
+var (
+	keySetsMu sync.Mutex
+	keySets   []*KeySet
+)
+
+// NewKeySet загружает JWKS из path и/или url (оба источника объединяются).
+// Набор регистрируется для ReloadKeySets.
+func NewKeySet(path, url string) (*KeySet, error) {
+	s := &KeySet{
+		path:   strings.TrimSpace(path),
+		url:    strings.TrimSpace(url),
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+	if s.path == "" && s.url == "" {
+		return nil, errors.New("jwks path or url is required")
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	keySetsMu.Lock()
+	keySets = append(keySets, s)
+	keySetsMu.Unlock()
+	return s, nil
+}
+
+// Reload перечитывает JWKS. При ошибке текущие ключи сохраняются.
+func (s *KeySet) Reload() error {
+	var keys []jwksKey
+	if s.path != "" {
+		data, err := os.ReadFile(s.path)
+		if err != nil {
+			return fmt.Errorf("read jwks: %w", err)
+		}
+		parsed, err := parseJWKS(data)
+		if err != nil {
+			return fmt.Errorf("jwks %s: %w", s.path, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	if s.url != "" {
+		data, err := s.fetch()
+		if err != nil {
+			return fmt.Errorf("fetch jwks: %w", err)
+		}
+		parsed, err := parseJWKS(data)
+		if err != nil {
+			return fmt.Errorf("jwks %s: %w", s.url, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	if len(keys) == 0 {
+		return errors.New("jwks contains no usable keys")
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *KeySet) fetch() ([]byte, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Watch перечитывает JWKS каждые interval до закрытия stop (nil — без остановки).
+// Ошибки пишутся в лог, сервис продолжает работать на прежних ключах.
+func (s *KeySet) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.reloadLogged()
+		}
+	}
+}
+
+func (s *KeySet) reloadLogged() {
+	if err := s.Reload(); err != nil {
+		logger.Warn("jwks_reload_failed", map[string]any{"error": err.Error()})
+		return
+	}
+	logger.Info("jwks_reloaded", map[string]any{"keys": s.Len()})
+}
+
+// Len возвращает число загруженных ключей.
+func (s *KeySet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// candidates возвращает ключи для токена с alg и kid. Без kid подходят
+// все ключи этого алгоритма.
+func (s *KeySet) candidates(alg, kid string) []jwksKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []jwksKey
+	for _, k := range s.keys {
+		if k.alg != alg {
+			continue
+		}
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// ReloadKeySets перечитывает все JWKS-наборы (вызывается по SIGHUP).
+func ReloadKeySets() {
+	keySetsMu.Lock()
+	list := append([]*KeySet(nil), keySets...)
+	keySetsMu.Unlock()
+	for _, s := range list {
+		s.reloadLogged()
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// parseJWKS разбирает документ {"keys":[...]}. Ключи с use != "sig" и
+// неподдерживаемых типов пропускаются.
+func parseJWKS(data []byte) ([]jwksKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	out := make([]jwksKey, 0, len(doc.Keys))
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, alg, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (kid %q): %w", i, k.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		if k.Alg != "" && !strings.EqualFold(k.Alg, alg) {
+			// ключ объявлен для другого алгоритма (напр. PS256) — не используем
+			continue
+		}
+		out = append(out, jwksKey{kid: k.Kid, alg: alg, key: key})
+	}
+	return out, nil
+}
+
+// publicKey строит ключ по kty. Неподдерживаемый kty — (nil, "", nil).
+func (k jwk) publicKey() (any, string, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, "", errors.New("invalid e")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, "RS256", nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, "", nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, "", errors.New("invalid x")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, "", errors.New("invalid y")
+		}
+		// ecdh проверяет, что точка лежит на кривой
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, "", errors.New("point is not on P-256")
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, "ES256", nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, "", errors.New("invalid k")
+		}
+		return secret, "HS256", nil
+	}
+	return nil, "", nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
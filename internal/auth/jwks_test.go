@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/config"
+)
+
+func rsaJWK(kid string, pub *rsa.PublicKey) map[string]any {
+	return map[string]any{
+		"kty": "RSA",
+		"kid": kid,
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, pub *ecdsa.PublicKey) map[string]any {
+	pad := func(b []byte) []byte { return append(make([]byte, 32-len(b)), b...) }
+	return map[string]any{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(pad(pub.X.Bytes())),
+		"y":   base64.RawURLEncoding.EncodeToString(pad(pub.Y.Bytes())),
+	}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]any) {
+	t.Helper()
+	raw, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+}
+
+func buildKidToken(t *testing.T, alg, kid string, sign func(hash []byte) []byte, claims map[string]any) string {
+	t.Helper()
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signingInput := encodePart(t, header) + "." + encodePart(t, claims)
+	hash := sha256.Sum256([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign(hash[:]))
+}
+
+func rsaSigner(t *testing.T, priv *rsa.PrivateKey) func([]byte) []byte {
+	return func(hash []byte) []byte {
+		sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, hash)
+		if err != nil {
+			t.Fatalf("SignPKCS1v15 failed: %v", err)
+		}
+		return sig
+	}
+}
+
+func ecSigner(t *testing.T, priv *ecdsa.PrivateKey) func([]byte) []byte {
+	return func(hash []byte) []byte {
+		r, s, err := ecdsa.Sign(rand.Reader, priv, hash)
+		if err != nil {
+			t.Fatalf("ecdsa.Sign failed: %v", err)
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	}
+}
+
+func TestJWKSValidateTokenByKid(t *testing.T) {
+	now := time.Unix(1730000000, 0)
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path,
+		rsaJWK("old", &oldKey.PublicKey),
+		rsaJWK("new", &newKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		map[string]any{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	)
+
+	cfg := config.JWTConfig{Issuer: "auth-service", Audience: "yrest-api", JWKSPath: path}
+	v, err := NewJWTValidator(cfg)
+	if err != nil {
+		t.Fatalf("NewJWTValidator failed: %v", err)
+	}
+	v.clockFunc = func() time.Time { return now }
+	if v.keys.Len() != 3 {
+		t.Fatalf("expected 3 signing keys, got %d", v.keys.Len())
+	}
+
+	claims := map[string]any{
+		"iss": cfg.Issuer,
+		"aud": cfg.Audience,
+		"iat": now.Unix() - 10,
+		"nbf": now.Unix() - 10,
+		"exp": now.Unix() + 60,
+	}
+	oldToken := buildKidToken(t, "RS256", "old", rsaSigner(t, oldKey), claims)
+	valid := map[string]string{
+		"old":    oldToken,
+		"new":    buildKidToken(t, "RS256", "new", rsaSigner(t, newKey), claims),
+		"no kid": buildKidToken(t, "RS256", "", rsaSigner(t, newKey), claims),
+		"ec":     buildKidToken(t, "ES256", "ec", ecSigner(t, ecKey), claims),
+	}
+	for name, token := range valid {
+		if _, err := v.ValidateToken(token); err != nil {
+			t.Fatalf("%s: ValidateToken failed: %v", name, err)
+		}
+	}
+
+	if _, err := v.ValidateToken(buildKidToken(t, "RS256", "missing", rsaSigner(t, oldKey), claims)); err == nil || !strings.Contains(err.Error(), "unknown jwt key id") {
+		t.Fatalf("expected unknown kid error, got %v", err)
+	}
+	if _, err := v.ValidateToken(buildKidToken(t, "RS256", "new", rsaSigner(t, oldKey), claims)); err == nil {
+		t.Fatalf("token signed by another key must fail")
+	}
+	// ключ RSA нельзя использовать как HMAC-секрет
+	if _, err := v.ValidateToken(buildKidToken(t, "HS256", "old", func([]byte) []byte { return []byte("x") }, claims)); err == nil {
+		t.Fatalf("alg confusion must fail")
+	}
+
+	// ротация: старый ключ убран из файла
+	writeJWKS(t, path, rsaJWK("new", &newKey.PublicKey))
+	if err := v.keys.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if _, err := v.ValidateToken(oldToken); err == nil {
+		t.Fatalf("token of a removed key must fail after reload")
+	}
+	if _, err := v.ValidateToken(valid["new"]); err != nil {
+		t.Fatalf("new key after reload: %v", err)
+	}
+
+	// битый файл не сбрасывает текущие ключи
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := v.keys.Reload(); err == nil {
+		t.Fatalf("expected reload error for invalid jwks")
+	}
+	if _, err := v.ValidateToken(valid["new"]); err != nil {
+		t.Fatalf("keys lost after failed reload: %v", err)
+	}
+}
+
+func TestJWKSFromURL(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []any{ecJWK("k1", &key.PublicKey)}})
+	}))
+	defer srv.Close()
+
+	ks, err := NewKeySet("", srv.URL)
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+	if got := ks.candidates("ES256", "k1"); len(got) != 1 {
+		t.Fatalf("expected key k1 from url, got %d", len(got))
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	if _, err := NewKeySet("", notFound.URL); err == nil || !strings.Contains(err.Error(), "unexpected status 404") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
@@ -29,6 +29,7 @@ type JWTValidator struct {
 	rsaKey    *rsa.PublicKey
 	ecdsaKey  *ecdsa.PublicKey
 	hmacKey   []byte
+	keys      *KeySet // JWKS: ключ выбирается по kid, alg — по ключу
 	expected  string
 	clockFunc func() time.Time
 }
@@ -40,6 +41,13 @@ func NewJWTValidator(cfg config.JWTConfig) (*JWTValidator, error) {
 	if strings.TrimSpace(cfg.Audience) == "" {
 		return nil, errors.New("jwt audience is required")
 	}
+	if strings.TrimSpace(cfg.JWKSPath) != "" || strings.TrimSpace(cfg.JWKSURL) != "" {
+		keys, err := NewKeySet(cfg.JWKSPath, cfg.JWKSURL)
+		if err != nil {
+			return nil, err
+		}
+		return &JWTValidator{cfg: cfg, keys: keys, clockFunc: time.Now}, nil
+	}
 	alg := strings.ToUpper(strings.TrimSpace(cfg.ValidationType))
 	if alg == "" {
 		return nil, errors.New("jwt validation type is required")
@@ -95,7 +103,7 @@ func (v *JWTValidator) ValidateToken(token string) (map[string]any, error) {
 		return nil, fmt.Errorf("invalid jwt header: %w", err)
 	}
 	alg, _ := header["alg"].(string)
-	if strings.ToUpper(alg) != v.expected {
+	if v.keys == nil && strings.ToUpper(alg) != v.expected {
 		return nil, fmt.Errorf("unexpected jwt alg: %s", alg)
 	}
 
@@ -104,7 +112,13 @@ func (v *JWTValidator) ValidateToken(token string) (map[string]any, error) {
 	if err != nil {
 		return nil, errors.New("invalid jwt signature encoding")
 	}
-	if err := v.verifySignature(signingInput, signature); err != nil {
+	if v.keys != nil {
+		kid, _ := header["kid"].(string)
+		err = v.verifyWithKeySet(strings.ToUpper(alg), kid, signingInput, signature)
+	} else {
+		err = v.verifySignature(signingInput, signature)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -128,29 +142,83 @@ func ClaimsFromContext(ctx context.Context) (map[string]any, bool) {
 	return claims, ok
 }
 
+// WatchKeys перечитывает JWKS каждые interval до закрытия stop.
+// Для валидатора без JWKS сразу возвращается.
+func (v *JWTValidator) WatchKeys(interval time.Duration, stop <-chan struct{}) {
+	if v == nil || v.keys == nil || interval <= 0 {
+		return
+	}
+	v.keys.Watch(interval, stop)
+}
+
 func (v *JWTValidator) verifySignature(signingInput string, signature []byte) error {
-	hash := sha256.Sum256([]byte(signingInput))
 	switch v.expected {
 	case "HS256":
-		mac := hmac.New(sha256.New, v.hmacKey)
+		return verifyWithKey(v.expected, v.hmacKey, signingInput, signature)
+	case "RS256":
+		return verifyWithKey(v.expected, v.rsaKey, signingInput, signature)
+	case "ES256":
+		return verifyWithKey(v.expected, v.ecdsaKey, signingInput, signature)
+	}
+	return nil
+}
+
+// verifyWithKeySet проверяет подпись ключами JWKS: по kid, а без kid —
+// всеми ключами алгоритма alg (несколько активных ключей при ротации).
+func (v *JWTValidator) verifyWithKeySet(alg, kid, signingInput string, signature []byte) error {
+	candidates := v.keys.candidates(alg, kid)
+	if len(candidates) == 0 {
+		if kid != "" {
+			return fmt.Errorf("unknown jwt key id: %s", kid)
+		}
+		return fmt.Errorf("unexpected jwt alg: %s", alg)
+	}
+	var err error
+	for _, k := range candidates {
+		if err = verifyWithKey(alg, k.key, signingInput, signature); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func verifyWithKey(alg string, key any, signingInput string, signature []byte) error {
+	hash := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("invalid jwt signature")
+		}
+		mac := hmac.New(sha256.New, secret)
 		_, _ = mac.Write([]byte(signingInput))
 		expected := mac.Sum(nil)
 		if !hmac.Equal(expected, signature) {
 			return errors.New("invalid jwt signature")
 		}
 	case "RS256":
-		if err := rsa.VerifyPKCS1v15(v.rsaKey, crypto.SHA256, hash[:], signature); err != nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("invalid jwt signature")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hash[:], signature); err != nil {
 			return errors.New("invalid jwt signature")
 		}
 	case "ES256":
+		ecdsaKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("invalid jwt signature")
+		}
 		if len(signature) != 64 {
 			return errors.New("invalid jwt signature length")
 		}
 		r := new(big.Int).SetBytes(signature[:32])
 		s := new(big.Int).SetBytes(signature[32:])
-		if !ecdsa.Verify(v.ecdsaKey, hash[:], r, s) {
+		if !ecdsa.Verify(ecdsaKey, hash[:], r, s) {
 			return errors.New("invalid jwt signature")
 		}
+	default:
+		return fmt.Errorf("unexpected jwt alg: %s", alg)
 	}
 	return nil
 }
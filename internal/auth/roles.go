@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultRolesClaim — claim с ролями вызывающего по умолчанию.
+const DefaultRolesClaim = "roles"
+
+var rolesClaim atomic.Value // string
+
+// SetRolesClaim задаёт claim, из которого берутся роли для access-правил
+// моделей и пресетов (напр. "roles" или "scope"). Пустое имя — значение по умолчанию.
+func SetRolesClaim(name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = DefaultRolesClaim
+	}
+	rolesClaim.Store(name)
+}
+
+// RolesClaim возвращает текущее имя claim с ролями.
+func RolesClaim() string {
+	if name, ok := rolesClaim.Load().(string); ok {
+		return name
+	}
+	return DefaultRolesClaim
+}
+
+// RolesFromContext возвращает роли вызывающего из JWT claims контекста.
+// Claim может быть массивом строк или строкой, разделённой пробелами
+// или запятыми (как OAuth2 "scope"). Без claims ролей нет.
+func RolesFromContext(ctx context.Context) []string {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return rolesFromClaim(claims[RolesClaim()])
+}
+
+func rolesFromClaim(v any) []string {
+	var out []string
+	switch val := v.(type) {
+	case string:
+		out = strings.FieldsFunc(val, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		})
+	case []any:
+		for _, item := range val {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+	case []string:
+		out = append(out, val...)
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRolesFromContext(t *testing.T) {
+	t.Cleanup(func() { SetRolesClaim("") })
+
+	ctx := WithClaims(context.Background(), map[string]any{
+		"roles": []any{"admin", 42, " manager "},
+		"scope": "read:users write:users,export",
+	})
+	if got := RolesFromContext(ctx); !reflect.DeepEqual(got, []string{"admin", "manager"}) {
+		t.Fatalf("unexpected roles: %#v", got)
+	}
+
+	SetRolesClaim("scope")
+	if got := RolesFromContext(ctx); !reflect.DeepEqual(got, []string{"read:users", "write:users", "export"}) {
+		t.Fatalf("unexpected scope roles: %#v", got)
+	}
+
+	if got := RolesFromContext(context.Background()); got != nil {
+		t.Fatalf("expected no roles without claims, got %#v", got)
+	}
+}
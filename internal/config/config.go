@@ -21,6 +21,11 @@ type Config struct {
 	CORS        CORSConfig
 	Auth        AuthConfig
 	Debug       DebugConfig
+	Stream      StreamConfig
+	Reload      ReloadConfig
+	RateLimit   RateLimitConfig
+	QueryCost   QueryCostConfig
+	Query       QueryConfig
 }
 
 type AliasCacheConfig struct {
@@ -33,14 +38,60 @@ type CORSConfig struct {
 }
 
 type AuthConfig struct {
-	Enabled bool
-	JWT     JWTConfig
+	Enabled    bool
+	RolesClaim string // claim с ролями для access-правил моделей и пресетов
+	JWT        JWTConfig
+	APIKeys    APIKeysConfig
+}
+
+// APIKeysConfig — источники API-ключей; ключи принимаются, если задан хотя бы один.
+type APIKeysConfig struct {
+	Path  string // YAML-файл с хешами ключей и их claims
+	Table string // таблица PostgreSQL с теми же данными
+	// RefreshSec — период перечитывания ключей, 0 — только по SIGHUP
+	RefreshSec int64
 }
 
 type DebugConfig struct {
 	LogsToken string
 }
 
+type StreamConfig struct {
+	ChunkSize int64
+}
+
+// RateLimitConfig — общие лимиты на субъекта (sub из JWT/API-ключа или IP клиента).
+// Нулевые RPS и Concurrency выключают соответствующее ограничение.
+type RateLimitConfig struct {
+	RPS         float64
+	Burst       int64 // 0 — max(1, RPS)
+	Concurrency int64
+	TrustProxy  bool // брать IP клиента из X-Forwarded-For
+}
+
+// QueryCostConfig — бюджет стоимости запроса; 0 в любом поле снимает ограничение.
+type QueryCostConfig struct {
+	MaxJoins    int64
+	MaxTails    int64
+	MaxDepth    int64
+	MaxFilters  int64
+	MaxLimit    int64
+	MaxPlanCost float64 // > 0 — перед выполнением запускается EXPLAIN
+}
+
+type QueryConfig struct {
+	TimeoutMS int64 // предел времени запроса, если у модели и пресета нет timeout_ms; 0 — без ограничения
+	// одновременных tail-запросов: на один корневой запрос и на весь процесс; 0 — без ограничения
+	TailConcurrency       int64
+	TailGlobalConcurrency int64
+	TailChunkSize         int64 // ключей родителей в одном tail-запросе; больше — несколько параллельных запросов
+	SnapshotConcurrency   int64 // одновременных consistent-снимков; 0 — половина пула соединений
+}
+
+type ReloadConfig struct {
+	WatchIntervalSec int64 // 0 — отслеживание файлов выключено, остаётся SIGHUP
+}
+
 type JWTConfig struct {
 	ValidationType string
 	Issuer         string
@@ -48,6 +99,9 @@ type JWTConfig struct {
 	HMACSecret     string
 	PublicKeyPEM   string
 	PublicKeyPath  string
+	JWKSPath       string // JWKS-файл; вместе с JWKSURL заменяет одиночный ключ
+	JWKSURL        string
+	JWKSRefreshSec int64 // период перечитывания JWKS, 0 — только по SIGHUP
 	ClockSkewSec   int64
 }
 
@@ -71,7 +125,13 @@ func LoadConfig() *Config {
 			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
 		},
 		Auth: AuthConfig{
-			Enabled: getEnvBool("AUTH_ENABLED", false),
+			Enabled:    getEnvBool("AUTH_ENABLED", false),
+			RolesClaim: getEnv("AUTH_ROLES_CLAIM", "roles"),
+			APIKeys: APIKeysConfig{
+				Path:       getEnvOptional("AUTH_API_KEYS_PATH"),
+				Table:      getEnvOptional("AUTH_API_KEYS_TABLE"),
+				RefreshSec: getEnvInt64("AUTH_API_KEYS_REFRESH_SEC", 60),
+			},
 			JWT: JWTConfig{
 				ValidationType: strings.ToUpper(getEnv("AUTH_JWT_VALIDATION_TYPE", "HS256")),
 				Issuer:         getEnvOptional("AUTH_JWT_ISSUER"),
@@ -79,12 +139,42 @@ func LoadConfig() *Config {
 				HMACSecret:     getEnvOptional("AUTH_JWT_HMAC_SECRET"),
 				PublicKeyPEM:   getEnvOptional("AUTH_JWT_PUBLIC_KEY"),
 				PublicKeyPath:  getEnvOptional("AUTH_JWT_PUBLIC_KEY_PATH"),
+				JWKSPath:       getEnvOptional("AUTH_JWT_JWKS_PATH"),
+				JWKSURL:        getEnvOptional("AUTH_JWT_JWKS_URL"),
+				JWKSRefreshSec: getEnvInt64("AUTH_JWT_JWKS_REFRESH_SEC", 0),
 				ClockSkewSec:   getEnvInt64("AUTH_JWT_CLOCK_SKEW_SEC", 60),
 			},
 		},
 		Debug: DebugConfig{
 			LogsToken: getEnvOptional("DEBUG_LOGS_TOKEN"),
 		},
+		Stream: StreamConfig{
+			ChunkSize: getEnvInt64("STREAM_CHUNK_SIZE", 500),
+		},
+		Reload: ReloadConfig{
+			WatchIntervalSec: getEnvInt64("MODELS_WATCH_INTERVAL_SEC", 0),
+		},
+		RateLimit: RateLimitConfig{
+			RPS:         getEnvFloat64("RATE_LIMIT_RPS", 0),
+			Burst:       getEnvInt64("RATE_LIMIT_BURST", 0),
+			Concurrency: getEnvInt64("RATE_LIMIT_CONCURRENCY", 0),
+			TrustProxy:  getEnvBool("RATE_LIMIT_TRUST_PROXY", false),
+		},
+		QueryCost: QueryCostConfig{
+			MaxJoins:    getEnvInt64("QUERY_MAX_JOINS", 0),
+			MaxTails:    getEnvInt64("QUERY_MAX_TAILS", 0),
+			MaxDepth:    getEnvInt64("QUERY_MAX_DEPTH", 0),
+			MaxFilters:  getEnvInt64("QUERY_MAX_FILTERS", 0),
+			MaxLimit:    getEnvInt64("QUERY_MAX_LIMIT", 0),
+			MaxPlanCost: getEnvFloat64("QUERY_MAX_PLAN_COST", 0),
+		},
+		Query: QueryConfig{
+			TimeoutMS:             getEnvInt64("QUERY_TIMEOUT_MS", 0),
+			TailConcurrency:       getEnvInt64("QUERY_TAIL_CONCURRENCY", 8),
+			TailGlobalConcurrency: getEnvInt64("QUERY_TAIL_GLOBAL_CONCURRENCY", 16),
+			TailChunkSize:         getEnvInt64("QUERY_TAIL_CHUNK_SIZE", 5000),
+			SnapshotConcurrency:   getEnvInt64("QUERY_SNAPSHOT_CONCURRENCY", 0),
+		},
 	}
 
 	return cfg
@@ -135,6 +225,23 @@ func getEnvInt64(key string, fallback int64) int64 {
 	return parsed
 }
 
+func getEnvFloat64(key string, fallback float64) float64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logger.Warn("env_invalid_float", map[string]any{
+			"key":      key,
+			"value":    value,
+			"fallback": fallback,
+		})
+		return fallback
+	}
+	return parsed
+}
+
 func getEnvOptional(key string) string {
 	return strings.TrimSpace(os.Getenv(key))
 }
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Query выполняет SELECT через Pool. При дедлайне ctx запрос идёт в
+// короткой READ ONLY транзакции с SET LOCAL statement_timeout на оставшееся
+// время: сервер остановит его, даже если отмена pgx не дойдёт. Внутри
+// BeginSnapshot транзакция ещё и читает общий снимок. Транзакция
+// откатывается в rows.Close.
+func Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx, err := beginFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return Pool.Query(ctx, sql, args...)
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(context.Background())
+		return nil, err
+	}
+	return &txRows{Rows: rows, tx: tx}, nil
+}
+
+// QueryRow — Query для одной строки.
+func QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	tx, err := beginFor(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	if tx == nil {
+		return Pool.QueryRow(ctx, sql, args...)
+	}
+	return &txRow{Row: tx.QueryRow(ctx, sql, args...), tx: tx}
+}
+
+// IsTimeout сообщает, что запрос прерван по дедлайну контекста или по
+// statement_timeout (SQLSTATE 57014).
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
+
+// statementTimeout возвращает время до дедлайна ctx (не меньше 1 мс).
+func statementTimeout(ctx context.Context) (time.Duration, bool, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false, nil
+	}
+	left := time.Until(deadline)
+	if left <= 0 {
+		return 0, false, context.DeadlineExceeded
+	}
+	return max(left, time.Millisecond), true, nil
+}
+
+// beginFor открывает транзакцию для снимка из BeginSnapshot или для
+// statement_timeout при дедлайне ctx; без того и другого возвращает nil, и
+// запрос идёт прямо в Pool. Истёкший дедлайн возвращается ошибкой без
+// обращения к БД.
+func beginFor(ctx context.Context) (pgx.Tx, error) {
+	timeout, hasDeadline, err := statementTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := snapshotFrom(ctx)
+	if snap == nil {
+		if !hasDeadline {
+			return nil, nil
+		}
+		// BEGIN и SET LOCAL уходят одним сообщением: один лишний round trip
+		return Pool.BeginTx(ctx, pgx.TxOptions{BeginQuery: timeoutBegin(timeout)})
+	}
+	tx, err := Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, snapshotSetup(snap, timeout, hasDeadline)); err != nil {
+		_ = tx.Rollback(context.Background())
+		return nil, fmt.Errorf("import snapshot: %w", err)
+	}
+	return tx, nil
+}
+
+// snapshotSetup — один round trip на импорт снимка и statement_timeout.
+// SET TRANSACTION SNAPSHOT должен идти первым и не принимает параметры;
+// идентификатор приходит от pg_export_snapshot и проверен в BeginSnapshot.
+// Без аргументов pgx отправляет строку простым протоколом, поэтому обе
+// команды уходят одним сообщением.
+func snapshotSetup(snap *snapshot, timeout time.Duration, hasDeadline bool) string {
+	sql := "SET TRANSACTION SNAPSHOT '" + snap.id + "'"
+	if hasDeadline {
+		sql += fmt.Sprintf("; SET LOCAL statement_timeout = %d", timeout.Milliseconds())
+	}
+	return sql
+}
+
+// timeoutBegin — BEGIN короткой транзакции запроса без снимка вместе с
+// statement_timeout на время до дедлайна.
+func timeoutBegin(timeout time.Duration) string {
+	return fmt.Sprintf("BEGIN READ ONLY; SET LOCAL statement_timeout = %d", timeout.Milliseconds())
+}
+
+type txRows struct {
+	pgx.Rows
+	tx pgx.Tx
+}
+
+func (r *txRows) Close() {
+	r.Rows.Close()
+	_ = r.tx.Rollback(context.Background())
+}
+
+type txRow struct {
+	pgx.Row
+	tx pgx.Tx
+}
+
+func (r *txRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	_ = r.tx.Rollback(context.Background())
+	return err
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
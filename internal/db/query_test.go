@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsTimeout(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("tail 'contacts': %w", context.DeadlineExceeded), true},
+		{&pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}, true},
+		{&pgconn.PgError{Code: "42P01"}, false},
+		{context.Canceled, false},
+		{errors.New("boom"), false},
+	}
+	for _, tc := range cases {
+		if got := IsTimeout(tc.err); got != tc.want {
+			t.Fatalf("IsTimeout(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	if _, ok, err := statementTimeout(context.Background()); ok || err != nil {
+		t.Fatalf("no deadline: ok=%v err=%v", ok, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	left, ok, err := statementTimeout(ctx)
+	if !ok || err != nil || left <= time.Second || left > 2*time.Second {
+		t.Fatalf("got %v ok=%v err=%v", left, ok, err)
+	}
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	if _, _, err := statementTimeout(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expired deadline: got %v", err)
+	}
+}
+
+func TestSnapshotSetup(t *testing.T) {
+	snap := &snapshot{id: "00000003-0000001B-1"}
+	if got := snapshotSetup(snap, 0, false); got != "SET TRANSACTION SNAPSHOT '00000003-0000001B-1'" {
+		t.Fatalf("without deadline: %q", got)
+	}
+	want := "SET TRANSACTION SNAPSHOT '00000003-0000001B-1'; SET LOCAL statement_timeout = 1500"
+	if got := snapshotSetup(snap, 1500*time.Millisecond, true); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// Без снимка и без дедлайна транзакция не открывается: запрос идёт прямо
+// в пул, и Pool (в тестах nil) не трогается.
+func TestBeginForWithoutSnapshot(t *testing.T) {
+	if tx, err := beginFor(context.Background()); tx != nil || err != nil {
+		t.Fatalf("expected no transaction, got tx=%v err=%v", tx, err)
+	}
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	if _, err := beginFor(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expired deadline: got %v", err)
+	}
+}
+
+func TestTimeoutBegin(t *testing.T) {
+	if got, want := timeoutBegin(1500*time.Millisecond), "BEGIN READ ONLY; SET LOCAL statement_timeout = 1500"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
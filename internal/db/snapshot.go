@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// snapshot — экспортированный снимок транзакции, которую держит BeginSnapshot.
+type snapshot struct {
+	id string
+}
+
+type snapshotKey struct{}
+
+var snapshotIDRe = regexp.MustCompile(`^[0-9A-Fa-f-]+$`)
+
+var (
+	snapshotMu    sync.Mutex
+	snapshotLimit int // из SetSnapshotConcurrency; 0 — половина пула
+	snapshotPool  *pgxpool.Pool
+	snapshotSlots chan struct{}
+)
+
+// SetSnapshotConcurrency ограничивает число одновременно открытых снимков.
+// 0 — половина MaxConns пула. Предел не бывает больше MaxConns-1: снимок
+// держит соединение до конца запроса, а его запросы берут из пула ещё, и
+// хотя бы одно соединение должно оставаться им, иначе запросы со снимками
+// ждут друг друга до дедлайна. Вызывается один раз при старте.
+func SetSnapshotConcurrency(n int) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	snapshotLimit = max(n, 0)
+	snapshotPool = nil
+}
+
+// snapshotCapacity — число снимков, которое может держать пул из maxConns.
+func snapshotCapacity(maxConns int32, limit int) int {
+	capacity := int(maxConns) - 1
+	if limit == 0 {
+		limit = max(int(maxConns)/2, 1)
+	}
+	return max(min(capacity, limit), 0)
+}
+
+// acquireSnapshotSlot ждёт свободный слот снимка не дольше жизни ctx.
+func acquireSnapshotSlot(ctx context.Context) (func(), error) {
+	snapshotMu.Lock()
+	if snapshotPool != Pool || snapshotSlots == nil {
+		snapshotPool = Pool
+		snapshotSlots = make(chan struct{}, snapshotCapacity(Pool.Config().MaxConns, snapshotLimit))
+	}
+	slots := snapshotSlots
+	snapshotMu.Unlock()
+	if cap(slots) == 0 {
+		return nil, fmt.Errorf("begin snapshot: connection pool is too small for consistent reads")
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("begin snapshot: %w", ctx.Err())
+	}
+}
+
+// BeginSnapshot открывает REPEATABLE READ READ ONLY транзакцию и экспортирует
+// её снимок (pg_export_snapshot). Query и QueryRow с возвращённым контекстом
+// импортируют этот снимок в собственные транзакции, поэтому параллельные
+// запросы на разных соединениях видят одни и те же данные. Снимок живёт,
+// пока не вызвана release; транзакция занимает одно соединение пула.
+// Число одновременных снимков ограничено (SetSnapshotConcurrency), лишние
+// ждут слота до дедлайна ctx. Повторный вызов внутри снимка ничего не открывает.
+func BeginSnapshot(ctx context.Context) (context.Context, func(), error) {
+	if snapshotFrom(ctx) != nil {
+		return ctx, func() {}, nil
+	}
+	releaseSlot, err := acquireSnapshotSlot(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		releaseSlot()
+		return nil, nil, fmt.Errorf("begin snapshot: %w", err)
+	}
+	release := func() {
+		_ = tx.Rollback(context.Background())
+		releaseSlot()
+	}
+	var id string
+	if err := tx.QueryRow(ctx, "SELECT pg_export_snapshot()").Scan(&id); err != nil {
+		release()
+		return nil, nil, fmt.Errorf("export snapshot: %w", err)
+	}
+	if !snapshotIDRe.MatchString(id) {
+		release()
+		return nil, nil, fmt.Errorf("export snapshot: unexpected id %q", id)
+	}
+	return context.WithValue(ctx, snapshotKey{}, &snapshot{id: id}), release, nil
+}
+
+// InSnapshot сообщает, что запросы с ctx читают общий снимок.
+func InSnapshot(ctx context.Context) bool {
+	return snapshotFrom(ctx) != nil
+}
+
+func snapshotFrom(ctx context.Context) *snapshot {
+	s, _ := ctx.Value(snapshotKey{}).(*snapshot)
+	return s
+}
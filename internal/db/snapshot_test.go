@@ -0,0 +1,23 @@
+package db
+
+import "testing"
+
+func TestSnapshotCapacity(t *testing.T) {
+	cases := []struct {
+		maxConns int32
+		limit    int
+		want     int
+	}{
+		{20, 0, 10}, // по умолчанию — половина пула
+		{20, 4, 4},
+		{20, 50, 19}, // одно соединение всегда остаётся запросам
+		{3, 0, 1},
+		{2, 0, 1},
+		{1, 0, 0}, // пул из одного соединения снимки держать не может
+	}
+	for _, tc := range cases {
+		if got := snapshotCapacity(tc.maxConns, tc.limit); got != tc.want {
+			t.Fatalf("snapshotCapacity(%d, %d) = %d, want %d", tc.maxConns, tc.limit, got, tc.want)
+		}
+	}
+}
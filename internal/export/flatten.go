@@ -0,0 +1,273 @@
+// Package export turns finalized /api/index items into spreadsheet rows
+// (CSV, XLSX) following the column layout of the requested preset.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"YrestAPI/internal/model"
+)
+
+// joinSeparator glues has_many values into one cell when rows are not exploded.
+const joinSeparator = "; "
+
+// Cell is one spreadsheet value. Numbers are kept apart so that XLSX can emit
+// numeric cells; CSV writes Text in both cases.
+type Cell struct {
+	Text   string
+	Number bool
+}
+
+// column is a node of the preset tree: a leaf column or a nested object
+// (belongs_to/has_one) or list (has_many) with its own columns.
+type column struct {
+	key      string
+	children []*column
+	many     bool
+}
+
+// Plan is the column layout of a preset. Headers are dotted paths of the
+// finalized JSON keys, e.g. "person.last_name" or "contacts.value".
+type Plan struct {
+	Headers []string
+	columns []*column
+}
+
+// NewPlan derives columns from the preset in field order. Internal fields are
+// skipped; relations with a formatter become a single column; recursive
+// presets stop at the first repetition and are exported as JSON.
+func NewPlan(m *model.Model, p *model.DataPreset) *Plan {
+	plan := &Plan{}
+	plan.columns = buildColumns(m, p, map[*model.DataPreset]bool{p: true})
+	for _, c := range plan.columns {
+		plan.Headers = appendHeaders(plan.Headers, c, "")
+	}
+	return plan
+}
+
+// ValuePlan is a single-column plan for unique_by exports; rows are built
+// from items of the form {header: value}.
+func ValuePlan(header string) *Plan {
+	return &Plan{Headers: []string{header}, columns: []*column{{key: header}}}
+}
+
+func buildColumns(m *model.Model, p *model.DataPreset, seen map[*model.DataPreset]bool) []*column {
+	if p == nil {
+		return nil
+	}
+	out := make([]*column, 0, len(p.Fields))
+	for i := range p.Fields {
+		f := &p.Fields[i]
+		if f.Internal {
+			continue
+		}
+		key := strings.TrimSpace(f.Alias)
+		if key == "" {
+			key = f.Source
+		}
+		col := &column{key: key}
+		if f.Type == "preset" && strings.TrimSpace(f.Formatter) == "" && m != nil {
+			rel := m.Relations[f.Source]
+			if rel != nil && !rel.Polymorphic && rel.GetModelRef() != nil {
+				nestedModel := rel.GetModelRef()
+				nested := f.GetPresetRef()
+				if nested == nil && f.NestedPreset != "" {
+					nested = nestedModel.Presets[f.NestedPreset]
+				}
+				if nested != nil && !seen[nested] {
+					seen[nested] = true
+					col.children = buildColumns(nestedModel, nested, seen)
+					delete(seen, nested)
+					col.many = rel.Type == "has_many" || rel.Type == "tree"
+				}
+			}
+		}
+		out = append(out, col)
+	}
+	return out
+}
+
+func appendHeaders(headers []string, c *column, prefix string) []string {
+	path := c.key
+	if prefix != "" {
+		path = prefix + "." + c.key
+	}
+	if len(c.children) == 0 {
+		return append(headers, path)
+	}
+	for _, child := range c.children {
+		headers = appendHeaders(headers, child, path)
+	}
+	return headers
+}
+
+// MaxExplodedRows caps the rows one item may explode into: every exploded
+// has_many multiplies the rows of its siblings, so a few long lists would
+// otherwise turn one item into millions of rows.
+const MaxExplodedRows = 10000
+
+// ErrTooManyRows is returned by Rows when an exploded item exceeds
+// MaxExplodedRows.
+var ErrTooManyRows = fmt.Errorf("exploded item exceeds %d rows; export without explode", MaxExplodedRows)
+
+// Rows flattens one finalized item. Without explode every item is exactly one
+// row and has_many values are joined; with explode every has_many element
+// gets its own row (several has_many lists multiply, up to MaxExplodedRows).
+func (p *Plan) Rows(item map[string]any, explode bool) ([][]Cell, error) {
+	return rowsFor(item, p.columns, explode)
+}
+
+func rowsFor(obj map[string]any, cols []*column, explode bool) ([][]Cell, error) {
+	rows := [][]Cell{{}}
+	for _, c := range cols {
+		var value any
+		if obj != nil {
+			value = obj[c.key]
+		}
+		right, err := columnRows(value, c, explode)
+		if err != nil {
+			return nil, err
+		}
+		if rows, err = product(rows, right); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func columnRows(value any, c *column, explode bool) ([][]Cell, error) {
+	if len(c.children) == 0 {
+		return [][]Cell{{cellOf(value)}}, nil
+	}
+	if !c.many {
+		obj, _ := value.(map[string]any)
+		return rowsFor(obj, c.children, explode)
+	}
+
+	elems := listOf(value)
+	if len(elems) == 0 {
+		return rowsFor(nil, c.children, explode)
+	}
+	if explode {
+		out := make([][]Cell, 0, len(elems))
+		for _, e := range elems {
+			obj, _ := e.(map[string]any)
+			rows, err := rowsFor(obj, c.children, true)
+			if err != nil {
+				return nil, err
+			}
+			if len(out)+len(rows) > MaxExplodedRows {
+				return nil, ErrTooManyRows
+			}
+			out = append(out, rows...)
+		}
+		return out, nil
+	}
+
+	// без explode каждый элемент даёт ровно одну строку — склеиваем по колонкам
+	var joined []Cell
+	for i, e := range elems {
+		obj, _ := e.(map[string]any)
+		rows, err := rowsFor(obj, c.children, false)
+		if err != nil {
+			return nil, err
+		}
+		row := rows[0]
+		if i == 0 {
+			joined = make([]Cell, len(row))
+			for j := range row {
+				joined[j] = Cell{Text: row[j].Text}
+			}
+			continue
+		}
+		for j := range row {
+			joined[j].Text += joinSeparator + row[j].Text
+		}
+	}
+	return [][]Cell{joined}, nil
+}
+
+func product(left, right [][]Cell) ([][]Cell, error) {
+	if len(left)*len(right) > MaxExplodedRows {
+		return nil, ErrTooManyRows
+	}
+	out := make([][]Cell, 0, len(left)*len(right))
+	for _, l := range left {
+		for _, r := range right {
+			row := make([]Cell, 0, len(l)+len(r))
+			row = append(row, l...)
+			row = append(row, r...)
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+func listOf(v any) []any {
+	switch vv := v.(type) {
+	case []any:
+		return vv
+	case []map[string]any:
+		out := make([]any, len(vv))
+		for i := range vv {
+			out[i] = vv[i]
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func cellOf(v any) Cell {
+	switch vv := v.(type) {
+	case nil:
+		return Cell{}
+	case string:
+		return Cell{Text: vv}
+	case bool:
+		return Cell{Text: strconv.FormatBool(vv)}
+	case int:
+		return Cell{Text: strconv.Itoa(vv), Number: true}
+	case int16:
+		return Cell{Text: strconv.FormatInt(int64(vv), 10), Number: true}
+	case int32:
+		return Cell{Text: strconv.FormatInt(int64(vv), 10), Number: true}
+	case int64:
+		return Cell{Text: strconv.FormatInt(vv, 10), Number: true}
+	case float32:
+		return Cell{Text: strconv.FormatFloat(float64(vv), 'f', -1, 32), Number: true}
+	case float64:
+		return Cell{Text: strconv.FormatFloat(vv, 'f', -1, 64), Number: true}
+	case time.Time:
+		return Cell{Text: vv.Format(time.RFC3339)}
+	case []string:
+		return Cell{Text: strings.Join(vv, joinSeparator)}
+	case []any:
+		parts := make([]string, 0, len(vv))
+		for _, e := range vv {
+			if _, nested := e.(map[string]any); nested {
+				return jsonCell(v)
+			}
+			parts = append(parts, cellOf(e).Text)
+		}
+		return Cell{Text: strings.Join(parts, joinSeparator)}
+	case map[string]any, []map[string]any:
+		return jsonCell(v)
+	case fmt.Stringer:
+		return Cell{Text: vv.String()}
+	default:
+		return jsonCell(v)
+	}
+}
+
+func jsonCell(v any) Cell {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Cell{Text: fmt.Sprint(v)}
+	}
+	return Cell{Text: string(b)}
+}
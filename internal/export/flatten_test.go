@@ -0,0 +1,134 @@
+package export
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+func exportFixture() (*model.Model, *model.DataPreset) {
+	org := &model.Model{Table: "organizations", Presets: map[string]*model.DataPreset{
+		"item": {Name: "item", Fields: []model.Field{{Source: "name", Type: "string"}}},
+	}}
+	contact := &model.Model{Table: "contacts", Presets: map[string]*model.DataPreset{
+		"item": {Name: "item", Fields: []model.Field{
+			{Source: "kind", Type: "string"},
+			{Source: "value", Type: "string"},
+			{Source: "person_id", Type: "int", Internal: true},
+		}},
+	}}
+	orgRel := &model.ModelRelation{Type: "belongs_to", Model: "Organization"}
+	orgRel.SetModelRef(org)
+	contactsRel := &model.ModelRelation{Type: "has_many", Model: "Contact"}
+	contactsRel.SetModelRef(contact)
+
+	person := &model.Model{
+		Table:     "people",
+		Relations: map[string]*model.ModelRelation{"organization": orgRel, "contacts": contactsRel},
+	}
+	preset := &model.DataPreset{Name: "card", Fields: []model.Field{
+		{Source: "id", Type: "int"},
+		{Source: "first_name", Type: "string", Internal: true},
+		{Source: "full_name", Type: "formatter", Formatter: "{last_name} {first_name}"},
+		{Source: "organization", Alias: "org", Type: "preset", NestedPreset: "item"},
+		{Source: "contacts", Type: "preset", NestedPreset: "item"},
+	}}
+	return person, preset
+}
+
+func mustRows(t *testing.T, plan *Plan, item map[string]any, explode bool) [][]Cell {
+	t.Helper()
+	rows, err := plan.Rows(item, explode)
+	if err != nil {
+		t.Fatalf("Rows: %v", err)
+	}
+	return rows
+}
+
+func texts(rows [][]Cell) [][]string {
+	out := make([][]string, len(rows))
+	for i, row := range rows {
+		out[i] = make([]string, len(row))
+		for j, c := range row {
+			out[i][j] = c.Text
+		}
+	}
+	return out
+}
+
+func TestNewPlanHeadersFollowPreset(t *testing.T) {
+	m, p := exportFixture()
+	plan := NewPlan(m, p)
+	want := []string{"id", "full_name", "org.name", "contacts.kind", "contacts.value"}
+	if !reflect.DeepEqual(plan.Headers, want) {
+		t.Fatalf("headers mismatch: got %v, want %v", plan.Headers, want)
+	}
+}
+
+func TestPlanRowsJoinAndExplode(t *testing.T) {
+	m, p := exportFixture()
+	plan := NewPlan(m, p)
+	item := map[string]any{
+		"id":        int64(7),
+		"full_name": "Smith John",
+		"org":       map[string]any{"name": "ACME"},
+		"contacts": []map[string]any{
+			{"kind": "email", "value": "john@acme.test"},
+			{"kind": "phone", "value": "+100"},
+		},
+	}
+
+	joined := mustRows(t, plan, item, false)
+	wantJoined := [][]string{{"7", "Smith John", "ACME", "email; phone", "john@acme.test; +100"}}
+	if got := texts(joined); !reflect.DeepEqual(got, wantJoined) {
+		t.Fatalf("joined rows mismatch: got %v, want %v", got, wantJoined)
+	}
+	if !joined[0][0].Number || joined[0][1].Number {
+		t.Fatalf("unexpected numeric flags: %#v", joined[0])
+	}
+
+	exploded := mustRows(t, plan, item, true)
+	wantExploded := [][]string{
+		{"7", "Smith John", "ACME", "email", "john@acme.test"},
+		{"7", "Smith John", "ACME", "phone", "+100"},
+	}
+	if got := texts(exploded); !reflect.DeepEqual(got, wantExploded) {
+		t.Fatalf("exploded rows mismatch: got %v, want %v", got, wantExploded)
+	}
+}
+
+func TestPlanRowsEmptyRelations(t *testing.T) {
+	m, p := exportFixture()
+	plan := NewPlan(m, p)
+	item := map[string]any{"id": int64(8), "full_name": "Doe Jane", "org": nil, "contacts": []any{}}
+
+	for _, explode := range []bool{false, true} {
+		got := texts(mustRows(t, plan, item, explode))
+		want := [][]string{{"8", "Doe Jane", "", "", ""}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("explode=%v: got %v, want %v", explode, got, want)
+		}
+	}
+}
+
+// Соседние has_many перемножаются при explode: элемент, который дал бы
+// больше MaxExplodedRows строк, отклоняется, а не раздувает выгрузку.
+func TestPlanRowsExplodeCap(t *testing.T) {
+	m, p := exportFixture()
+	p.Fields = append(p.Fields, model.Field{Source: "contacts", Alias: "more_contacts", Type: "preset", NestedPreset: "item"})
+	plan := NewPlan(m, p)
+	contacts := make([]any, 101)
+	for i := range contacts {
+		contacts[i] = map[string]any{"kind": "email"}
+	}
+	item := map[string]any{"id": int64(1), "contacts": contacts, "more_contacts": contacts}
+
+	if _, err := plan.Rows(item, true); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("expected ErrTooManyRows, got %v", err)
+	}
+	if rows := mustRows(t, plan, item, false); len(rows) != 1 {
+		t.Fatalf("joined export must stay one row, got %d", len(rows))
+	}
+}
@@ -0,0 +1,109 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeader([]string{"id", "name"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]Cell{{Text: "1", Number: true}, {Text: "Smith, John"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name\n1,\"Smith, John\"\n"
+	if buf.String() != want {
+		t.Fatalf("csv mismatch: got %q, want %q", buf.String(), want)
+	}
+}
+
+// Строки, которые таблица прочла бы как формулу, экранируются апострофом;
+// числа пишутся как есть.
+func TestCSVWriterEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := []Cell{
+		{Text: "=HYPERLINK(\"http://x\")"}, {Text: "+100"}, {Text: "-5", Number: true},
+		{Text: "@SUM(A1)"}, {Text: "\tx"}, {Text: "a=b"},
+	}
+	if err := w.WriteRow(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "\"'=HYPERLINK(\"\"http://x\"\")\",'+100,-5,'@SUM(A1),'\tx,a=b\n"
+	if buf.String() != want {
+		t.Fatalf("csv mismatch: got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestXLSXWriterProducesWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeader([]string{"id", "name"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]Cell{{Text: "42", Number: true}, {Text: "A & B <c>\x01"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="A2"><v>42</v></c>`,
+		`<t xml:space="preserve">A &amp; B &lt;c&gt;</t>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Fatalf("expected %q in sheet: %s", want, sheet)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Fatalf("columnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,643 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"YrestAPI/internal/graphql/language"
+	"YrestAPI/internal/model"
+	"YrestAPI/internal/resolver"
+)
+
+// Request — тело запроса GraphQL over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Response — ответ GraphQL. Data == nil означает, что до исполнения дело
+// не дошло (синтаксис/валидация, бюджет стоимости) — тогда есть только errors.
+type Response struct {
+	Data   *Object `json:"data,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+	err    error
+}
+
+// Err возвращает ошибку, из-за которой документ не исполнялся
+// (например, *resolver.CostError), или nil.
+func (r *Response) Err() error { return r.err }
+
+// Пределы документа. Каждое корневое поле — отдельный запрос к БД, а
+// глубина и алиасы раздувают разбор и ответ; стоимость самих запросов
+// ограничивает бюджет резолвера, общий для всех корней документа.
+const (
+	maxRootFields     = 16
+	maxSelectionDepth = 12
+	maxAliases        = 64
+)
+
+// Error — элемент массива errors.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Object — объект ответа с сохранением порядка полей из запроса.
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+func newObject(n int) *Object {
+	return &Object{keys: make([]string, 0, n), values: make(map[string]any, n)}
+}
+
+func (o *Object) set(key string, v any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// Get возвращает значение поля ответа.
+func (o *Object) Get(key string) (any, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// MarshalJSON пишет поля в порядке выборки.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		b.Write(kb)
+		b.WriteByte(':')
+		vb, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// fieldPlan — проверенное поле выборки: ключ ответа, определение и дочерние поля.
+type fieldPlan struct {
+	Key      string
+	Name     string
+	Def      *FieldDef // nil для __typename
+	Children []*fieldPlan
+}
+
+// rootPlan — корневое поле Query, готовое к исполнению.
+type rootPlan struct {
+	Key      string
+	Field    *RootField
+	Req      resolver.IndexRequest
+	Children []*fieldPlan
+}
+
+// Execute исполняет query-операцию документа. Каждое корневое поле
+// превращается в IndexRequest с синтетическим пресетом (PresetObj), который
+// собирается из выбранных полей; ответ переупорядочивается по выборке.
+//
+// Бюджет стоимости проверяется один раз на весь документ: запросы всех
+// корней суммируются до исполнения первого из них.
+func Execute(ctx context.Context, s *Schema, req Request) *Response {
+	roots, err := prepare(s, req)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}, err: err}
+	}
+	failed := make(map[*rootPlan]error)
+	reqs := make([]resolver.IndexRequest, 0, len(roots))
+	for _, root := range roots {
+		if root.Field == nil {
+			continue
+		}
+		preset, err := buildPreset(s, s.Types[root.Field.Model], root.Children)
+		if err != nil {
+			failed[root] = err
+			continue
+		}
+		root.Req.PresetObj = preset
+		root.Req.CountOnly = root.Field.Count
+		reqs = append(reqs, root.Req)
+	}
+	if ctx, err = resolver.CheckCost(ctx, reqs...); err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}, err: err}
+	}
+	resp := &Response{Data: newObject(len(roots))}
+	for _, root := range roots {
+		err := failed[root]
+		var value any
+		if err == nil {
+			value, err = executeRoot(ctx, s, root)
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, Error{Message: err.Error(), Path: []any{root.Key}})
+			resp.Data.set(root.Key, nil)
+			continue
+		}
+		resp.Data.set(root.Key, value)
+	}
+	return resp
+}
+
+// RootModels возвращает модели корневых полей запроса без повторов — для
+// rate_limit моделей. Для документа, который не проходит разбор или
+// валидацию, возвращает nil: Execute его не исполнит.
+func RootModels(s *Schema, req Request) []string {
+	roots, err := prepare(s, req)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, root := range roots {
+		if root.Field != nil && !slices.Contains(out, root.Field.Model) {
+			out = append(out, root.Field.Model)
+		}
+	}
+	return out
+}
+
+// prepare разбирает запрос, выбирает операцию и валидирует выборку.
+func prepare(s *Schema, req Request) ([]*rootPlan, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	doc, err := language.Parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	if op.Type != "query" {
+		return nil, fmt.Errorf("only query operations are supported, got %s", op.Type)
+	}
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+	c := &collector{doc: doc, vars: vars}
+
+	groups, err := c.collect("Query", op.Selections)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) > maxRootFields {
+		return nil, fmt.Errorf("document selects %d root fields, at most %d are allowed", len(groups), maxRootFields)
+	}
+	roots := make([]*rootPlan, 0, len(groups))
+	for _, g := range groups {
+		if g.name == "__typename" {
+			roots = append(roots, &rootPlan{Key: g.key})
+			continue
+		}
+		if g.name == "__schema" || g.name == "__type" {
+			return nil, fmt.Errorf("introspection is not supported; fetch the schema with GET /graphql")
+		}
+		rf := s.Roots[g.name]
+		if rf == nil {
+			return nil, fmt.Errorf("cannot query field %q on type \"Query\"", g.name)
+		}
+		root := &rootPlan{Key: g.key, Field: rf}
+		if root.Req, err = rootRequest(rf, g.field.Arguments, vars); err != nil {
+			return nil, err
+		}
+		if rf.Count {
+			if len(g.sels) > 0 {
+				return nil, fmt.Errorf("field %q of type \"Int\" must not have a selection set", g.name)
+			}
+		} else {
+			if len(g.sels) == 0 {
+				return nil, fmt.Errorf("field %q of type \"[%s!]\" must have a selection of subfields", g.name, rf.Model)
+			}
+			if root.Children, err = c.plan(s, s.Types[rf.Model], g.sels, 1); err != nil {
+				return nil, err
+			}
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+func selectOperation(doc *language.Document, name string) (*language.Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document contains several operations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// coerceVariables подставляет значения по умолчанию и проверяет non-null.
+func coerceVariables(op *language.Operation, provided map[string]any) (map[string]any, error) {
+	vars := make(map[string]any, len(op.Variables))
+	declared := map[string]struct{}{}
+	for _, def := range op.Variables {
+		declared[def.Name] = struct{}{}
+		v, ok := provided[def.Name]
+		if !ok && def.Default != nil {
+			var err error
+			if v, err = language.ResolveValue(*def.Default, nil); err != nil {
+				return nil, err
+			}
+			ok = true
+		}
+		if strings.HasSuffix(def.Type, "!") && (!ok || v == nil) {
+			return nil, fmt.Errorf("variable $%s of required type %s was not provided", def.Name, def.Type)
+		}
+		if ok {
+			vars[def.Name] = v
+		}
+	}
+	// все ссылки на переменные должны быть объявлены
+	refs := map[string]struct{}{}
+	var walk func(sels []language.Selection)
+	walk = func(sels []language.Selection) {
+		for _, sel := range sels {
+			for _, a := range sel.Arguments {
+				language.VariableRefs(a.Value, refs)
+			}
+			for _, d := range sel.Directives {
+				for _, a := range d.Arguments {
+					language.VariableRefs(a.Value, refs)
+				}
+			}
+			walk(sel.Selections)
+		}
+	}
+	walk(op.Selections)
+	for name := range refs {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", name)
+		}
+	}
+	return vars, nil
+}
+
+// rootRequest переводит аргументы корневого поля в IndexRequest.
+func rootRequest(rf *RootField, args []language.Argument, vars map[string]any) (resolver.IndexRequest, error) {
+	req := resolver.IndexRequest{Model: rf.Model}
+	for _, a := range args {
+		v, err := language.ResolveValue(a.Value, vars)
+		if err != nil {
+			return req, err
+		}
+		if v == nil {
+			continue
+		}
+		switch {
+		case a.Name == "filters":
+			m, ok := v.(map[string]any)
+			if !ok {
+				return req, fmt.Errorf("argument \"filters\" of %q must be an object", rf.Name)
+			}
+			req.Filters = m
+		case a.Name == "sorts" && !rf.Count:
+			// одиночное значение допускается вместо списка
+			list, ok := v.([]any)
+			if !ok {
+				list = []any{v}
+			}
+			for _, item := range list {
+				str, ok := item.(string)
+				if !ok {
+					return req, fmt.Errorf("argument \"sorts\" of %q must be a list of strings", rf.Name)
+				}
+				req.Sorts = append(req.Sorts, str)
+			}
+		case (a.Name == "offset" || a.Name == "limit") && !rf.Count:
+			n, ok := toUint(v)
+			if !ok {
+				return req, fmt.Errorf("argument %q of %q must be a non-negative Int", a.Name, rf.Name)
+			}
+			if a.Name == "offset" {
+				req.Offset = n
+			} else {
+				req.Limit = n
+			}
+		default:
+			return req, fmt.Errorf("unknown argument %q on field %q", a.Name, rf.Name)
+		}
+	}
+	return req, nil
+}
+
+func toUint(v any) (uint64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return uint64(n), n >= 0
+	case float64:
+		return uint64(n), n >= 0 && n == math.Trunc(n) && n <= math.MaxInt32
+	case json.Number:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return uint64(i), err == nil && i >= 0
+	}
+	return 0, false
+}
+
+// fieldGroup — все вхождения одного ключа ответа в selection set (после фрагментов).
+type fieldGroup struct {
+	key   string
+	name  string
+	field language.Selection
+	sels  []language.Selection
+}
+
+type collector struct {
+	doc     *language.Document
+	vars    map[string]any
+	aliases int // алиасов во всём документе, с учётом повторно раскрытых фрагментов
+}
+
+// collect раскрывает фрагменты и директивы и группирует поля по ключу ответа.
+func (c *collector) collect(typeName string, sels []language.Selection) ([]*fieldGroup, error) {
+	var out []*fieldGroup
+	index := map[string]*fieldGroup{}
+	var walk func(sels []language.Selection, visiting map[string]bool) error
+	walk = func(sels []language.Selection, visiting map[string]bool) error {
+		for _, sel := range sels {
+			include, err := c.included(sel.Directives)
+			if err != nil {
+				return err
+			}
+			if !include {
+				continue
+			}
+			switch sel.Kind {
+			case language.SelectionField:
+				if sel.Alias != "" {
+					if c.aliases++; c.aliases > maxAliases {
+						return fmt.Errorf("document uses more than %d aliases", maxAliases)
+					}
+				}
+				key := sel.ResponseKey()
+				if g, ok := index[key]; ok {
+					if g.name != sel.Name {
+						return fmt.Errorf("fields %q conflict because %s and %s are different fields", key, g.name, sel.Name)
+					}
+					g.sels = append(g.sels, sel.Selections...)
+					continue
+				}
+				g := &fieldGroup{key: key, name: sel.Name, field: sel, sels: append([]language.Selection(nil), sel.Selections...)}
+				index[key] = g
+				out = append(out, g)
+			case language.SelectionFragmentSpread:
+				fr := c.doc.Fragments[sel.Name]
+				if fr == nil {
+					return fmt.Errorf("unknown fragment %q", sel.Name)
+				}
+				if visiting[sel.Name] {
+					return fmt.Errorf("cannot spread fragment %q within itself", sel.Name)
+				}
+				if fr.TypeCondition != typeName {
+					continue
+				}
+				visiting[sel.Name] = true
+				if err := walk(fr.Selections, visiting); err != nil {
+					return err
+				}
+				delete(visiting, sel.Name)
+			case language.SelectionInlineFragment:
+				if sel.TypeCondition != "" && sel.TypeCondition != typeName {
+					continue
+				}
+				if err := walk(sel.Selections, visiting); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(sels, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// included вычисляет @skip/@include.
+func (c *collector) included(dirs []language.Directive) (bool, error) {
+	for _, d := range dirs {
+		if d.Name != "skip" && d.Name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", d.Name)
+		}
+		if len(d.Arguments) != 1 || d.Arguments[0].Name != "if" {
+			return false, fmt.Errorf("directive @%s requires a single \"if\" argument", d.Name)
+		}
+		v, err := language.ResolveValue(d.Arguments[0].Value, c.vars)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := v.(bool)
+		if !ok {
+			return false, fmt.Errorf("argument \"if\" of @%s must be Boolean", d.Name)
+		}
+		if (d.Name == "skip" && cond) || (d.Name == "include" && !cond) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// plan проверяет выборку для типа t и строит дерево fieldPlan; depth —
+// уровень вложенности выборки от корневого поля.
+func (c *collector) plan(s *Schema, t *ObjectType, sels []language.Selection, depth int) ([]*fieldPlan, error) {
+	if depth > maxSelectionDepth {
+		return nil, fmt.Errorf("selection is nested deeper than %d levels", maxSelectionDepth)
+	}
+	groups, err := c.collect(t.Name, sels)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*fieldPlan, 0, len(groups))
+	for _, g := range groups {
+		if len(g.field.Arguments) > 0 {
+			return nil, fmt.Errorf("unknown argument %q on field \"%s.%s\"", g.field.Arguments[0].Name, t.Name, g.name)
+		}
+		if g.name == "__typename" {
+			out = append(out, &fieldPlan{Key: g.key, Name: g.name})
+			continue
+		}
+		def := t.Fields[g.name]
+		if def == nil {
+			return nil, fmt.Errorf("cannot query field %q on type %q", g.name, t.Name)
+		}
+		fp := &fieldPlan{Key: g.key, Name: g.name, Def: def}
+		switch {
+		case def.IsRelation() && len(g.sels) == 0:
+			return nil, fmt.Errorf("field \"%s.%s\" of type %q must have a selection of subfields", t.Name, g.name, def.Type)
+		case !def.IsRelation() && len(g.sels) > 0:
+			return nil, fmt.Errorf("field \"%s.%s\" of type %q must not have a selection set", t.Name, g.name, def.Type)
+		case def.IsRelation():
+			if fp.Children, err = c.plan(s, s.Types[def.Target], g.sels, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, fp)
+	}
+	return out, nil
+}
+
+// buildPreset собирает синтетический пресет модели из планов полей.
+// Одна и та же связь под разными алиасами даёт одно preset-поле с объединённой
+// выборкой. Имя пресета — хэш его формы, чтобы кэш карт алиасов переиспользовался
+// для одинаковых запросов.
+func buildPreset(s *Schema, t *ObjectType, plans []*fieldPlan) (*model.DataPreset, error) {
+	dp := &model.DataPreset{}
+	have := map[string]bool{}
+	addField := func(f model.Field) {
+		if have[f.Alias] {
+			return
+		}
+		have[f.Alias] = true
+		dp.Fields = append(dp.Fields, f)
+	}
+	var deps []model.Field
+	columns := 0
+	relChildren := map[string][]*fieldPlan{}
+	var relOrder []string
+	for _, fp := range plans {
+		switch {
+		case fp.Def == nil:
+			continue
+		case fp.Def.IsRelation():
+			if _, ok := relChildren[fp.Name]; !ok {
+				relOrder = append(relOrder, fp.Name)
+			}
+			relChildren[fp.Name] = append(relChildren[fp.Name], fp.Children...)
+		default:
+			addField(fp.Def.Template)
+			deps = append(deps, fp.Def.Deps...)
+			if fp.Def.Template.Type != "formatter" {
+				columns++
+			}
+		}
+	}
+	for _, f := range deps {
+		if f.Type != "formatter" {
+			columns++
+		}
+		addField(f)
+	}
+	// без колонок belongs_to нельзя отличить от пустого — добавляем скрытый PK
+	if columns == 0 {
+		for _, pk := range t.Model.GetPrimaryKeys() {
+			f := pkField(t.Model, pk)
+			f.Internal = true
+			addField(f)
+		}
+	}
+	for _, name := range relOrder {
+		def := t.Fields[name]
+		nested, err := buildPreset(s, s.Types[def.Target], relChildren[name])
+		if err != nil {
+			return nil, err
+		}
+		f := model.Field{Source: name, Alias: name, Type: "preset", NestedPreset: nested.Name}
+		f.SetPresetRef(nested)
+		addField(f)
+	}
+
+	dp.Name = presetName(dp)
+	if err := model.BuildSyntheticPresetAliasMap(t.Model, dp); err != nil {
+		return nil, err
+	}
+	return dp, nil
+}
+
+func presetName(dp *model.DataPreset) string {
+	var b strings.Builder
+	for _, f := range dp.Fields {
+		fmt.Fprintf(&b, "%q %q %q %q %q %t %t\n", f.Source, f.Alias, f.Type, f.Formatter, f.NestedPreset, f.Internal, f.Localize)
+	}
+	sum := sha1.Sum([]byte(b.String()))
+	return "graphql_" + hex.EncodeToString(sum[:8])
+}
+
+// executeRoot исполняет корень; синтетический пресет уже лежит в root.Req.
+func executeRoot(ctx context.Context, s *Schema, root *rootPlan) (any, error) {
+	if root.Field == nil {
+		return "Query", nil
+	}
+	t := s.Types[root.Field.Model]
+	req := root.Req
+	if root.Field.Count {
+		return resolver.ResolveCount(ctx, req)
+	}
+	items, err := resolver.Resolver(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Object, len(items))
+	for i, item := range items {
+		out[i] = complete(s, t, root.Children, item)
+	}
+	return out, nil
+}
+
+// complete строит объект ответа из элемента резолвера по плану выборки.
+func complete(s *Schema, t *ObjectType, plans []*fieldPlan, item map[string]any) *Object {
+	obj := newObject(len(plans))
+	for _, fp := range plans {
+		switch {
+		case fp.Def == nil:
+			obj.set(fp.Key, t.Name)
+		case !fp.Def.IsRelation():
+			obj.set(fp.Key, item[fp.Def.Template.Alias])
+		default:
+			target := s.Types[fp.Def.Target]
+			switch v := item[fp.Name].(type) {
+			case map[string]any:
+				if fp.Def.Relation.Type == "has_many" {
+					obj.set(fp.Key, []*Object{complete(s, target, fp.Children, v)})
+				} else {
+					obj.set(fp.Key, complete(s, target, fp.Children, v))
+				}
+			case []map[string]any:
+				list := make([]*Object, len(v))
+				for i, row := range v {
+					list[i] = complete(s, target, fp.Children, row)
+				}
+				obj.set(fp.Key, list)
+			case []any:
+				list := make([]*Object, 0, len(v))
+				for _, row := range v {
+					if m, ok := row.(map[string]any); ok {
+						list = append(list, complete(s, target, fp.Children, m))
+					}
+				}
+				obj.set(fp.Key, list)
+			default:
+				if fp.Def.Relation.Type == "has_many" {
+					obj.set(fp.Key, []*Object{})
+				} else {
+					obj.set(fp.Key, nil)
+				}
+			}
+		}
+	}
+	return obj
+}
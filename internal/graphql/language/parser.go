@@ -0,0 +1,799 @@
+// Package language — общий лексер и парсер GraphQL-документов: им пользуются
+// исполнитель /graphql (internal/graphql) и импорт пресетов (graphqlimport).
+package language
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document — разобранный GraphQL-документ: операции и именованные фрагменты.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation — query-операция (mutation/subscription разбираются, но не исполняются).
+type Operation struct {
+	Type       string // query, mutation, subscription
+	Name       string
+	Variables  []VariableDef
+	Directives []Directive
+	Selections []Selection
+}
+
+// VariableDef — объявление переменной операции: $name: Type = default.
+type VariableDef struct {
+	Name    string
+	Type    string // как в запросе, напр. "[String!]!"
+	Default *Value
+}
+
+// Fragment — именованный фрагмент: fragment Name on Type { ... }.
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []Directive
+	Selections    []Selection
+}
+
+// SelectionKind различает поле, ...Fragment и inline-фрагмент.
+type SelectionKind int
+
+const (
+	SelectionField SelectionKind = iota
+	SelectionFragmentSpread
+	SelectionInlineFragment
+)
+
+// Selection — элемент selection set.
+type Selection struct {
+	Kind          SelectionKind
+	Alias         string // только для полей
+	Name          string // имя поля или фрагмента
+	Arguments     []Argument
+	Directives    []Directive
+	Selections    []Selection
+	TypeCondition string // только для inline-фрагментов
+}
+
+// ResponseKey — ключ поля в ответе (алиас, если задан).
+func (s Selection) ResponseKey() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+// Argument — аргумент поля/директивы или поле объектного литерала.
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// Directive — @name(args).
+type Directive struct {
+	Name      string
+	Arguments []Argument
+}
+
+// ValueKind — вид литерала GraphQL.
+type ValueKind int
+
+const (
+	ValueVariable ValueKind = iota
+	ValueInt
+	ValueFloat
+	ValueString
+	ValueBoolean
+	ValueNull
+	ValueEnum
+	ValueList
+	ValueObject
+)
+
+// Value — литерал или ссылка на переменную.
+type Value struct {
+	Kind   ValueKind
+	Raw    string // имя переменной, текст числа/enum, строка без кавычек, "true"/"false"
+	List   []Value
+	Fields []Argument
+}
+
+// Parse разбирает executable-документ (операции и фрагменты).
+// Определения схемы (type, schema, ...) не поддерживаются.
+func Parse(src string) (*Document, error) {
+	p := &parser{lx: &lexer{src: src}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.cur.typ != tokEOF {
+		switch {
+		case p.cur.is(tokPunct, "{"):
+			sels, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", Selections: sels})
+		case p.cur.is(tokName, "query"), p.cur.is(tokName, "mutation"), p.cur.is(tokName, "subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.cur.is(tokName, "fragment"):
+			fr, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.Fragments[fr.Name]; dup {
+				return nil, fmt.Errorf("there can be only one fragment named %q", fr.Name)
+			}
+			doc.Fragments[fr.Name] = fr
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document does not contain any operations")
+	}
+	return doc, nil
+}
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokName
+	tokInt
+	tokFloat
+	tokString
+	tokPunct
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) is(typ tokenType, val string) bool {
+	return t.typ == typ && t.val == val
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "<EOF>"
+	case tokString:
+		return strconv.Quote(t.val)
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (lx *lexer) next() (token, error) {
+	lx.skipIgnored()
+	if lx.pos >= len(lx.src) {
+		return token{typ: tokEOF, pos: lx.pos}, nil
+	}
+	start := lx.pos
+	c := lx.src[lx.pos]
+	switch {
+	case strings.HasPrefix(lx.src[lx.pos:], "..."):
+		lx.pos += 3
+		return token{typ: tokPunct, val: "...", pos: start}, nil
+	case strings.ContainsRune("!$&():=@[]{}|", rune(c)):
+		lx.pos++
+		return token{typ: tokPunct, val: string(c), pos: start}, nil
+	case isNameStart(c):
+		for lx.pos < len(lx.src) && isNamePart(lx.src[lx.pos]) {
+			lx.pos++
+		}
+		return token{typ: tokName, val: lx.src[start:lx.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		return lx.number()
+	case c == '"':
+		if strings.HasPrefix(lx.src[lx.pos:], `"""`) {
+			return lx.blockString()
+		}
+		return lx.string()
+	}
+	r, _ := utf8.DecodeRuneInString(lx.src[lx.pos:])
+	return token{}, fmt.Errorf("syntax error at %d: unexpected character %q", start, r)
+}
+
+// skipIgnored пропускает пробелы, запятые, BOM и комментарии.
+func (lx *lexer) skipIgnored() {
+	for lx.pos < len(lx.src) {
+		switch c := lx.src[lx.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			lx.pos++
+		case c == '#':
+			for lx.pos < len(lx.src) && lx.src[lx.pos] != '\n' && lx.src[lx.pos] != '\r' {
+				lx.pos++
+			}
+		case strings.HasPrefix(lx.src[lx.pos:], "\uFEFF"):
+			lx.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+func (lx *lexer) number() (token, error) {
+	start := lx.pos
+	if lx.src[lx.pos] == '-' {
+		lx.pos++
+	}
+	digits := func() int {
+		n := 0
+		for lx.pos < len(lx.src) && isDigit(lx.src[lx.pos]) {
+			lx.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return token{}, fmt.Errorf("syntax error at %d: invalid number", start)
+	}
+	typ := tokInt
+	if lx.pos < len(lx.src) && lx.src[lx.pos] == '.' {
+		lx.pos++
+		typ = tokFloat
+		if digits() == 0 {
+			return token{}, fmt.Errorf("syntax error at %d: invalid number", start)
+		}
+	}
+	if lx.pos < len(lx.src) && (lx.src[lx.pos] == 'e' || lx.src[lx.pos] == 'E') {
+		lx.pos++
+		typ = tokFloat
+		if lx.pos < len(lx.src) && (lx.src[lx.pos] == '+' || lx.src[lx.pos] == '-') {
+			lx.pos++
+		}
+		if digits() == 0 {
+			return token{}, fmt.Errorf("syntax error at %d: invalid number", start)
+		}
+	}
+	if lx.pos < len(lx.src) && (isNameStart(lx.src[lx.pos]) || lx.src[lx.pos] == '.') {
+		return token{}, fmt.Errorf("syntax error at %d: invalid number", start)
+	}
+	return token{typ: typ, val: lx.src[start:lx.pos], pos: start}, nil
+}
+
+func (lx *lexer) string() (token, error) {
+	start := lx.pos
+	lx.pos++ // открывающая кавычка
+	var b strings.Builder
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		switch {
+		case c == '"':
+			lx.pos++
+			return token{typ: tokString, val: b.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+		case c == '\\':
+			if lx.pos+1 >= len(lx.src) {
+				return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+			}
+			esc := lx.src[lx.pos+1]
+			lx.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if lx.pos+4 > len(lx.src) {
+					return token{}, fmt.Errorf("syntax error at %d: invalid unicode escape", lx.pos)
+				}
+				code, err := strconv.ParseUint(lx.src[lx.pos:lx.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("syntax error at %d: invalid unicode escape", lx.pos)
+				}
+				b.WriteRune(rune(code))
+				lx.pos += 4
+			default:
+				return token{}, fmt.Errorf("syntax error at %d: invalid escape \\%c", lx.pos-2, esc)
+			}
+		default:
+			b.WriteByte(c)
+			lx.pos++
+		}
+	}
+	return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+}
+
+func (lx *lexer) blockString() (token, error) {
+	start := lx.pos
+	lx.pos += 3
+	var b strings.Builder
+	for lx.pos < len(lx.src) {
+		switch {
+		case strings.HasPrefix(lx.src[lx.pos:], `"""`):
+			lx.pos += 3
+			return token{typ: tokString, val: blockStringValue(b.String()), pos: start}, nil
+		case strings.HasPrefix(lx.src[lx.pos:], `\"""`):
+			b.WriteString(`"""`)
+			lx.pos += 4
+		default:
+			b.WriteByte(lx.src[lx.pos])
+			lx.pos++
+		}
+	}
+	return token{}, fmt.Errorf("syntax error at %d: unterminated block string", start)
+}
+
+// blockStringValue убирает общий отступ и пустые крайние строки (BlockStringValue из спецификации).
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(raw, "\r\n", "\n"), "\r", "\n"), "\n")
+	common := -1
+	for _, l := range lines[1:] {
+		indent := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < len(l) && (common < 0 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= common {
+				lines[i] = lines[i][common:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	lx  *lexer
+	cur token
+}
+
+func (p *parser) next() error {
+	t, err := p.lx.next()
+	if err != nil {
+		return err
+	}
+	p.cur = t
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf("syntax error at %d: unexpected %s", p.cur.pos, p.cur)
+}
+
+func (p *parser) expect(val string) error {
+	if !p.cur.is(tokPunct, val) {
+		return fmt.Errorf("syntax error at %d: expected %q, got %s", p.cur.pos, val, p.cur)
+	}
+	return p.next()
+}
+
+func (p *parser) skip(val string) (bool, error) {
+	if !p.cur.is(tokPunct, val) {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+func (p *parser) name() (string, error) {
+	if p.cur.typ != tokName {
+		return "", fmt.Errorf("syntax error at %d: expected name, got %s", p.cur.pos, p.cur)
+	}
+	v := p.cur.val
+	return v, p.next()
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Type: p.cur.val}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.cur.typ == tokName {
+		op.Name = p.cur.val
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.cur.is(tokPunct, ")") {
+			def, err := p.parseVariableDef()
+			if err != nil {
+				return nil, err
+			}
+			op.Variables = append(op.Variables, def)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if op.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if op.Selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) parseVariableDef() (VariableDef, error) {
+	if err := p.expect("$"); err != nil {
+		return VariableDef{}, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return VariableDef{}, err
+	}
+	if err := p.expect(":"); err != nil {
+		return VariableDef{}, err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return VariableDef{}, err
+	}
+	def := VariableDef{Name: name, Type: typ}
+	if ok, err := p.skip("="); err != nil {
+		return VariableDef{}, err
+	} else if ok {
+		v, err := p.parseValue(true)
+		if err != nil {
+			return VariableDef{}, err
+		}
+		def.Default = &v
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return VariableDef{}, err
+	}
+	return def, nil
+}
+
+func (p *parser) parseType() (string, error) {
+	var typ string
+	if ok, err := p.skip("["); err != nil {
+		return "", err
+	} else if ok {
+		inner, err := p.parseType()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if ok, err := p.skip("!"); err != nil {
+		return "", err
+	} else if ok {
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *parser) parseFragment() (*Fragment, error) {
+	if err := p.next(); err != nil { // "fragment"
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("syntax error at %d: fragment cannot be named \"on\"", p.cur.pos)
+	}
+	if !p.cur.is(tokName, "on") {
+		return nil, fmt.Errorf("syntax error at %d: expected \"on\", got %s", p.cur.pos, p.cur)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	fr := &Fragment{Name: name}
+	if fr.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if fr.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if fr.Selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return fr, nil
+}
+
+func (p *parser) parseSelectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var out []Selection
+	for !p.cur.is(tokPunct, "}") {
+		if p.cur.typ == tokEOF {
+			return nil, p.unexpected()
+		}
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sel)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("syntax error at %d: empty selection set", p.cur.pos)
+	}
+	return out, p.next()
+}
+
+func (p *parser) parseSelection() (Selection, error) {
+	var err error
+	if ok, err := p.skip("..."); err != nil {
+		return Selection{}, err
+	} else if ok {
+		if p.cur.typ == tokName && p.cur.val != "on" {
+			sel := Selection{Kind: SelectionFragmentSpread, Name: p.cur.val}
+			if err := p.next(); err != nil {
+				return Selection{}, err
+			}
+			sel.Directives, err = p.parseDirectives()
+			return sel, err
+		}
+		sel := Selection{Kind: SelectionInlineFragment}
+		if p.cur.is(tokName, "on") {
+			if err := p.next(); err != nil {
+				return Selection{}, err
+			}
+			if sel.TypeCondition, err = p.name(); err != nil {
+				return Selection{}, err
+			}
+		}
+		if sel.Directives, err = p.parseDirectives(); err != nil {
+			return Selection{}, err
+		}
+		sel.Selections, err = p.parseSelectionSet()
+		return sel, err
+	}
+
+	sel := Selection{Kind: SelectionField}
+	if sel.Name, err = p.name(); err != nil {
+		return Selection{}, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return Selection{}, err
+	} else if ok {
+		sel.Alias = sel.Name
+		if sel.Name, err = p.name(); err != nil {
+			return Selection{}, err
+		}
+	}
+	if sel.Arguments, err = p.parseArguments(false); err != nil {
+		return Selection{}, err
+	}
+	if sel.Directives, err = p.parseDirectives(); err != nil {
+		return Selection{}, err
+	}
+	if p.cur.is(tokPunct, "{") {
+		if sel.Selections, err = p.parseSelectionSet(); err != nil {
+			return Selection{}, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *parser) parseArguments(constant bool) ([]Argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var out []Argument
+	for !p.cur.is(tokPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Argument{Name: name, Value: v})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("syntax error at %d: empty argument list", p.cur.pos)
+	}
+	return out, p.next()
+}
+
+func (p *parser) parseDirectives() ([]Directive, error) {
+	var out []Directive
+	for p.cur.is(tokPunct, "@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments(false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Directive{Name: name, Arguments: args})
+	}
+	return out, nil
+}
+
+func (p *parser) parseValue(constant bool) (Value, error) {
+	t := p.cur
+	switch t.typ {
+	case tokInt:
+		return Value{Kind: ValueInt, Raw: t.val}, p.next()
+	case tokFloat:
+		return Value{Kind: ValueFloat, Raw: t.val}, p.next()
+	case tokString:
+		return Value{Kind: ValueString, Raw: t.val}, p.next()
+	case tokName:
+		switch t.val {
+		case "true", "false":
+			return Value{Kind: ValueBoolean, Raw: t.val}, p.next()
+		case "null":
+			return Value{Kind: ValueNull}, p.next()
+		}
+		return Value{Kind: ValueEnum, Raw: t.val}, p.next()
+	case tokPunct:
+		switch t.val {
+		case "$":
+			if constant {
+				return Value{}, fmt.Errorf("syntax error at %d: variables are not allowed here", t.pos)
+			}
+			if err := p.next(); err != nil {
+				return Value{}, err
+			}
+			name, err := p.name()
+			return Value{Kind: ValueVariable, Raw: name}, err
+		case "[":
+			if err := p.next(); err != nil {
+				return Value{}, err
+			}
+			v := Value{Kind: ValueList, List: []Value{}}
+			for !p.cur.is(tokPunct, "]") {
+				item, err := p.parseValue(constant)
+				if err != nil {
+					return Value{}, err
+				}
+				v.List = append(v.List, item)
+			}
+			return v, p.next()
+		case "{":
+			if err := p.next(); err != nil {
+				return Value{}, err
+			}
+			v := Value{Kind: ValueObject, Fields: []Argument{}}
+			for !p.cur.is(tokPunct, "}") {
+				name, err := p.name()
+				if err != nil {
+					return Value{}, err
+				}
+				if err := p.expect(":"); err != nil {
+					return Value{}, err
+				}
+				item, err := p.parseValue(constant)
+				if err != nil {
+					return Value{}, err
+				}
+				v.Fields = append(v.Fields, Argument{Name: name, Value: item})
+			}
+			return v, p.next()
+		}
+	}
+	return Value{}, p.unexpected()
+}
+
+// ResolveValue превращает литерал в Go-значение (как после json.Unmarshal),
+// подставляя переменные.
+func ResolveValue(v Value, vars map[string]any) (any, error) {
+	switch v.Kind {
+	case ValueVariable:
+		val, ok := vars[v.Raw]
+		if !ok {
+			return nil, nil
+		}
+		return val, nil
+	case ValueInt:
+		n, err := strconv.ParseInt(v.Raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Int literal %s", v.Raw)
+		}
+		return n, nil
+	case ValueFloat:
+		f, err := strconv.ParseFloat(v.Raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Float literal %s", v.Raw)
+		}
+		return f, nil
+	case ValueString, ValueEnum:
+		return v.Raw, nil
+	case ValueBoolean:
+		return v.Raw == "true", nil
+	case ValueNull:
+		return nil, nil
+	case ValueList:
+		out := make([]any, len(v.List))
+		for i, item := range v.List {
+			val, err := ResolveValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = val
+		}
+		return out, nil
+	case ValueObject:
+		out := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			val, err := ResolveValue(f.Value, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[f.Name] = val
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported value")
+}
+
+// VariableRefs собирает имена переменных, на которые ссылается значение.
+func VariableRefs(v Value, out map[string]struct{}) {
+	switch v.Kind {
+	case ValueVariable:
+		out[v.Raw] = struct{}{}
+	case ValueList:
+		for _, item := range v.List {
+			VariableRefs(item, out)
+		}
+	case ValueObject:
+		for _, f := range v.Fields {
+			VariableRefs(f.Value, out)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package language
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOperationWithVariablesAndFragments(t *testing.T) {
+	doc, err := Parse(`
+		# комментарий
+		query People($limit: Int = 10, $withContacts: Boolean!) {
+			list: person(filters: {last_name__cnt: "Sm", id__in: [1, 2]}, limit: $limit, sorts: "id DESC") {
+				...Base
+				contacts @include(if: $withContacts) { kind value }
+				... on Person { __typename }
+			}
+		}
+		fragment Base on Person { id full_name }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Operations) != 1 || len(doc.Fragments) != 1 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	op := doc.Operations[0]
+	if op.Type != "query" || op.Name != "People" || len(op.Variables) != 2 {
+		t.Fatalf("unexpected operation: %+v", op)
+	}
+	if op.Variables[0].Type != "Int" || op.Variables[0].Default == nil || op.Variables[1].Type != "Boolean!" {
+		t.Fatalf("unexpected variables: %+v", op.Variables)
+	}
+	root := op.Selections[0]
+	if root.Alias != "list" || root.Name != "person" || root.ResponseKey() != "list" {
+		t.Fatalf("unexpected root selection: %+v", root)
+	}
+	filters, err := ResolveValue(root.Arguments[0].Value, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"last_name__cnt": "Sm", "id__in": []any{int64(1), int64(2)}}
+	if !reflect.DeepEqual(filters, want) {
+		t.Fatalf("filters mismatch: got %#v, want %#v", filters, want)
+	}
+	limit, _ := ResolveValue(root.Arguments[1].Value, map[string]any{"limit": float64(5)})
+	if limit != float64(5) {
+		t.Fatalf("variable not substituted: %#v", limit)
+	}
+	kinds := []SelectionKind{}
+	for _, sel := range root.Selections {
+		kinds = append(kinds, sel.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []SelectionKind{SelectionFragmentSpread, SelectionField, SelectionInlineFragment}) {
+		t.Fatalf("unexpected selection kinds: %v", kinds)
+	}
+	if root.Selections[1].Directives[0].Name != "include" {
+		t.Fatalf("directive not parsed: %+v", root.Selections[1])
+	}
+}
+
+func TestParseShorthandAndStrings(t *testing.T) {
+	doc, err := Parse(`{ person(filters: {name: "a\"bA", note: """
+		line one
+		  line two
+	"""}) { id } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := ResolveValue(doc.Operations[0].Selections[0].Arguments[0].Value, nil)
+	got := v.(map[string]any)
+	if got["name"] != `a"bA` || got["note"] != "line one\n  line two" {
+		t.Fatalf("unexpected strings: %#v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`{ person { id }`,
+		`{ person(limit: ) { id } }`,
+		`{ person { } }`,
+		`query ($a: Int = $b) { person { id } }`,
+		`fragment F on Person { id }`,
+		`{ person(limit: 1.) { id } }`,
+		`{ person(name: "open) { id } }`,
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Fatalf("expected error for %q", src)
+		} else if !strings.Contains(err.Error(), "error") && !strings.Contains(err.Error(), "operations") {
+			t.Fatalf("unexpected error text for %q: %v", src, err)
+		}
+	}
+}
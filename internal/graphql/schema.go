@@ -0,0 +1,343 @@
+package graphql
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"YrestAPI/internal/model"
+)
+
+// Schema — GraphQL-схема, построенная из реестра моделей.
+// Каждая модель — объектный тип; скалярные поля — то, что публикуют её пресеты
+// (плюс первичные ключи и computable), связи — поля с типом связанной модели.
+type Schema struct {
+	Types     map[string]*ObjectType
+	Roots     map[string]*RootField
+	typeNames []string
+	rootNames []string
+}
+
+// ObjectType — тип, соответствующий модели.
+type ObjectType struct {
+	Name   string
+	Model  *model.Model
+	Fields map[string]*FieldDef
+	order  []string
+}
+
+// FieldDef — поле объектного типа: скаляр (Template) или связь (Relation).
+type FieldDef struct {
+	Name     string
+	Type     string      // тип в SDL, напр. "Int", "Contact", "[Contact!]!"
+	Template model.Field // поле пресета, которое вставляется в синтетический пресет
+	Deps     []model.Field
+	Relation *model.ModelRelation
+	Target   string // имя типа связанной модели
+}
+
+// IsRelation сообщает, что поле требует selection set.
+func (f *FieldDef) IsRelation() bool { return f.Relation != nil }
+
+// RootField — поле Query: список записей модели или их количество.
+type RootField struct {
+	Name  string
+	Model string
+	Count bool
+}
+
+var graphqlName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// placeholderRe — подстановки форматтера, как в model.FormatTemplate.
+var placeholderRe = regexp.MustCompile(`\{([\w\.]+)`)
+
+// BuildSchema строит схему из реестра. Полиморфные связи и поля с именами,
+// недопустимыми в GraphQL, пропускаются.
+func BuildSchema(registry map[string]*model.Model) *Schema {
+	s := &Schema{Types: map[string]*ObjectType{}, Roots: map[string]*RootField{}}
+	names := make([]string, 0, len(registry))
+	for name, m := range registry {
+		if m != nil && graphqlName.MatchString(name) && !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s.Types[name] = &ObjectType{Name: name, Model: registry[name], Fields: map[string]*FieldDef{}}
+		s.typeNames = append(s.typeNames, name)
+	}
+	for _, name := range names {
+		t := s.Types[name]
+		addRelationFields(s, t)
+		addScalarFields(t)
+		sort.Strings(t.order)
+
+		list := RootFieldName(name)
+		for _, root := range []*RootField{
+			{Name: list, Model: name},
+			{Name: list + "_count", Model: name, Count: true},
+		} {
+			if _, dup := s.Roots[root.Name]; dup {
+				continue
+			}
+			s.Roots[root.Name] = root
+			s.rootNames = append(s.rootNames, root.Name)
+		}
+	}
+	sort.Strings(s.rootNames)
+	return s
+}
+
+func addRelationFields(s *Schema, t *ObjectType) {
+	for relName, rel := range t.Model.Relations {
+		if rel == nil || rel.Polymorphic || !graphqlName.MatchString(relName) || strings.HasPrefix(relName, "__") {
+			continue
+		}
+		target := rel.GetModelRef()
+		if target == nil || s.Types[target.Name] == nil {
+			continue
+		}
+		typ := target.Name
+		if rel.Type == "has_many" {
+			typ = "[" + typ + "!]!"
+		} else if rel.Type != "belongs_to" && rel.Type != "has_one" {
+			continue
+		}
+		t.Fields[relName] = &FieldDef{Name: relName, Type: typ, Relation: rel, Target: target.Name}
+		t.order = append(t.order, relName)
+	}
+}
+
+func addScalarFields(t *ObjectType) {
+	m := t.Model
+	add := func(name string, def *FieldDef) {
+		if _, exists := t.Fields[name]; exists || !graphqlName.MatchString(name) || strings.HasPrefix(name, "__") {
+			return
+		}
+		def.Name = name
+		t.Fields[name] = def
+		t.order = append(t.order, name)
+	}
+
+	// поля пресетов: в детерминированном порядке, первый по имени пресета выигрывает
+	presetNames := make([]string, 0, len(m.Presets))
+	for name := range m.Presets {
+		presetNames = append(presetNames, name)
+	}
+	sort.Strings(presetNames)
+	for _, pn := range presetNames {
+		p := m.Presets[pn]
+		if p == nil {
+			continue
+		}
+		for _, f := range p.Fields {
+			if f.Internal {
+				continue
+			}
+			key := fieldKey(f)
+			switch f.Type {
+			case "formatter":
+				deps, ok := formatterDeps(p, f)
+				if !ok {
+					continue
+				}
+				add(key, &FieldDef{Type: "String", Template: templateField(f), Deps: deps})
+			case "computable":
+				comp := m.Computable[f.Source]
+				if comp == nil {
+					continue
+				}
+				typ := comp.Type
+				if strings.TrimSpace(typ) == "" {
+					typ = f.Type
+				}
+				add(key, &FieldDef{Type: scalarType(typ), Template: templateField(f)})
+			default:
+				if !isColumnType(f.Type) {
+					continue
+				}
+				add(key, &FieldDef{Type: scalarType(f.Type), Template: templateField(f)})
+			}
+		}
+	}
+
+	compNames := make([]string, 0, len(m.Computable))
+	for name := range m.Computable {
+		compNames = append(compNames, name)
+	}
+	sort.Strings(compNames)
+	for _, name := range compNames {
+		comp := m.Computable[name]
+		if comp == nil {
+			continue
+		}
+		add(name, &FieldDef{
+			Type:     scalarType(comp.Type),
+			Template: model.Field{Source: name, Alias: name, Type: "computable"},
+		})
+	}
+
+	for _, pk := range m.GetPrimaryKeys() {
+		if _, exists := t.Fields[pk]; exists {
+			continue
+		}
+		add(pk, &FieldDef{Type: scalarType("int"), Template: pkField(m, pk)})
+	}
+}
+
+// fieldKey — ключ поля в ответе пресета (alias или source).
+func fieldKey(f model.Field) string {
+	if strings.TrimSpace(f.Alias) != "" {
+		return f.Alias
+	}
+	return f.Source
+}
+
+// templateField копирует поле пресета без runtime-ссылок и флага internal.
+func templateField(f model.Field) model.Field {
+	return model.Field{
+		Source:    f.Source,
+		Formatter: f.Formatter,
+		Alias:     fieldKey(f),
+		Type:      f.Type,
+		Localize:  f.Localize,
+		VisibleTo: f.VisibleTo,
+		Mask:      f.Mask,
+	}
+}
+
+// pkField — поле первичного ключа с типом из пресетов модели (по умолчанию int).
+func pkField(m *model.Model, pk string) model.Field {
+	typ := "int"
+	for _, p := range m.Presets {
+		for _, f := range p.Fields {
+			if f.Source == pk && isColumnType(f.Type) {
+				typ = f.Type
+			}
+		}
+	}
+	return model.Field{Source: pk, Alias: pk, Type: typ}
+}
+
+// formatterDeps возвращает поля того же пресета, которые нужны форматтеру,
+// в порядке зависимостей. Форматтеры со ссылками на вложенные пресеты
+// (напр. {email.value}) в схему не попадают.
+func formatterDeps(p *model.DataPreset, f model.Field) ([]model.Field, bool) {
+	byKey := make(map[string]model.Field, len(p.Fields))
+	for _, pf := range p.Fields {
+		if pf.Type == "formatter" || pf.Type == "computable" || isColumnType(pf.Type) {
+			byKey[fieldKey(pf)] = pf
+		}
+	}
+	var out []model.Field
+	seen := map[string]bool{fieldKey(f): true}
+	var walk func(tpl string) bool
+	walk = func(tpl string) bool {
+		keys := []string{}
+		for _, m := range placeholderRe.FindAllStringSubmatch(tpl, -1) {
+			keys = append(keys, m[1])
+		}
+		if strings.Contains(tpl, "{?") {
+			// условия тернарников ссылаются на поля без скобок — берём все поля пресета
+			for _, pf := range p.Fields {
+				if _, ok := byKey[fieldKey(pf)]; ok {
+					keys = append(keys, fieldKey(pf))
+				}
+			}
+		}
+		for _, key := range keys {
+			if strings.Contains(key, ".") {
+				return false
+			}
+			if seen[key] {
+				continue
+			}
+			dep, ok := byKey[key]
+			if !ok {
+				return false
+			}
+			seen[key] = true
+			if dep.Type == "formatter" && !walk(dep.Source) {
+				return false
+			}
+			tf := templateField(dep)
+			tf.Internal = true
+			out = append(out, tf)
+		}
+		return true
+	}
+	if !walk(f.Source) {
+		return nil, false
+	}
+	return out, true
+}
+
+func isColumnType(t string) bool {
+	switch t {
+	case "int", "string", "bool", "float", "UUID", "time", "datetime", "date":
+		return true
+	}
+	return false
+}
+
+// scalarType отображает тип поля YAML в скаляр GraphQL.
+func scalarType(t string) string {
+	switch t {
+	case "int":
+		return "Int"
+	case "float":
+		return "Float"
+	case "bool":
+		return "Boolean"
+	case "UUID":
+		return "ID"
+	}
+	return "String"
+}
+
+// RootFieldName — имя корневого поля для модели: PersonContact -> person_contact
+// (обратное преобразование делает graphqlimport).
+func RootFieldName(modelName string) string {
+	var b strings.Builder
+	runes := []rune(modelName)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SDL печатает схему в языке описания схем GraphQL.
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	b.WriteString("\"\"\"Filter object in the /api/index format, e.g. {\"name__cnt\": \"Ann\"}.\"\"\"\nscalar JSON\n\n")
+	b.WriteString("type Query {\n")
+	for _, name := range s.rootNames {
+		root := s.Roots[name]
+		if root.Count {
+			fmt.Fprintf(&b, "  %s(filters: JSON): Int\n", root.Name)
+		} else {
+			fmt.Fprintf(&b, "  %s(filters: JSON, sorts: [String!], offset: Int, limit: Int): [%s!]\n", root.Name, root.Model)
+		}
+	}
+	b.WriteString("}\n")
+	for _, name := range s.typeNames {
+		t := s.Types[name]
+		fmt.Fprintf(&b, "\ntype %s {\n", t.Name)
+		for _, fn := range t.order {
+			fmt.Fprintf(&b, "  %s: %s\n", fn, t.Fields[fn].Type)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
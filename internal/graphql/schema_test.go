@@ -0,0 +1,276 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"YrestAPI/internal/model"
+	"YrestAPI/internal/resolver"
+)
+
+func schemaFixture() map[string]*model.Model {
+	org := &model.Model{Name: "Organization", Table: "organizations", Presets: map[string]*model.DataPreset{
+		"item": {Name: "item", Fields: []model.Field{{Source: "id", Type: "int"}, {Source: "name", Type: "string"}}},
+	}}
+	contact := &model.Model{Name: "Contact", Table: "contacts", Presets: map[string]*model.DataPreset{
+		"item": {Name: "item", Fields: []model.Field{
+			{Source: "kind", Type: "string"},
+			{Source: "value", Type: "string"},
+			{Source: "{kind}: {value}", Alias: "label", Type: "formatter"},
+		}},
+	}}
+	orgRel := &model.ModelRelation{Type: "belongs_to", Model: "Organization", FK: "organization_id", PK: "id"}
+	orgRel.SetModelRef(org)
+	contactsRel := &model.ModelRelation{Type: "has_many", Model: "Contact", FK: "person_id", PK: "id"}
+	contactsRel.SetModelRef(contact)
+	ownerRel := &model.ModelRelation{Type: "belongs_to", Polymorphic: true, FK: "owner_id", PK: "id"}
+
+	person := &model.Model{
+		Name:  "PersonCard",
+		Table: "people",
+		Relations: map[string]*model.ModelRelation{
+			"organization": orgRel,
+			"contacts":     contactsRel,
+			"owner":        ownerRel,
+		},
+		Computable: map[string]*model.Computable{"contacts_count": {Source: "(SELECT 1)", Type: "int"}},
+		Presets: map[string]*model.DataPreset{
+			"item": {Name: "item", Fields: []model.Field{
+				{Source: "id", Type: "int"},
+				{Source: "first_name", Type: "string", Internal: true},
+				{Source: "last_name", Type: "string"},
+				{Source: "{last_name} {first_name}", Alias: "full_name", Type: "formatter"},
+				{Source: "{full_name} {organization.name}", Alias: "head", Type: "formatter"},
+				{Source: "tags", Type: "array"},
+			}},
+		},
+	}
+	return map[string]*model.Model{"PersonCard": person, "Organization": org, "Contact": contact}
+}
+
+func TestBuildSchemaFromRegistry(t *testing.T) {
+	s := BuildSchema(schemaFixture())
+	p := s.Types["PersonCard"]
+	if p == nil {
+		t.Fatal("PersonCard type missing")
+	}
+	got := map[string]string{}
+	for name, f := range p.Fields {
+		got[name] = f.Type
+	}
+	want := map[string]string{
+		"id":             "Int",
+		"last_name":      "String",
+		"full_name":      "String",
+		"contacts_count": "Int",
+		"organization":   "Organization",
+		"contacts":       "[Contact!]!",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fields mismatch:\n got %v\nwant %v", got, want)
+	}
+	if deps := p.Fields["full_name"].Deps; len(deps) != 2 || !deps[0].Internal {
+		t.Fatalf("formatter deps not collected: %+v", deps)
+	}
+	if s.Roots["person_card"] == nil || !s.Roots["person_card_count"].Count {
+		t.Fatalf("unexpected roots: %v", s.rootNames)
+	}
+	sdl := s.SDL()
+	for _, want := range []string{
+		"person_card(filters: JSON, sorts: [String!], offset: Int, limit: Int): [PersonCard!]",
+		"person_card_count(filters: JSON): Int",
+		"type Contact {\n  id: Int\n  kind: String\n  label: String\n  value: String\n}",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Fatalf("SDL missing %q:\n%s", want, sdl)
+		}
+	}
+}
+
+func TestRootFieldName(t *testing.T) {
+	for in, want := range map[string]string{"Person": "person", "PersonContact": "person_contact", "HTTPServer": "http_server", "Area51": "area51"} {
+		if got := RootFieldName(in); got != want {
+			t.Fatalf("RootFieldName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPrepareBuildsSyntheticPreset(t *testing.T) {
+	s := BuildSchema(schemaFixture())
+	roots, err := prepare(s, Request{
+		Query: `query($n: Int) {
+			people: person_card(limit: $n, sorts: ["last_name ASC"], filters: {id__gt: 1}) {
+				full_name
+				org: organization { name }
+				organization { __typename }
+				emails: contacts { value }
+				contacts { label }
+			}
+		}`,
+		Variables: map[string]any{"n": float64(5)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := roots[0]
+	if root.Key != "people" || root.Req.Model != "PersonCard" || root.Req.Limit != 5 ||
+		!reflect.DeepEqual(root.Req.Sorts, []string{"last_name ASC"}) || root.Req.Filters["id__gt"] != int64(1) {
+		t.Fatalf("unexpected root request: %+v", root)
+	}
+
+	dp, err := buildPreset(s, s.Types["PersonCard"], root.Children)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range dp.Fields {
+		names = append(names, f.Alias)
+	}
+	if want := []string{"full_name", "last_name", "first_name", "organization", "contacts"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("preset fields mismatch: got %v, want %v", names, want)
+	}
+	contacts := dp.Fields[4].GetPresetRef()
+	if contacts == nil || dp.Fields[4].NestedPreset != contacts.Name {
+		t.Fatalf("nested preset not linked: %+v", dp.Fields[4])
+	}
+	var contactFields []string
+	for _, f := range contacts.Fields {
+		contactFields = append(contactFields, f.Alias)
+	}
+	if want := []string{"value", "label", "kind"}; !reflect.DeepEqual(contactFields, want) {
+		t.Fatalf("merged relation selection mismatch: got %v, want %v", contactFields, want)
+	}
+	if dp.FieldsAliasMap == nil || dp.FieldsAliasMap.PathToAlias["organization"] == "" {
+		t.Fatalf("alias map not built: %+v", dp.FieldsAliasMap)
+	}
+
+	again, err := buildPreset(s, s.Types["PersonCard"], root.Children)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dp.Name, "graphql_") || again.Name != dp.Name {
+		t.Fatalf("preset name must be stable: %q vs %q", dp.Name, again.Name)
+	}
+}
+
+func TestCompleteFollowsSelectionOrder(t *testing.T) {
+	s := BuildSchema(schemaFixture())
+	roots, err := prepare(s, Request{Query: `{ person_card { __typename full_name o: organization { name } contacts { kind } } }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := map[string]any{
+		"id":           int64(1),
+		"full_name":    "Smith John",
+		"organization": nil,
+		"contacts":     []map[string]any{{"kind": "email", "value": "x"}},
+	}
+	out, err := json.Marshal(complete(s, s.Types["PersonCard"], roots[0].Children, item))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"__typename":"PersonCard","full_name":"Smith John","o":null,"contacts":[{"kind":"email"}]}`
+	if string(out) != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+}
+
+func TestExecuteValidationErrors(t *testing.T) {
+	s := BuildSchema(schemaFixture())
+	for query, want := range map[string]string{
+		`{ nope { id } }`:                                            `cannot query field "nope"`,
+		`{ person_card { missing } }`:                                `cannot query field "missing" on type "PersonCard"`,
+		`{ person_card { organization } }`:                           "must have a selection of subfields",
+		`{ person_card { id { x } } }`:                               "must not have a selection set",
+		`{ person_card { owner { id } } }`:                           `cannot query field "owner"`,
+		`{ person_card(limit: -1) { id } }`:                          "non-negative Int",
+		`{ person_card(page: 1) { id } }`:                            `unknown argument "page"`,
+		`{ person_card { ...Missing } }`:                             `unknown fragment "Missing"`,
+		`{ person_card { id @include(if: $flag) } }`:                 "variable $flag is not defined",
+		`mutation { person_card { id } }`:                            "only query operations",
+		`{ __schema { types { name } } }`:                            "introspection is not supported",
+		`{ person_card { a: id a: last_name } }`:                     `fields "a" conflict`,
+		`query($id: Int!) { person_card { id } }`:                    "was not provided",
+		`{ person_card_count { id } }`:                               "must not have a selection set",
+		`{ person_card { ...F } } fragment F on PersonCard { ...F }`: "within itself",
+	} {
+		resp := Execute(context.Background(), s, Request{Query: query})
+		if resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, want) {
+			t.Fatalf("query %s: expected error containing %q, got %+v", query, want, resp.Errors)
+		}
+	}
+}
+
+func TestExecuteTypenameAndSkippedFields(t *testing.T) {
+	s := BuildSchema(schemaFixture())
+	resp := Execute(context.Background(), s, Request{Query: `query Q($skip: Boolean = true) { __typename person_card @skip(if: $skip) { id } }`})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %+v", resp.Errors)
+	}
+	out, _ := json.Marshal(resp)
+	if string(out) != `{"data":{"__typename":"Query"}}` {
+		t.Fatalf("unexpected response: %s", out)
+	}
+}
+
+func TestExecuteDocumentLimits(t *testing.T) {
+	reg := schemaFixture()
+	person := reg["PersonCard"]
+	parentRel := &model.ModelRelation{Type: "belongs_to", Model: "PersonCard", FK: "parent_id", PK: "id"}
+	parentRel.SetModelRef(person)
+	person.Relations["parent"] = parentRel
+	s := BuildSchema(reg)
+
+	var roots, aliases strings.Builder
+	for i := 0; i <= maxRootFields; i++ {
+		fmt.Fprintf(&roots, "r%d: __typename ", i)
+	}
+	for i := 0; i <= maxAliases; i++ {
+		fmt.Fprintf(&aliases, "f%d: id ", i)
+	}
+	deep := "id"
+	for i := 0; i <= maxSelectionDepth; i++ {
+		deep = "parent { " + deep + " }"
+	}
+	for query, want := range map[string]string{
+		"{ " + roots.String() + "}":                                "root fields",
+		"{ person_card { " + aliases.String() + "} }":              "aliases",
+		"{ person_card { " + deep + " } }":                         "nested deeper",
+		"{ person_card { ...F } } fragment F on PersonCard { id }": "",
+	} {
+		_, err := prepare(s, Request{Query: query})
+		if want == "" {
+			if err != nil {
+				t.Fatalf("query %s: unexpected %v", query, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("query %s: expected error containing %q, got %v", query, want, err)
+		}
+	}
+}
+
+// Бюджет стоимости действует на документ целиком: два корня в пределах
+// бюджета по отдельности отклоняются вместе, до SQL (db.Pool в тестах nil).
+func TestExecuteChecksCostAcrossRoots(t *testing.T) {
+	origRegistry := model.Registry
+	t.Cleanup(func() {
+		model.Registry = origRegistry
+		resolver.SetCostBudget(resolver.CostBudget{})
+	})
+	model.Registry = schemaFixture()
+	resolver.SetCostBudget(resolver.CostBudget{MaxLimit: 100})
+
+	s := BuildSchema(model.Registry)
+	resp := Execute(context.Background(), s, Request{Query: `{ a: person_card(limit: 60) { id } b: person_card(limit: 60) { id } }`})
+	var costErr *resolver.CostError
+	if resp.Data != nil || !errors.As(resp.Err(), &costErr) || costErr.Part != "limit" {
+		t.Fatalf("expected limit CostError for the whole document, got %+v (err=%v)", resp.Errors, resp.Err())
+	}
+}
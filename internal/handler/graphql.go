@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/graphql"
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/model"
+	"YrestAPI/internal/resolver"
+)
+
+const maxGraphQLBodyBytes = 1 << 20 // тело POST /graphql
+
+// maxCachedSchemas ограничивает кеш схем одного поколения реестра: по одной
+// схеме на набор ролей.
+const maxCachedSchemas = 64
+
+// schemaCache хранит схемы GraphQL текущего поколения реестра по наборам
+// ролей; смена поколения (перезагрузка реестра) сбрасывает кеш.
+var schemaCache struct {
+	sync.Mutex
+	gen     uint64
+	schemas map[string]*graphql.Schema
+}
+
+// cachedSchema возвращает схему для ролей вызывающего, собирая её только
+// при первом обращении в текущем поколении реестра.
+func cachedSchema(reg map[string]*model.Model, gen uint64, roles []string) *graphql.Schema {
+	roles = slices.Clone(roles)
+	slices.Sort(roles)
+	key := strings.Join(slices.Compact(roles), "\x00")
+
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	if s, ok := schemaCache.schemas[key]; ok && schemaCache.gen == gen {
+		return s
+	}
+	if schemaCache.schemas == nil || schemaCache.gen != gen || len(schemaCache.schemas) >= maxCachedSchemas {
+		schemaCache.gen = gen
+		schemaCache.schemas = make(map[string]*graphql.Schema)
+	}
+	s := graphql.BuildSchema(model.VisibleRegistry(reg, roles))
+	schemaCache.schemas[key] = s
+	return s
+}
+
+// GraphQLRootModels returns the models selected by the root fields of a
+// GraphQL request, resolved against the caller's schema; the router uses
+// them to apply per-model rate limits to /graphql.
+func GraphQLRootModels(r *http.Request, req graphql.Request) []string {
+	reg, gen := model.RegistrySnapshot()
+	return graphql.RootModels(cachedSchema(reg, gen, auth.RolesFromContext(r.Context())), req)
+}
+
+// GraphQLHandler executes read-only GraphQL queries against the model registry.
+// POST takes {"query","operationName","variables"}; GET with ?query= executes
+// the query, GET without it returns the schema in SDL. The schema only covers
+// models and presets the caller's roles allow; it is built once per registry
+// generation and role set.
+func GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint := "/graphql"
+	reg, gen := model.RegistrySnapshot()
+	schema := cachedSchema(reg, gen, auth.RolesFromContext(r.Context()))
+	// резолверы ищут модели в том же реестре, по которому собрана схема
+	ctx := resolver.WithRegistryMap(r.Context(), reg)
+
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		if q.Get("query") == "" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = io.WriteString(w, schema.SDL())
+			return
+		}
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if raw := q.Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "Invalid variables: "+err.Error())
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGraphQLBodyBytes))
+		if err != nil {
+			logger.Warn("read_body_failed", map[string]any{
+				"endpoint": endpoint,
+				"error":    err.Error(),
+			})
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			writeGraphQLError(w, status, "Failed to read body: "+err.Error())
+			return
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			logger.Warn("invalid_json", map[string]any{
+				"endpoint": endpoint,
+				"error":    err.Error(),
+			})
+			writeGraphQLError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+			return
+		}
+	default:
+		logger.Warn("method_not_allowed", map[string]any{
+			"endpoint": endpoint,
+			"method":   r.Method,
+		})
+		http.Error(w, "Only GET and POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	logger.Info("request", map[string]any{
+		"endpoint":      endpoint,
+		"query":         req.Query,
+		"operationName": req.OperationName,
+	})
+
+	resp := graphql.Execute(ctx, schema, req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		// запрос не прошёл разбор/валидацию или бюджет — исполнения не было
+		status = http.StatusBadRequest
+		var costErr *resolver.CostError
+		if errors.As(resp.Err(), &costErr) {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	for _, e := range resp.Errors {
+		logger.Warn("graphql_error", map[string]any{
+			"endpoint": endpoint,
+			"path":     e.Path,
+			"error":    e.Message,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Error("write_response_failed", map[string]any{
+			"endpoint": endpoint,
+			"error":    err.Error(),
+		})
+	}
+}
+
+func writeGraphQLError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(graphql.Response{Errors: []graphql.Error{{Message: msg}}})
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"YrestAPI/internal/db"
 	"YrestAPI/internal/logger"
 	"YrestAPI/internal/model"
 	"YrestAPI/internal/resolver"
 
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 )
@@ -47,15 +49,72 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 		"endpoint": "/api/index",
 		"payload":  json.RawMessage(body),
 	})
+	labelRequest(r, req.Model, req.Preset)
+
+	format, err := exportFormat(r, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format != "" {
+		writeIndexExport(w, r, req, format)
+		return
+	}
+
+	if wantsNDJSON(r) {
+		writeIndexStream(w, r, req)
+		return
+	}
+
+	if req.Envelope {
+		env, err := resolver.ResolveEnvelope(r.Context(), req)
+		if err != nil {
+			var accessErr *model.AccessError
+			if errors.As(err, &accessErr) {
+				writeAccessDenied(w, "/api/index", req.Model, req.Preset)
+				return
+			}
+			var costErr *resolver.CostError
+			if errors.As(err, &costErr) {
+				writeCostError(w, "/api/index", costErr)
+				return
+			}
+			if db.IsTimeout(err) {
+				writeQueryTimeout(w, "/api/index", err)
+				return
+			}
+			status := indexErrorStatus(err)
+			logger.Error("resolver_error", map[string]any{
+				"endpoint": "/api/index",
+				"error":    err.Error(),
+			})
+			http.Error(w, "Failed to resolve data: "+err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if env.NextCursor != nil {
+			w.Header().Set("X-Next-Cursor", *env.NextCursor)
+		}
+		if err := json.NewEncoder(w).Encode(env); err != nil {
+			logger.Error("write_response_failed", map[string]any{"endpoint": "/api/index", "error": err.Error()})
+		}
+		return
+	}
 
 	if req.UniqueBy != "" {
 		result, err := resolver.ResolveDistinctValues(r.Context(), req)
 		if err != nil {
-			status := http.StatusInternalServerError
-			var validationErr *model.DistinctValidationError
-			if errors.As(err, &validationErr) {
-				status = http.StatusBadRequest
+			var policyErr *model.PolicyError
+			if errors.As(err, &policyErr) {
+				writePolicyError(w, "/api/index", policyErr)
+				return
+			}
+			var accessErr *model.AccessError
+			if errors.As(err, &accessErr) {
+				writeAccessDenied(w, "/api/index", req.Model, req.Preset)
+				return
 			}
+			status := indexErrorStatus(err)
 			logger.Error("distinct_resolver_error", map[string]any{"endpoint": "/api/index", "error": err.Error()})
 			http.Error(w, "Failed to resolve distinct values: "+err.Error(), status)
 			return
@@ -68,8 +127,45 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Вызываем Resolver
-	result, err := resolver.Resolver(r.Context(), req)
+	page, err := resolver.ResolvePage(r.Context(), req)
 	if err != nil {
+		var cursorErr *resolver.CursorError
+		if errors.As(err, &cursorErr) {
+			logger.Warn("invalid_cursor", map[string]any{
+				"endpoint": "/api/index",
+				"error":    err.Error(),
+			})
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var filterErr *model.FilterError
+		if errors.As(err, &filterErr) {
+			logger.Warn("invalid_filter", map[string]any{
+				"endpoint": "/api/index",
+				"error":    err.Error(),
+			})
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var policyErr *model.PolicyError
+		if errors.As(err, &policyErr) {
+			writePolicyError(w, "/api/index", policyErr)
+			return
+		}
+		var accessErr *model.AccessError
+		if errors.As(err, &accessErr) {
+			writeAccessDenied(w, "/api/index", req.Model, req.Preset)
+			return
+		}
+		var costErr *resolver.CostError
+		if errors.As(err, &costErr) {
+			writeCostError(w, "/api/index", costErr)
+			return
+		}
+		if db.IsTimeout(err) {
+			writeQueryTimeout(w, "/api/index", err)
+			return
+		}
 		logger.Error("resolver_error", map[string]any{
 			"endpoint": "/api/index",
 			"error":    err.Error(),
@@ -80,7 +176,10 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Успешный JSON-ответ
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(result); err != nil {
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	if err := json.NewEncoder(w).Encode(page.Items); err != nil {
 		logger.Error("write_response_failed", map[string]any{
 			"endpoint": "/api/index",
 			"error":    err.Error(),
@@ -88,3 +187,87 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to write response: "+err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// indexErrorStatus maps resolver errors caused by the request payload to 400,
+// unsatisfiable access policies or role restrictions to 403, requests over
+// the cost budget to 422 and queries cut off by the request deadline to 504.
+func indexErrorStatus(err error) int {
+	var cursorErr *resolver.CursorError
+	var validationErr *model.DistinctValidationError
+	var filterErr *model.FilterError
+	var policyErr *model.PolicyError
+	var accessErr *model.AccessError
+	var costErr *resolver.CostError
+	if errors.As(err, &cursorErr) || errors.As(err, &validationErr) || errors.As(err, &filterErr) {
+		return http.StatusBadRequest
+	}
+	if errors.As(err, &policyErr) || errors.As(err, &accessErr) {
+		return http.StatusForbidden
+	}
+	if errors.As(err, &costErr) {
+		return http.StatusUnprocessableEntity
+	}
+	if db.IsTimeout(err) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// writeCostError answers 422 when the request exceeds the query cost budget.
+// The body names the part that is too expensive so the caller can adjust it.
+func writeCostError(w http.ResponseWriter, endpoint string, err *resolver.CostError) {
+	logger.Warn("query_too_expensive", map[string]any{
+		"endpoint": endpoint,
+		"part":     err.Part,
+		"error":    err.Error(),
+	})
+	http.Error(w, "Query too expensive: "+err.Message, http.StatusUnprocessableEntity)
+}
+
+// writePolicyError answers 403 when a row-level policy cannot be applied
+// (no claims or a required claim is missing); the query is never run unfiltered.
+func writePolicyError(w http.ResponseWriter, endpoint string, err error) {
+	logger.Warn("policy_denied", map[string]any{
+		"endpoint": endpoint,
+		"error":    err.Error(),
+	})
+	http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+}
+
+// writeAccessDenied answers 403 when the caller's roles do not allow the model
+// or one of the requested presets. The body never names the preset, so hidden
+// and unknown presets look the same.
+func writeAccessDenied(w http.ResponseWriter, endpoint, modelName, presetName string) {
+	logger.Warn("access_denied", map[string]any{
+		"endpoint": endpoint,
+		"model":    modelName,
+		"preset":   presetName,
+	})
+	http.Error(w, "Forbidden", http.StatusForbidden)
+}
+
+// writePresetNotFound answers 400 for an unknown preset. Models with access
+// rules answer with the same 403 as for a denied preset instead, so that
+// callers cannot probe which hidden presets exist.
+func writePresetNotFound(w http.ResponseWriter, endpoint string, m *model.Model, modelName, presetName string) {
+	if m.UsesAccess() {
+		writeAccessDenied(w, endpoint, modelName, presetName)
+		return
+	}
+	logger.Warn("preset_not_found", map[string]any{
+		"endpoint": endpoint,
+		"model":    modelName,
+		"preset":   presetName,
+	})
+	http.Error(w, fmt.Sprintf("Preset %s not found", presetName), http.StatusBadRequest)
+}
+
+// writeQueryTimeout answers 504 when the request deadline (timeout_ms or
+// QUERY_TIMEOUT_MS) or the database statement_timeout stopped the query.
+func writeQueryTimeout(w http.ResponseWriter, endpoint string, err error) {
+	logger.Warn("query_timeout", map[string]any{
+		"endpoint": endpoint,
+		"error":    err.Error(),
+	})
+	http.Error(w, "Query timed out", http.StatusGatewayTimeout)
+}
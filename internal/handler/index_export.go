@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"YrestAPI/internal/export"
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/resolver"
+)
+
+// exportFormat picks the spreadsheet format from the "format" field or, when
+// it is empty, from the Accept header. An empty result means a JSON response.
+func exportFormat(r *http.Request, req resolver.IndexRequest) (string, error) {
+	if f := strings.ToLower(strings.TrimSpace(req.Format)); f != "" {
+		switch f {
+		case export.FormatCSV, export.FormatXLSX:
+			return f, nil
+		case "json":
+			return "", nil
+		default:
+			return "", fmt.Errorf("unsupported format %q (expected csv, xlsx or json)", req.Format)
+		}
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return export.FormatCSV, nil
+		case export.ContentTypeXLSX:
+			return export.FormatXLSX, nil
+		}
+	}
+	return "", nil
+}
+
+// writeIndexExport streams the preset as a CSV/XLSX attachment. Root rows are
+// resolved in the same keyset chunks as NDJSON streaming; each finalized item
+// is flattened by export.Plan into one or more rows.
+func writeIndexExport(w http.ResponseWriter, r *http.Request, req resolver.IndexRequest, format string) {
+	endpoint := "/api/index"
+	if req.Envelope {
+		http.Error(w, "envelope cannot be combined with export format", http.StatusBadRequest)
+		return
+	}
+	// модель и все запросы ниже — из одного снимка реестра
+	r = r.WithContext(resolver.WithRegistry(r.Context()))
+	m, ok := resolver.LookupModel(r.Context(), req.Model)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Model %s not found", req.Model), http.StatusNotFound)
+		return
+	}
+
+	var plan *export.Plan
+	name := req.Model
+	if req.UniqueBy != "" {
+		plan = export.ValuePlan(req.UniqueBy)
+		name += "_" + req.UniqueBy
+	} else {
+		preset := m.GetPreset(req.Preset)
+		if preset == nil {
+			writePresetNotFound(w, endpoint, m, req.Model, req.Preset)
+			return
+		}
+		plan = export.NewPlan(m, preset)
+		name += "_" + req.Preset
+	}
+
+	var out export.Writer
+	started := false
+	rows := 0
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+		w.WriteHeader(http.StatusOK)
+		var err error
+		if out, err = export.NewWriter(format, w); err != nil {
+			return err
+		}
+		return out.WriteHeader(plan.Headers)
+	}
+	writeItems := func(items []map[string]any) error {
+		for _, item := range items {
+			// строки элемента строятся до начала ответа: слишком большой
+			// explode первого элемента ещё может стать ошибкой 422
+			itemRows, err := plan.Rows(item, req.Explode)
+			if err != nil {
+				return err
+			}
+			if err := start(); err != nil {
+				return err
+			}
+			for _, row := range itemRows {
+				if err := out.WriteRow(row); err != nil {
+					return err
+				}
+			}
+		}
+		if err := start(); err != nil {
+			return err
+		}
+		rows += len(items)
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	var err error
+	if req.UniqueBy != "" {
+		var values []any
+		if values, err = resolver.ResolveDistinctValues(r.Context(), req); err == nil {
+			items := make([]map[string]any, len(values))
+			for i, v := range values {
+				items[i] = map[string]any{req.UniqueBy: v}
+			}
+			err = writeItems(items)
+		}
+	} else {
+		err = resolver.StreamIndex(r.Context(), req, writeItems)
+	}
+	if err == nil {
+		err = start()
+	}
+	if err == nil && out != nil {
+		err = out.Close()
+	}
+	if err != nil {
+		logger.Error("export_error", map[string]any{
+			"endpoint": endpoint,
+			"format":   format,
+			"rows":     rows,
+			"error":    err.Error(),
+		})
+		if !started {
+			status := indexErrorStatus(err)
+			if errors.Is(err, export.ErrTooManyRows) {
+				status = http.StatusUnprocessableEntity
+			}
+			http.Error(w, "Failed to resolve data: "+err.Error(), status)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/resolver"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON reports whether the client asked for newline-delimited JSON.
+func wantsNDJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// writeIndexStream writes one JSON item per line and flushes after every
+// resolved chunk. Errors before the first chunk are reported with a regular
+// HTTP status; after that the status is already sent, so the stream ends with
+// an {"error": "..."} line instead.
+func writeIndexStream(w http.ResponseWriter, r *http.Request, req resolver.IndexRequest) {
+	endpoint := "/api/index"
+	if req.Envelope {
+		http.Error(w, "envelope cannot be combined with NDJSON streaming", http.StatusBadRequest)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+	}
+	rows := 0
+
+	var err error
+	if req.UniqueBy != "" {
+		var values []any
+		if values, err = resolver.ResolveDistinctValues(r.Context(), req); err == nil {
+			start()
+			for _, v := range values {
+				if err = enc.Encode(v); err != nil {
+					break
+				}
+			}
+			rows = len(values)
+		}
+	} else {
+		err = resolver.StreamIndex(r.Context(), req, func(items []map[string]any) error {
+			start()
+			for _, item := range items {
+				if err := enc.Encode(item); err != nil {
+					return err
+				}
+			}
+			rows += len(items)
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+
+	if err != nil {
+		logger.Error("stream_error", map[string]any{
+			"endpoint": endpoint,
+			"rows":     rows,
+			"error":    err.Error(),
+		})
+		if !started {
+			http.Error(w, "Failed to resolve data: "+err.Error(), indexErrorStatus(err))
+			return
+		}
+		_ = enc.Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	start()
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/meta"
+	"YrestAPI/internal/model"
+)
+
+// MetaHandler returns the linked registry: models, relations, aliases,
+// computable and aggregatable fields, presets with resolved fields.
+// ?model=<Name> narrows the answer to one model. Models and presets the
+// caller's roles do not allow are left out.
+func MetaHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint := "/api/meta"
+	if r.Method != http.MethodGet {
+		logger.Warn("method_not_allowed", map[string]any{
+			"endpoint": endpoint,
+			"method":   r.Method,
+		})
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	registry := visibleRegistry(r)
+	var body any
+	if name := r.URL.Query().Get("model"); name != "" {
+		m, ok := registry[name]
+		if !ok || m == nil {
+			logger.Warn("model_not_found", map[string]any{
+				"endpoint": endpoint,
+				"model":    name,
+			})
+			http.Error(w, "Model "+name+" not found", http.StatusNotFound)
+			return
+		}
+		body = meta.Describe(name, m)
+	} else {
+		body = meta.Build(registry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("write_response_failed", map[string]any{
+			"endpoint": endpoint,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// visibleRegistry returns the registry as seen by the caller: without models
+// and presets whose access rules their roles do not satisfy.
+func visibleRegistry(r *http.Request) map[string]*model.Model {
+	return model.VisibleRegistry(model.CurrentRegistry(), auth.RolesFromContext(r.Context()))
+}
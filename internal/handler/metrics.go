@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+
+	"YrestAPI/internal/metrics"
+	"YrestAPI/internal/model"
+)
+
+// labelRequest задаёт метки model/preset для /metrics. В метки попадают
+// только имена, известные реестру, чтобы произвольный ввод не плодил серии.
+func labelRequest(r *http.Request, modelName, presetName string) {
+	m, ok := model.LookupModel(modelName)
+	if !ok {
+		return
+	}
+	if m.GetPreset(presetName) == nil {
+		presetName = ""
+	}
+	metrics.SetModelPreset(r.Context(), modelName, presetName)
+}
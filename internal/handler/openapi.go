@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/openapi"
+)
+
+// NewOpenAPIHandler serves the OpenAPI document generated from the loaded
+// registry, limited to the models and presets the caller may use.
+// opts declares the enabled security schemes (JWT, API key).
+func NewOpenAPIHandler(opts openapi.Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := "/api/openapi.json"
+		if r.Method != http.MethodGet {
+			logger.Warn("method_not_allowed", map[string]any{
+				"endpoint": endpoint,
+				"method":   r.Method,
+			})
+			http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		doc := openapi.Build(visibleRegistry(r), opts)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(doc); err != nil {
+			logger.Error("write_response_failed", map[string]any{
+				"endpoint": endpoint,
+				"error":    err.Error(),
+			})
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/model"
+	"YrestAPI/internal/resolver"
+)
+
+// ShowHandler returns a single preset object by primary key or 404.
+func ShowHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint := "/api/show"
+	if r.Method != http.MethodPost {
+		logger.Warn("method_not_allowed", map[string]any{
+			"endpoint": endpoint,
+			"method":   r.Method,
+		})
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req resolver.ShowRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Warn("read_body_failed", map[string]any{
+			"endpoint": endpoint,
+			"error":    err.Error(),
+		})
+		http.Error(w, "Failed to read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// UseNumber: bigint id не должен терять точность через float64
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	err = dec.Decode(&req)
+	if err == nil && dec.More() {
+		err = errors.New("unexpected data after JSON object")
+	}
+	if err != nil {
+		logger.Warn("invalid_json", map[string]any{
+			"endpoint": endpoint,
+			"error":    err.Error(),
+		})
+		http.Error(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	logger.Info("request", map[string]any{
+		"endpoint": endpoint,
+		"payload":  json.RawMessage(body),
+	})
+	labelRequest(r, req.Model, req.Preset)
+
+	// модель и все запросы ниже — из одного снимка реестра
+	r = r.WithContext(resolver.WithRegistry(r.Context()))
+	m, ok := resolver.LookupModel(r.Context(), req.Model)
+	if !ok {
+		logger.Warn("model_not_found", map[string]any{
+			"endpoint": endpoint,
+			"model":    req.Model,
+		})
+		http.Error(w, fmt.Sprintf("Model %s not found", req.Model), http.StatusNotFound)
+		return
+	}
+	if m.GetPreset(req.Preset) == nil {
+		writePresetNotFound(w, endpoint, m, req.Model, req.Preset)
+		return
+	}
+
+	item, err := resolver.ResolveOne(r.Context(), req)
+	if err != nil {
+		var validationErr *resolver.ShowValidationError
+		var policyErr *model.PolicyError
+		var accessErr *model.AccessError
+		var costErr *resolver.CostError
+		switch {
+		case errors.Is(err, resolver.ErrNotFound):
+			http.Error(w, "Record not found", http.StatusNotFound)
+		case errors.As(err, &validationErr):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.As(err, &policyErr):
+			writePolicyError(w, endpoint, policyErr)
+		case errors.As(err, &accessErr):
+			writeAccessDenied(w, endpoint, req.Model, req.Preset)
+		case errors.As(err, &costErr):
+			writeCostError(w, endpoint, costErr)
+		case db.IsTimeout(err):
+			writeQueryTimeout(w, endpoint, err)
+		default:
+			logger.Error("resolver_error", map[string]any{
+				"endpoint": endpoint,
+				"error":    err.Error(),
+			})
+			http.Error(w, "Failed to resolve data: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		logger.Error("write_response_failed", map[string]any{
+			"endpoint": endpoint,
+			"error":    err.Error(),
+		})
+	}
+}
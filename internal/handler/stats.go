@@ -11,7 +11,9 @@ import (
 
 	"YrestAPI/internal/db"
 	"YrestAPI/internal/logger"
+	"YrestAPI/internal/metrics"
 	"YrestAPI/internal/model"
+	"YrestAPI/internal/resolver"
 )
 
 type StatsRequest struct {
@@ -56,8 +58,11 @@ func StatsHandler(w http.ResponseWriter, r *http.Request) {
 		"endpoint": endpoint,
 		"payload":  json.RawMessage(body),
 	})
+	labelRequest(r, req.Model, req.Preset)
 
-	m, ok := model.Registry[req.Model]
+	// модель и все запросы ниже — из одного снимка реестра
+	r = r.WithContext(resolver.WithRegistry(r.Context()))
+	m, ok := resolver.LookupModel(r.Context(), req.Model)
 	if !ok {
 		logger.Warn("model_not_found", map[string]any{
 			"endpoint": endpoint,
@@ -71,21 +76,34 @@ func StatsHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "aggregates cannot be combined with unique_by", http.StatusBadRequest)
 			return
 		}
-		filters := model.NormalizeFiltersWithAliases(m, req.Filters)
+		if err := resolver.Authorize(r.Context(), m, nil); err != nil {
+			writeAccessDenied(w, endpoint, req.Model, req.Preset)
+			return
+		}
+		ctx, cancel := resolver.WithDeadline(r.Context(), m, nil)
+		defer cancel()
+		r = r.WithContext(ctx)
+		requestFilters := model.NormalizeFiltersWithAliases(m, req.Filters)
 		field := strings.TrimSpace(model.ExpandAliasPath(m, req.UniqueBy))
-		aliasMap, err := m.CreateAliasMap(m, nil, filters, []string{field + " ASC"})
+		if err := resolver.AuthorizeFields(r.Context(), m, requestFilters, field); err != nil {
+			writeAccessDenied(w, endpoint, req.Model, req.Preset)
+			return
+		}
+		filters, err := resolver.ApplyPolicy(r.Context(), m, requestFilters, "")
+		if err != nil {
+			writePolicyError(w, endpoint, err)
+			return
+		}
+		sorts := []string{field + " ASC"}
+		aliasMap, err := m.CreateAliasMap(m, nil, filters, sorts)
 		if err != nil {
 			http.Error(w, "alias map error: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		aliasMap = resolver.ScopeAliasMap(r.Context(), aliasMap)
 		query, err := m.BuildDistinctCountQuery(aliasMap, filters, field)
 		if err != nil {
-			status := http.StatusInternalServerError
-			var validationErr *model.DistinctValidationError
-			if errors.As(err, &validationErr) {
-				status = http.StatusBadRequest
-			}
-			http.Error(w, "Query error: "+err.Error(), status)
+			http.Error(w, "Query error: "+err.Error(), indexErrorStatus(err))
 			return
 		}
 		sqlStr, args, err := query.ToSql()
@@ -94,8 +112,19 @@ func StatsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		logger.Debug("sql", map[string]any{"endpoint": endpoint, "sql": sqlStr, "args": args})
+		if err := resolver.CheckAggregateCost(r.Context(), m, nil, aliasMap, requestFilters, filters, sorts, sqlStr, args); err != nil {
+			writeStatsCostError(w, endpoint, err)
+			return
+		}
 		var count int
-		if err := db.Pool.QueryRow(r.Context(), sqlStr, args...).Scan(&count); err != nil {
+		started := time.Now()
+		err = db.QueryRow(r.Context(), sqlStr, args...).Scan(&count)
+		metrics.ObserveQuery(metrics.QueryCount, time.Since(started))
+		if err != nil {
+			if db.IsTimeout(err) {
+				writeQueryTimeout(w, endpoint, err)
+				return
+			}
 			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -108,25 +137,39 @@ func StatsHandler(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimSpace(req.Preset) != "" {
 		preset = m.Presets[req.Preset]
 		if preset == nil {
-			logger.Warn("preset_not_found", map[string]any{
-				"endpoint": endpoint,
-				"model":    req.Model,
-				"preset":   req.Preset,
-			})
-			http.Error(w, fmt.Sprintf("Preset %s not found", req.Preset), http.StatusBadRequest)
+			writePresetNotFound(w, endpoint, m, req.Model, req.Preset)
 			return
 		}
 	}
+	if err := resolver.Authorize(r.Context(), m, preset); err != nil {
+		writeAccessDenied(w, endpoint, req.Model, req.Preset)
+		return
+	}
+	ctx, cancel := resolver.WithDeadline(r.Context(), m, preset)
+	defer cancel()
+	r = r.WithContext(ctx)
 
 	// Разворачиваем короткие алиасы в фильтрах, чтобы карта алиасов и WHERE работали с одними ключами
-	filters := model.NormalizeFiltersWithAliases(m, req.Filters)
+	// и добавляем row-level политику модели
+	requestFilters := model.NormalizeFiltersWithAliases(m, req.Filters)
 	aggregateSpecs := make([]model.AggregateSpec, 0, len(req.Aggregates))
+	aggregateFields := make([]string, 0, len(req.Aggregates))
 	for name, spec := range req.Aggregates {
 		aggregateSpecs = append(aggregateSpecs, model.AggregateSpec{
 			Name:  name,
 			Fn:    spec.Fn,
 			Field: spec.Field,
 		})
+		aggregateFields = append(aggregateFields, spec.Field)
+	}
+	if err := resolver.AuthorizeFields(r.Context(), m, requestFilters, aggregateFields...); err != nil {
+		writeAccessDenied(w, endpoint, req.Model, req.Preset)
+		return
+	}
+	filters, err := resolver.ApplyPolicy(r.Context(), m, requestFilters, "")
+	if err != nil {
+		writePolicyError(w, endpoint, err)
+		return
 	}
 
 	// Получаем карту алиасов из кэша или строим на лету
@@ -140,21 +183,40 @@ func StatsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "alias map error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	aliasMap = resolver.ScopeAliasMap(r.Context(), aliasMap)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if len(aggregateSpecs) == 0 {
-		if err := writeStatsOnlyResponse(r, w, endpoint, m, aliasMap, preset, filters); err != nil {
+		if err := writeStatsOnlyResponse(r, w, endpoint, m, aliasMap, preset, requestFilters, filters); err != nil {
+			var costErr *resolver.CostError
+			if errors.As(err, &costErr) {
+				writeCostError(w, endpoint, costErr)
+				return
+			}
+			if db.IsTimeout(err) {
+				writeQueryTimeout(w, endpoint, err)
+				return
+			}
 			logger.Error("stats_error", map[string]any{
 				"endpoint": endpoint,
 				"error":    err.Error(),
 			})
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), indexErrorStatus(err))
 		}
 		return
 	}
 
-	if err := writeStatsAggregateResponse(r, w, endpoint, m, aliasMap, preset, filters, aggregateSpecs); err != nil {
-		status := http.StatusInternalServerError
+	if err := writeStatsAggregateResponse(r, w, endpoint, m, aliasMap, preset, requestFilters, filters, aggregateSpecs); err != nil {
+		var costErr *resolver.CostError
+		if errors.As(err, &costErr) {
+			writeCostError(w, endpoint, costErr)
+			return
+		}
+		if db.IsTimeout(err) {
+			writeQueryTimeout(w, endpoint, err)
+			return
+		}
+		status := indexErrorStatus(err)
 		if isAggregateValidationError(err) {
 			status = http.StatusBadRequest
 		}
@@ -195,10 +257,25 @@ func isAggregateValidationError(err error) bool {
 	return false
 }
 
-func writeStatsOnlyResponse(r *http.Request, w http.ResponseWriter, endpoint string, m *model.Model, aliasMap *model.AliasMap, preset *model.DataPreset, filters map[string]interface{}) error {
+// writeStatsCostError answers 422 for a CostError and 500 for a failure of
+// the cost check itself (EXPLAIN).
+func writeStatsCostError(w http.ResponseWriter, endpoint string, err error) {
+	var costErr *resolver.CostError
+	if errors.As(err, &costErr) {
+		writeCostError(w, endpoint, costErr)
+		return
+	}
+	if db.IsTimeout(err) {
+		writeQueryTimeout(w, endpoint, err)
+		return
+	}
+	http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+}
+
+func writeStatsOnlyResponse(r *http.Request, w http.ResponseWriter, endpoint string, m *model.Model, aliasMap *model.AliasMap, preset *model.DataPreset, requestFilters, filters map[string]interface{}) error {
 	query, err := m.BuildCountQuery(aliasMap, preset, filters)
 	if err != nil {
-		return fmt.Errorf("Query error: %v", err)
+		return fmt.Errorf("Query error: %w", err)
 	}
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
@@ -209,22 +286,28 @@ func writeStatsOnlyResponse(r *http.Request, w http.ResponseWriter, endpoint str
 		"sql":      sqlStr,
 		"args":     args,
 	})
-	row := db.Pool.QueryRow(r.Context(), sqlStr, args...)
+	if err := resolver.CheckAggregateCost(r.Context(), m, preset, aliasMap, requestFilters, filters, nil, sqlStr, args); err != nil {
+		return err
+	}
+	started := time.Now()
+	row := db.QueryRow(r.Context(), sqlStr, args...)
 	var count int
-	if err := row.Scan(&count); err != nil {
-		return fmt.Errorf("DB error: %v", err)
+	err = row.Scan(&count)
+	metrics.ObserveQuery(metrics.QueryCount, time.Since(started))
+	if err != nil {
+		return fmt.Errorf("DB error: %w", err)
 	}
 	return json.NewEncoder(w).Encode(map[string]int{"count": count})
 }
 
-func writeStatsAggregateResponse(r *http.Request, w http.ResponseWriter, endpoint string, m *model.Model, aliasMap *model.AliasMap, preset *model.DataPreset, filters map[string]interface{}, aggregateSpecs []model.AggregateSpec) error {
+func writeStatsAggregateResponse(r *http.Request, w http.ResponseWriter, endpoint string, m *model.Model, aliasMap *model.AliasMap, preset *model.DataPreset, requestFilters, filters map[string]interface{}, aggregateSpecs []model.AggregateSpec) error {
 	resolved, err := m.ValidateAndResolveAggregates(aliasMap, aggregateSpecs)
 	if err != nil {
 		return err
 	}
 	query, err := m.BuildCountAggregateQuery(aliasMap, preset, filters, resolved)
 	if err != nil {
-		return fmt.Errorf("Query error: %v", err)
+		return fmt.Errorf("Query error: %w", err)
 	}
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
@@ -235,10 +318,15 @@ func writeStatsAggregateResponse(r *http.Request, w http.ResponseWriter, endpoin
 		"sql":      sqlStr,
 		"args":     args,
 	})
+	if err := resolver.CheckAggregateCost(r.Context(), m, preset, aliasMap, requestFilters, filters, nil, sqlStr, args); err != nil {
+		return err
+	}
 
-	rows, err := db.Pool.Query(r.Context(), sqlStr, args...)
+	started := time.Now()
+	defer func() { metrics.ObserveQuery(metrics.QueryCount, time.Since(started)) }()
+	rows, err := db.Query(r.Context(), sqlStr, args...)
 	if err != nil {
-		return fmt.Errorf("DB error: %v", err)
+		return fmt.Errorf("DB error: %w", err)
 	}
 	defer rows.Close()
 	if !rows.Next() {
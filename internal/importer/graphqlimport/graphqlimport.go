@@ -8,8 +8,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-	"unicode"
 
+	"YrestAPI/internal/graphql/language"
 	"YrestAPI/internal/model"
 
 	"gopkg.in/yaml.v3"
@@ -65,33 +65,6 @@ type parsedModel struct {
 	Data model.Model
 }
 
-type tokenType int
-
-const (
-	tokenEOF tokenType = iota
-	tokenName
-	tokenLBrace
-	tokenRBrace
-	tokenColon
-	tokenLParen
-	tokenRParen
-)
-
-type token struct {
-	typ tokenType
-	val string
-}
-
-type lexer struct {
-	src []rune
-	pos int
-}
-
-type parser struct {
-	lx  *lexer
-	cur token
-}
-
 func ImportFromPath(path string, opts ImportOptions) (*Result, error) {
 	raw, err := readGraphQLSources(path)
 	if err != nil {
@@ -330,175 +303,77 @@ func readGraphQLSources(path string) (string, error) {
 	return strings.Join(parts, "\n"), nil
 }
 
+// parseDocument разбирает запросы общим парсером internal/graphql/language и
+// сводит их к дереву полей: фрагменты раскрываются, аргументы и директивы
+// на форму пресета не влияют и отбрасываются.
 func parseDocument(src string) (*document, error) {
-	p := &parser{lx: &lexer{src: []rune(src)}}
-	p.next()
+	parsed, err := language.Parse(src)
+	if err != nil {
+		return nil, err
+	}
 	doc := &document{}
-	for p.cur.typ != tokenEOF {
-		op, err := p.parseOperation()
+	for _, op := range parsed.Operations {
+		sels, err := flattenSelections(parsed, op.Selections, map[string]bool{})
 		if err != nil {
 			return nil, err
 		}
-		doc.Operations = append(doc.Operations, op)
+		doc.Operations = append(doc.Operations, operation{Type: op.Type, Name: op.Name, Selections: sels})
 	}
 	return doc, nil
 }
 
-func (p *parser) parseOperation() (operation, error) {
-	op := operation{Type: "query"}
-	if p.cur.typ == tokenLBrace {
-		sels, err := p.parseSelectionSet()
-		if err != nil {
-			return op, err
-		}
-		op.Selections = sels
-		return op, nil
-	}
-	if p.cur.typ != tokenName {
-		return op, fmt.Errorf("unexpected token %q", p.cur.val)
-	}
-	if p.cur.val == "fragment" {
-		return op, fmt.Errorf("fragments are not supported in graphql import")
-	}
-	op.Type = p.cur.val
-	p.next()
-	if p.cur.typ == tokenName {
-		op.Name = p.cur.val
-		p.next()
-	}
-	if p.cur.typ == tokenLParen {
-		p.skipParens()
-	}
-	sels, err := p.parseSelectionSet()
-	if err != nil {
-		return op, err
-	}
-	op.Selections = sels
-	return op, nil
-}
-
-func (p *parser) parseSelectionSet() ([]selection, error) {
-	if p.cur.typ != tokenLBrace {
-		return nil, fmt.Errorf("expected '{', got %q", p.cur.val)
-	}
-	p.next()
+func flattenSelections(doc *language.Document, sels []language.Selection, visiting map[string]bool) ([]selection, error) {
 	var out []selection
-	for p.cur.typ != tokenRBrace && p.cur.typ != tokenEOF {
-		sel, err := p.parseSelection()
-		if err != nil {
-			return nil, err
-		}
-		out = append(out, sel)
-	}
-	if p.cur.typ != tokenRBrace {
-		return nil, fmt.Errorf("expected '}', got %q", p.cur.val)
-	}
-	p.next()
-	return out, nil
-}
-
-func (p *parser) parseSelection() (selection, error) {
-	if p.cur.typ != tokenName {
-		return selection{}, fmt.Errorf("expected field name, got %q", p.cur.val)
-	}
-	sel := selection{Name: p.cur.val}
-	p.next()
-	if p.cur.typ == tokenColon {
-		sel.Alias = sel.Name
-		p.next()
-		if p.cur.typ != tokenName {
-			return selection{}, fmt.Errorf("expected field name after alias, got %q", p.cur.val)
-		}
-		sel.Name = p.cur.val
-		p.next()
-	}
-	if p.cur.typ == tokenLParen {
-		p.skipParens()
-	}
-	if p.cur.typ == tokenLBrace {
-		children, err := p.parseSelectionSet()
-		if err != nil {
-			return selection{}, err
-		}
-		sel.Selections = children
-	}
-	return sel, nil
-}
-
-func (p *parser) skipParens() {
-	depth := 0
-	for {
-		if p.cur.typ == tokenLParen {
-			depth++
-		} else if p.cur.typ == tokenRParen {
-			depth--
-			if depth == 0 {
-				p.next()
-				return
+	for _, sel := range sels {
+		switch sel.Kind {
+		case language.SelectionField:
+			children, err := flattenSelections(doc, sel.Selections, visiting)
+			if err != nil {
+				return nil, err
+			}
+			out = mergeSelection(out, selection{Alias: sel.Alias, Name: sel.Name, Selections: children})
+		case language.SelectionFragmentSpread:
+			fr, ok := doc.Fragments[sel.Name]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", sel.Name)
+			}
+			if visiting[sel.Name] {
+				return nil, fmt.Errorf("fragment %q spreads itself", sel.Name)
+			}
+			visiting[sel.Name] = true
+			children, err := flattenSelections(doc, fr.Selections, visiting)
+			delete(visiting, sel.Name)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				out = mergeSelection(out, child)
+			}
+		case language.SelectionInlineFragment:
+			children, err := flattenSelections(doc, sel.Selections, visiting)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				out = mergeSelection(out, child)
 			}
-		} else if p.cur.typ == tokenEOF {
-			return
 		}
-		p.next()
 	}
+	return out, nil
 }
 
-func (p *parser) next() {
-	p.cur = p.lx.nextToken()
-}
-
-func (lx *lexer) nextToken() token {
-	for lx.pos < len(lx.src) {
-		r := lx.src[lx.pos]
-		if unicode.IsSpace(r) || r == ',' {
-			lx.pos++
-			continue
-		}
-		if r == '#' {
-			for lx.pos < len(lx.src) && lx.src[lx.pos] != '\n' {
-				lx.pos++
-			}
-			continue
-		}
-		if lx.pos+2 < len(lx.src) && string(lx.src[lx.pos:lx.pos+3]) == "..." {
-			return token{typ: tokenEOF, val: "..."}
-		}
-		switch r {
-		case '{':
-			lx.pos++
-			return token{typ: tokenLBrace, val: "{"}
-		case '}':
-			lx.pos++
-			return token{typ: tokenRBrace, val: "}"}
-		case ':':
-			lx.pos++
-			return token{typ: tokenColon, val: ":"}
-		case '(':
-			lx.pos++
-			return token{typ: tokenLParen, val: "("}
-		case ')':
-			lx.pos++
-			return token{typ: tokenRParen, val: ")"}
-		}
-		if isNameStart(r) {
-			start := lx.pos
-			lx.pos++
-			for lx.pos < len(lx.src) && isNamePart(lx.src[lx.pos]) {
-				lx.pos++
+// mergeSelection добавляет поле, сливая его с уже выбранным под тем же ключом
+// (одно поле часто приходит и напрямую, и из фрагмента).
+func mergeSelection(out []selection, sel selection) []selection {
+	for i := range out {
+		if out[i].Name == sel.Name && out[i].Alias == sel.Alias {
+			for _, child := range sel.Selections {
+				out[i].Selections = mergeSelection(out[i].Selections, child)
 			}
-			return token{typ: tokenName, val: string(lx.src[start:lx.pos])}
+			return out
 		}
-		lx.pos++
 	}
-	return token{typ: tokenEOF}
-}
-
-func isNameStart(r rune) bool {
-	return unicode.IsLetter(r) || r == '_'
-}
-
-func isNamePart(r rune) bool {
-	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+	return append(out, sel)
 }
 
 func presetNameForSelection(op operation, sel selection, path []string) string {
@@ -173,3 +173,35 @@ presets:
 		t.Fatalf("expected existing preset to remain unchanged, got:\n%s", out)
 	}
 }
+
+func TestParseDocument_ArgumentsVariablesAndFragments(t *testing.T) {
+	doc, err := parseDocument(`query GetUserCard($limit: Int = 10) {
+  user(filters: {email__cnt: "@example.com"}, limit: $limit) {
+    id
+    ...Card
+    posts(sorts: "id DESC") { ... on Post { id } title }
+  }
+}
+fragment Card on User { id displayEmail: email posts { id } }`)
+	if err != nil {
+		t.Fatalf("parseDocument: %v", err)
+	}
+	want := selection{
+		Name: "user",
+		Selections: []selection{
+			{Name: "id"},
+			{Name: "email", Alias: "displayEmail"},
+			{Name: "posts", Selections: []selection{{Name: "id"}, {Name: "title"}}},
+		},
+	}
+	if len(doc.Operations) != 1 || doc.Operations[0].Name != "GetUserCard" || len(doc.Operations[0].Selections) != 1 {
+		t.Fatalf("unexpected document: %#v", doc)
+	}
+	if got := doc.Operations[0].Selections[0]; selectionSignature(got) != selectionSignature(want) {
+		t.Fatalf("unexpected selection:\n got %#v\nwant %#v", got, want)
+	}
+
+	if _, err := parseDocument(`{ user { ...Loop } } fragment Loop on User { ...Loop }`); err == nil {
+		t.Fatal("expected error for recursive fragment")
+	}
+}
@@ -0,0 +1,97 @@
+package itests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/resolver"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func Test_DB_SnapshotHidesConcurrentWrites(t *testing.T) {
+	bg := context.Background()
+	if _, err := db.Pool.Exec(bg, `CREATE TABLE IF NOT EXISTS snapshot_probe (id serial PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = db.Pool.Exec(bg, `DROP TABLE IF EXISTS snapshot_probe`) })
+
+	ctx, release, err := db.BeginSnapshot(bg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	var before int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM snapshot_probe`).Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+	// запись вне снимка не должна быть видна запросам внутри него
+	if _, err := db.Pool.Exec(bg, `INSERT INTO snapshot_probe DEFAULT VALUES`); err != nil {
+		t.Fatal(err)
+	}
+	var after int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM snapshot_probe`).Scan(&after); err != nil {
+		t.Fatal(err)
+	}
+	if after != before {
+		t.Fatalf("snapshot must not see the insert: before=%d after=%d", before, after)
+	}
+}
+
+func Test_Index_Consistent_Person_Item(t *testing.T) {
+	status, _, body := postIndexPage(t, map[string]any{
+		"model":      "Person",
+		"preset":     "item",
+		"limit":      2,
+		"envelope":   true,
+		"consistent": true,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+}
+
+// Пул меньше числа одновременных consistent-запросов: снимки держат
+// соединения до конца запроса, а их хвосты берут ещё. Слоты снимков
+// оставляют хвостам свободное соединение, и все запросы завершаются
+// до дедлайна, а не ждут друг друга.
+func Test_DB_ConsistentRequestsWithSmallPool(t *testing.T) {
+	cfg := db.Pool.Config()
+	cfg.MaxConns = 2
+	cfg.MinConns = 0
+	small, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := db.Pool
+	db.Pool = small
+	t.Cleanup(func() {
+		db.Pool = orig
+		small.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	const requests = 6
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			_, err := resolver.ResolvePage(ctx, resolver.IndexRequest{
+				Model:      "Person",
+				Preset:     "with_contacts",
+				Limit:      5,
+				Consistent: true,
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < requests; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("consistent request %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package itests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/db"
+)
+
+func Test_DB_QueryDeadlineSetsStatementTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// вне снимка запрос идёт прямо в пул: дедлайн отрабатывает отменой,
+	// лишней транзакции и SET LOCAL нет
+	var setting string
+	if err := db.QueryRow(ctx, `SELECT current_setting('statement_timeout')`).Scan(&setting); err != nil {
+		t.Fatal(err)
+	}
+	if setting != "0" {
+		t.Fatalf("plain query must not set statement_timeout, got %q", setting)
+	}
+
+	// в транзакции снимка statement_timeout следует за дедлайном
+	snapCtx, release, err := db.BeginSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if err := db.QueryRow(snapCtx, `SELECT current_setting('statement_timeout')`).Scan(&setting); err != nil {
+		t.Fatal(err)
+	}
+	if setting == "0" || setting == "" {
+		t.Fatalf("statement_timeout must follow the context deadline, got %q", setting)
+	}
+
+	// SET LOCAL не протекает в пул
+	if err := db.QueryRow(context.Background(), `SELECT current_setting('statement_timeout')`).Scan(&setting); err != nil {
+		t.Fatal(err)
+	}
+	if setting != "0" {
+		t.Fatalf("SET LOCAL must not leak into the pool, got %q", setting)
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelShort()
+	var one int
+	err = db.QueryRow(short, `SELECT 1 FROM pg_sleep(2)`).Scan(&one)
+	if !db.IsTimeout(err) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package itests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/db"
+)
+
+func Test_GraphQL_Person_WithRelations(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	var id int
+	var lastName, firstName string
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT id, last_name, first_name FROM people ORDER BY id ASC LIMIT 1`).Scan(&id, &lastName, &firstName); err != nil {
+		t.Skipf("no people in DB: %v", err)
+	}
+	var contacts int
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM person_contacts WHERE person_id = $1`, id).Scan(&contacts); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := postGraphQL(t, map[string]any{
+		"query": `query One($id: Int) {
+			people: person(filters: {id__eq: $id}, limit: 1) {
+				__typename
+				full_name
+				name: last_name
+				contacts { kind value }
+			}
+			total: person_count(filters: {id__eq: $id})
+		}`,
+		"variables": map[string]any{"id": id},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var resp struct {
+		Data struct {
+			People []map[string]any `json:"people"`
+			Total  int              `json:"total"`
+		} `json:"data"`
+		Errors []map[string]any `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("invalid JSON: %v; body=%s", err, body)
+	}
+	if len(resp.Errors) > 0 || len(resp.Data.People) != 1 || resp.Data.Total != 1 {
+		t.Fatalf("unexpected response: %s", body)
+	}
+	p := resp.Data.People[0]
+	if p["__typename"] != "Person" || p["name"] != lastName || p["full_name"] != lastName+" "+firstName {
+		t.Fatalf("unexpected person: %#v", p)
+	}
+	if _, leaked := p["id"]; leaked {
+		t.Fatalf("unselected field leaked: %#v", p)
+	}
+	list, ok := p["contacts"].([]any)
+	if !ok || len(list) != contacts {
+		t.Fatalf("expected %d contacts, got %#v", contacts, p["contacts"])
+	}
+	// порядок полей в ответе повторяет выборку
+	if i, j := bytes.Index(body, []byte(`"full_name"`)), bytes.Index(body, []byte(`"name"`)); i < 0 || j < i {
+		t.Fatalf("field order not preserved: %s", body)
+	}
+}
+
+func Test_GraphQL_ValidationAndSchema(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	status, body := postGraphQL(t, map[string]any{"query": `{ person { unknown_field } }`})
+	if status != http.StatusBadRequest || !strings.Contains(string(body), "unknown_field") {
+		t.Fatalf("expected 400 with validation error, got %d: %s", status, body)
+	}
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Get(testBaseURL + "/graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	sdl, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(sdl), "type Person {") || !strings.Contains(string(sdl), "contacts: [Contact!]!") {
+		t.Fatalf("unexpected SDL (%d): %s", resp.StatusCode, sdl)
+	}
+}
+
+func postGraphQL(t *testing.T, payload map[string]any) (int, []byte) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Post(testBaseURL+"/graphql", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /graphql failed: %v", err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, out
+}
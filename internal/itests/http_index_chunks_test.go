@@ -0,0 +1,34 @@
+package itests
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"YrestAPI/internal/resolver"
+)
+
+// Разбиение ключей родителей на куски не должно менять ответ: каждый родитель
+// попадает в один кусок, а строки склеиваются в порядке кусков.
+func Test_Index_TailChunksMatchSingleQuery(t *testing.T) {
+	payload := map[string]any{
+		"model":  "Person",
+		"preset": "with_contacts",
+		"sorts":  []string{"id ASC"},
+	}
+	status, _, want := postIndexPage(t, payload)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, want)
+	}
+
+	resolver.SetTailChunkSize(1)
+	t.Cleanup(func() { resolver.SetTailChunkSize(5000) })
+
+	status, _, got := postIndexPage(t, payload)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 with chunked tails, got %d: %s", status, got)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("chunked response differs:\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package itests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/db"
+)
+
+func Test_Index_Person_Item_CursorPagination(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	rows, err := db.Pool.Query(context.Background(), `SELECT id FROM people ORDER BY last_name DESC, id DESC`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			t.Fatal(err)
+		}
+		want = append(want, id)
+	}
+	rows.Close()
+	if len(want) < 3 {
+		t.Skipf("need at least 3 people, got %d", len(want))
+	}
+
+	got := make([]int, 0, len(want))
+	payload := map[string]any{
+		"model":  "Person",
+		"preset": "item",
+		"sorts":  []string{"last_name DESC"},
+		"limit":  2,
+		"cursor": true,
+	}
+	for page := 0; page <= len(want); page++ {
+		status, next, body := postIndexPage(t, payload)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, body)
+		}
+		var items []map[string]any
+		if err := json.Unmarshal(body, &items); err != nil {
+			t.Fatalf("expected JSON array: %v; body=%s", err, body)
+		}
+		for _, it := range items {
+			id, ok := asInt(it["id"])
+			if !ok {
+				t.Fatalf("item without id: %v", it)
+			}
+			got = append(got, id)
+		}
+		if next == "" {
+			break
+		}
+		delete(payload, "cursor")
+		payload["after"] = next
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("cursor pages mismatch: got %v, want %v", got, want)
+	}
+}
+
+func Test_Index_Cursor_RejectsOffsetAndForeignCursor(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	status, next, body := postIndexPage(t, map[string]any{
+		"model": "Person", "preset": "item", "limit": 1, "cursor": true,
+	})
+	if status != http.StatusOK || next == "" {
+		t.Skipf("no second page available: %d %s", status, body)
+	}
+	status, _, body = postIndexPage(t, map[string]any{
+		"model": "Person", "preset": "item", "limit": 1, "offset": 2, "after": next,
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for offset+after, got %d: %s", status, body)
+	}
+	status, _, body = postIndexPage(t, map[string]any{
+		"model": "Person", "preset": "item", "limit": 1, "sorts": []string{"last_name ASC"}, "after": next,
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for cursor issued with other sorts, got %d: %s", status, body)
+	}
+}
+
+func postIndexPage(t *testing.T, payload map[string]any) (int, string, []byte) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Post(testBaseURL+"/api/index", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /api/index failed: %v", err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get("X-Next-Cursor"), out
+}
@@ -0,0 +1,98 @@
+package itests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"YrestAPI/internal/db"
+)
+
+func Test_Index_Envelope_Person_Item(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	var total int
+	if err := db.Pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM people`).Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+
+	status, _, body := postIndexPage(t, map[string]any{
+		"model":    "Person",
+		"preset":   "item",
+		"offset":   1,
+		"limit":    2,
+		"envelope": true,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var env struct {
+		Items      []map[string]any `json:"items"`
+		Total      int              `json:"total"`
+		Offset     int              `json:"offset"`
+		Limit      int              `json:"limit"`
+		NextCursor *string          `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		t.Fatalf("expected envelope object: %v; body=%s", err, body)
+	}
+	if env.Total != total {
+		t.Fatalf("total mismatch: got %d, want %d", env.Total, total)
+	}
+	if env.Offset != 1 || env.Limit != 2 {
+		t.Fatalf("page metadata mismatch: offset=%d limit=%d", env.Offset, env.Limit)
+	}
+	wantItems := total - 1
+	if wantItems > 2 {
+		wantItems = 2
+	}
+	if wantItems < 0 {
+		wantItems = 0
+	}
+	if len(env.Items) != wantItems {
+		t.Fatalf("items count mismatch: got %d, want %d", len(env.Items), wantItems)
+	}
+	if env.NextCursor != nil {
+		t.Fatalf("next_cursor must be null outside cursor mode, got %q", *env.NextCursor)
+	}
+}
+
+func Test_Index_Envelope_CursorAndFilteredTotal(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	var lastName string
+	var total int
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT last_name, COUNT(*) FROM people GROUP BY last_name ORDER BY COUNT(*) DESC, last_name LIMIT 1`,
+	).Scan(&lastName, &total); err != nil {
+		t.Skipf("no people in DB: %v", err)
+	}
+
+	status, next, body := postIndexPage(t, map[string]any{
+		"model":    "Person",
+		"preset":   "item",
+		"filters":  map[string]any{"last_name__in": []string{lastName}},
+		"limit":    1,
+		"cursor":   true,
+		"envelope": true,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var env map[string]any
+	if err := json.Unmarshal(body, &env); err != nil {
+		t.Fatalf("expected envelope object: %v; body=%s", err, body)
+	}
+	if got, _ := asInt(env["total"]); got != total {
+		t.Fatalf("filtered total mismatch: got %v, want %d", env["total"], total)
+	}
+	cursor, _ := env["next_cursor"].(string)
+	if total > 1 && (cursor == "" || cursor != next) {
+		t.Fatalf("expected next_cursor in body and header, body=%v header=%q", env["next_cursor"], next)
+	}
+}
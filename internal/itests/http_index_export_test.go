@@ -0,0 +1,105 @@
+package itests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/db"
+)
+
+func Test_Index_Person_Item_CSVExport(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	var total int
+	if err := db.Pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM people`).Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+
+	status, header, body := postExport(t, map[string]any{"model": "Person", "preset": "item"}, "text/csv")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	if !strings.HasPrefix(header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("unexpected content type: %q", header.Get("Content-Type"))
+	}
+	if !strings.Contains(header.Get("Content-Disposition"), `filename="Person_item.csv"`) {
+		t.Fatalf("unexpected content disposition: %q", header.Get("Content-Disposition"))
+	}
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v; body=%s", err, body)
+	}
+	if len(records) != total+1 {
+		t.Fatalf("expected %d data rows plus header, got %d records", total, len(records))
+	}
+	if records[0][0] != "id" {
+		t.Fatalf("unexpected header: %v", records[0])
+	}
+	for _, h := range records[0] {
+		if h == "first_name" {
+			t.Fatalf("internal field exported: %v", records[0])
+		}
+	}
+}
+
+func Test_Index_Person_Item_XLSXExport_ByFormatField(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	status, header, body := postExport(t, map[string]any{"model": "Person", "preset": "item", "limit": 3, "format": "xlsx"}, "")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	if header.Get("Content-Type") != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Fatalf("unexpected content type: %q", header.Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("expected XLSX zip: %v", err)
+	}
+	found := false
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("sheet1.xml missing in XLSX")
+	}
+
+	status, _, body = postExport(t, map[string]any{"model": "Person", "preset": "item", "format": "pdf"}, "")
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported format, got %d: %s", status, body)
+	}
+}
+
+func postExport(t *testing.T, payload map[string]any, accept string) (int, http.Header, []byte) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, testBaseURL+"/api/index", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/index failed: %v", err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, out
+}
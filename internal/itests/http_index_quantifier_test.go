@@ -0,0 +1,73 @@
+package itests
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// Кванторы над has_many: сотрудники 1 и 3 работают в Acme (1) инженерами,
+// 2 — исследователем в Globex (2); у 1 два контакта, у 2 и 3 по одному.
+func Test_Index_HasManyQuantifiers(t *testing.T) {
+	cases := []struct {
+		name    string
+		filters map[string]any
+		want    []int
+	}{
+		{"any", map[string]any{"employees__any": map[string]any{"position__cnt": "engineer"}}, []int{1, 3}},
+		{"none", map[string]any{"employees__none": map[string]any{"organization_id__eq": 1}}, []int{2}},
+		{"all", map[string]any{"employees__all": map[string]any{"organization_id__eq": 1}}, []int{1, 3}},
+		{"count", map[string]any{"contacts__count_gte": 2}, []int{1}},
+		{"through where", map[string]any{"email__any": map[string]any{}}, []int{1, 3}},
+		{"in group", map[string]any{"or": []any{
+			map[string]any{"contacts__count_gte": 2},
+			map[string]any{"employees__any": map[string]any{"organization_id__eq": 2}},
+		}}, []int{1, 2}},
+	}
+	for _, tc := range cases {
+		filters := map[string]any{"id__in": []int{1, 2, 3}}
+		for k, v := range tc.filters {
+			filters[k] = v
+		}
+		status, _, body := postIndexPage(t, map[string]any{
+			"model":   "Person",
+			"preset":  "item",
+			"filters": filters,
+			"sorts":   []string{"id ASC"},
+		})
+		if status != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.name, status, body)
+		}
+		var items []struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(body, &items); err != nil {
+			t.Fatalf("%s: decode: %v; body=%s", tc.name, err, body)
+		}
+		got := []int{}
+		for _, it := range items {
+			got = append(got, it.ID)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func Test_Index_InvalidQuantifierIsBadRequest(t *testing.T) {
+	for _, filters := range []map[string]any{
+		{"contacts__count_gte": -1},
+		{"employees__any": "engineer"},
+		{"first_name__any": map[string]any{}},
+	} {
+		status, _, body := postIndexPage(t, map[string]any{
+			"model":   "Person",
+			"preset":  "item",
+			"filters": filters,
+		})
+		if status != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d: %s", filters, status, body)
+		}
+	}
+}
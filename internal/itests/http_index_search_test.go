@@ -0,0 +1,82 @@
+package itests
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// Поиск по first_name, last_name и profile.bio (конфигурация simple):
+// 1 — Serge Ivankov "Go/JS/Ruby dev", 2 — Mira Tan "Data wrangler", 3 — Alex Chen.
+func Test_Index_FullTextSearch(t *testing.T) {
+	cases := []struct {
+		name string
+		req  map[string]any
+		want []int
+	}{
+		{"q over search fields", map[string]any{"q": "serge dev"}, []int{1}},
+		{"q reaches has_one path", map[string]any{"q": "wrangler"}, []int{2}},
+		{"websearch or", map[string]any{"q": "mira or alex"}, []int{2, 3}},
+		{"websearch negation", map[string]any{"q": "-tan", "filters": map[string]any{"id__in": []int{1, 2}}}, []int{1}},
+		{"field operator", map[string]any{"filters": map[string]any{"last_name__fts": "chen"}}, []int{3}},
+		{"filter key", map[string]any{"filters": map[string]any{"__fts": "ivankov"}}, []int{1}},
+	}
+	for _, tc := range cases {
+		req := map[string]any{"model": "Person", "preset": "item", "sorts": []string{"id ASC"}}
+		for k, v := range tc.req {
+			req[k] = v
+		}
+		status, _, body := postIndexPage(t, req)
+		if status != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.name, status, body)
+		}
+		var items []struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(body, &items); err != nil {
+			t.Fatalf("%s: decode: %v; body=%s", tc.name, err, body)
+		}
+		got := []int{}
+		for _, it := range items {
+			got = append(got, it.ID)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func Test_Index_RankSort(t *testing.T) {
+	// "serge" встречается только у 1, "dev" — у 1; у 2 совпадает лишь "data"
+	status, _, body := postIndexPage(t, map[string]any{
+		"model":  "Person",
+		"preset": "item",
+		"q":      "serge or dev or data",
+		"sorts":  []string{"rank", "id ASC"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var items []struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		t.Fatalf("decode: %v; body=%s", err, body)
+	}
+	if len(items) != 2 || items[0].ID != 1 || items[1].ID != 2 {
+		t.Fatalf("expected [1 2] by rank, got %s", body)
+	}
+
+	status, _, body = postIndexPage(t, map[string]any{
+		"model":  "Person",
+		"preset": "item",
+		"q":      "serge",
+		"sorts":  []string{"rank"},
+		"cursor": true,
+		"limit":  10,
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("rank with cursor must be rejected, got %d: %s", status, body)
+	}
+}
@@ -0,0 +1,82 @@
+package itests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/resolver"
+)
+
+func Test_Index_Person_Item_NDJSONStream(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	rows, err := db.Pool.Query(context.Background(), `SELECT id FROM people ORDER BY id ASC`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			t.Fatal(err)
+		}
+		want = append(want, id)
+	}
+	rows.Close()
+
+	// маленький чанк, чтобы поток гарантированно прошёл несколько страниц
+	resolver.SetStreamChunkSize(2)
+	defer resolver.SetStreamChunkSize(500)
+
+	body, _ := json.Marshal(map[string]any{"model": "Person", "preset": "item"})
+	httpReq, err := http.NewRequest(http.MethodPost, testBaseURL+"/api/index", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/x-ndjson")
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(httpReq)
+	if err != nil {
+		t.Fatalf("POST /api/index failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+
+	got := make([]int, 0, len(want))
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var item map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		if msg, ok := item["error"]; ok {
+			t.Fatalf("stream failed: %v", msg)
+		}
+		id, ok := asInt(item["id"])
+		if !ok {
+			t.Fatalf("item without id: %v", item)
+		}
+		got = append(got, id)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("streamed ids mismatch: got %v, want %v", got, want)
+	}
+}
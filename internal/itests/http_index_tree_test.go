@@ -0,0 +1,103 @@
+package itests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type deptNode struct {
+	ID       int        `json:"id"`
+	Name     string     `json:"name"`
+	Children []deptNode `json:"children"`
+}
+
+// Engineering (10) → Frontend (12), Platform (11): поддерево одним запросом,
+// дети отсортированы по order связи (name).
+func Test_Index_TreeDescendants(t *testing.T) {
+	status, _, body := postIndexPage(t, map[string]any{
+		"model":   "Department",
+		"preset":  "tree",
+		"filters": map[string]any{"id__in": []int{10, 20}},
+		"sorts":   []string{"id ASC"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var items []deptNode
+	if err := json.Unmarshal(body, &items); err != nil {
+		t.Fatalf("decode: %v; body=%s", err, body)
+	}
+	if len(items) != 2 || items[0].ID != 10 || items[1].ID != 20 {
+		t.Fatalf("expected departments 10 and 20, got %s", body)
+	}
+	kids := items[0].Children
+	if len(kids) != 2 || kids[0].ID != 12 || kids[1].ID != 11 {
+		t.Fatalf("expected children [12 11] of department 10, got %s", body)
+	}
+	if kids[0].Children == nil || len(kids[0].Children) != 0 {
+		t.Fatalf("leaf must carry an empty children array, got %s", body)
+	}
+	if items[1].Children == nil || len(items[1].Children) != 0 {
+		t.Fatalf("department 20 has no children, got %s", body)
+	}
+}
+
+func Test_Index_TreeAncestors(t *testing.T) {
+	status, _, body := postIndexPage(t, map[string]any{
+		"model":   "Department",
+		"preset":  "path",
+		"filters": map[string]any{"id__in": []int{11, 10}},
+		"sorts":   []string{"id ASC"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var items []struct {
+		ID        int        `json:"id"`
+		Ancestors []deptNode `json:"ancestors"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		t.Fatalf("decode: %v; body=%s", err, body)
+	}
+	if len(items) != 2 || len(items[0].Ancestors) != 0 {
+		t.Fatalf("root department has no ancestors, got %s", body)
+	}
+	if a := items[1].Ancestors; len(a) != 1 || a[0].ID != 10 {
+		t.Fatalf("expected ancestors [10] of department 11, got %s", body)
+	}
+}
+
+func Test_Index_TreeFilters(t *testing.T) {
+	cases := []struct {
+		filters map[string]any
+		want    []int
+	}{
+		{map[string]any{"subtree__descendants_of": 10}, []int{11, 12}},
+		{map[string]any{"subtree__ancestors_of": []int{11}}, []int{10}},
+	}
+	for _, tc := range cases {
+		status, _, body := postIndexPage(t, map[string]any{
+			"model":   "Department",
+			"preset":  "item",
+			"filters": tc.filters,
+			"sorts":   []string{"id ASC"},
+		})
+		if status != http.StatusOK {
+			t.Fatalf("%v: expected 200, got %d: %s", tc.filters, status, body)
+		}
+		var items []struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(body, &items); err != nil {
+			t.Fatalf("decode: %v; body=%s", err, body)
+		}
+		got := make([]int, 0, len(items))
+		for _, it := range items {
+			got = append(got, it.ID)
+		}
+		if len(got) != len(tc.want) || (len(got) > 0 && got[0] != tc.want[0]) || (len(got) > 1 && got[1] != tc.want[1]) {
+			t.Fatalf("%v: got %v, want %v", tc.filters, got, tc.want)
+		}
+	}
+}
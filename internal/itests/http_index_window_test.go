@@ -0,0 +1,54 @@
+package itests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// limit/offset на has_many действуют на каждого родителя: у контрагента 300
+// две организации, у 301 — одна.
+func Test_Index_HasManyWindowPerParent(t *testing.T) {
+	status, _, body := postIndexPage(t, map[string]any{
+		"model":   "Contragent",
+		"preset":  "organization_window",
+		"filters": map[string]any{"id__in": []int{300, 301}},
+		"sorts":   []string{"id ASC"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var items []struct {
+		ID    int `json:"id"`
+		First []struct {
+			ID int `json:"id"`
+		} `json:"first_organization"`
+		Second []struct {
+			ID int `json:"id"`
+		} `json:"second_organization"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		t.Fatalf("decode: %v; body=%s", err, body)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 contragents, got %s", body)
+	}
+	want := map[int][2][]int{300: {{1}, {2}}, 301: {{3}, {}}}
+	for _, it := range items {
+		ids := func(rows []struct {
+			ID int `json:"id"`
+		}) []int {
+			out := []int{}
+			for _, r := range rows {
+				out = append(out, r.ID)
+			}
+			return out
+		}
+		first, second := ids(it.First), ids(it.Second)
+		w := want[it.ID]
+		if len(first) != len(w[0]) || (len(first) > 0 && first[0] != w[0][0]) ||
+			len(second) != len(w[1]) || (len(second) > 0 && second[0] != w[1][0]) {
+			t.Fatalf("contragent %d: got first=%v second=%v, want %v", it.ID, first, second, w)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package itests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Meta_Registry(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(testBaseURL + "/api/meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var reg struct {
+		Models []struct {
+			Name    string                    `json:"name"`
+			Table   string                    `json:"table"`
+			Presets map[string]map[string]any `json:"presets"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	found := false
+	for _, m := range reg.Models {
+		if m.Name == "Person" {
+			found = m.Table == "people" && m.Presets["item"] != nil
+		}
+	}
+	if !found {
+		t.Fatalf("Person with preset item missing: %+v", reg.Models)
+	}
+
+	one, err := client.Get(testBaseURL + "/api/meta?model=Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer one.Body.Close()
+	var person struct {
+		Name      string         `json:"name"`
+		Relations map[string]any `json:"relations"`
+	}
+	if err := json.NewDecoder(one.Body).Decode(&person); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if person.Name != "Person" || person.Relations["contacts"] == nil {
+		t.Fatalf("unexpected model: %+v", person)
+	}
+
+	missing, err := client.Get(testBaseURL + "/api/meta?model=Nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", missing.StatusCode)
+	}
+}
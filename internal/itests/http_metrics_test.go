@@ -0,0 +1,48 @@
+package itests
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Metrics_CountsIndexRequests(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	status, _, body := postIndexPage(t, map[string]any{
+		"model":  "Person",
+		"preset": "with_contacts",
+		"limit":  2,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Get(testBaseURL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(raw)
+	for _, want := range []string{
+		`yrest_requests_total{endpoint="/api/index",model="Person",preset="with_contacts",status="200"}`,
+		`yrest_sql_query_duration_seconds_count{kind="root"}`,
+		`yrest_sql_query_duration_seconds_count{kind="tail"}`,
+		"yrest_db_pool_total_conns ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("metrics missing %q", want)
+		}
+	}
+}
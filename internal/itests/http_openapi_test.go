@@ -0,0 +1,41 @@
+package itests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_OpenAPI_Document(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Get(testBaseURL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Paths      map[string]any
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc.OpenAPI == "" || doc.Paths["/api/index"] == nil {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	for _, name := range []string{"Person.item", "Person.Filters", "Person.IndexRequest", "Contact.item"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Fatalf("schema %s missing", name)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package itests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/db"
+)
+
+func Test_Show_Person_Item_ByID(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	var id int
+	var lastName string
+	if err := db.Pool.QueryRow(context.Background(), `SELECT id, last_name FROM people ORDER BY id ASC LIMIT 1`).Scan(&id, &lastName); err != nil {
+		t.Skipf("no people in DB: %v", err)
+	}
+
+	status, body := postShow(t, map[string]any{"model": "Person", "preset": "item", "id": id})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("expected JSON object: %v; body=%s", err, body)
+	}
+	if gotID, ok := asInt(got["id"]); !ok || gotID != id {
+		t.Fatalf("id mismatch: got %v, want %d", got["id"], id)
+	}
+	if got["last_name"] != lastName {
+		t.Fatalf("last_name mismatch: got %v, want %s", got["last_name"], lastName)
+	}
+	if _, leaked := got["first_name"]; leaked {
+		t.Fatalf("internal field leaked: %v", got)
+	}
+}
+
+func Test_Show_Person_NotFound(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	var maxID int
+	if err := db.Pool.QueryRow(context.Background(), `SELECT COALESCE(MAX(id), 0) FROM people`).Scan(&maxID); err != nil {
+		t.Fatal(err)
+	}
+	status, body := postShow(t, map[string]any{"model": "Person", "preset": "item", "id": maxID + 1000})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", status, body)
+	}
+}
+
+func Test_Show_Person_MissingID(t *testing.T) {
+	if testBaseURL == "" || httpSrv == nil {
+		t.Fatal("bootstrap not ready: HTTP server/baseURL missing")
+	}
+
+	status, body := postShow(t, map[string]any{"model": "Person", "preset": "item"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", status, body)
+	}
+}
+
+func postShow(t *testing.T, payload map[string]any) (int, []byte) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Post(testBaseURL+"/api/show", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /api/show failed: %v", err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, out
+}
@@ -2,6 +2,8 @@ package itests
 
 import (
 	"YrestAPI/internal/db"
+	"YrestAPI/internal/model"
+	"YrestAPI/internal/resolver"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -385,3 +387,67 @@ func Test_Stats_DeprecatedCountAlias_Employee_WithAggregates(t *testing.T) {
 		}
 	}
 }
+
+// /api/stats и unique_by сверяются с бюджетом стоимости так же, как /api/index.
+func Test_Stats_RejectsOverCostBudget(t *testing.T) {
+	t.Cleanup(func() { resolver.SetCostBudget(resolver.CostBudget{}) })
+	resolver.SetCostBudget(resolver.CostBudget{MaxFilters: 1})
+
+	filters := map[string]any{"first_name__cnt": "a", "last_name__cnt": "b"}
+	payloads := []map[string]any{
+		{"model": "Person", "preset": "item", "filters": filters},
+		{"model": "Person", "unique_by": "first_name", "filters": filters},
+		{"model": "Employee", "filters": map[string]any{"position__cnt": "a", "id__gt": 0}, "aggregates": map[string]any{"max_id": map[string]any{"fn": "max", "field": "id"}}},
+	}
+	for _, payload := range payloads {
+		body, _ := json.Marshal(payload)
+		resp, err := (&http.Client{Timeout: 5 * time.Second}).Post(testBaseURL+"/api/stats", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		responseBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("%v: expected 422, got %d: %s", payload, resp.StatusCode, responseBody)
+		}
+	}
+}
+
+// Поля с visible_to нельзя использовать в фильтрах, unique_by и агрегатах
+// /api/stats: в itests роли не передаются, поэтому поле скрыто от всех.
+func Test_Stats_RejectsRedactedFields(t *testing.T) {
+	reg := model.CurrentRegistry()
+	employee := *reg["Employee"]
+	employee.Presets = map[string]*model.DataPreset{}
+	for name, p := range reg["Employee"].Presets {
+		employee.Presets[name] = p
+	}
+	employee.Presets["restricted"] = &model.DataPreset{Name: "restricted", Fields: []model.Field{
+		{Source: "position", Type: "string", VisibleTo: model.StringList{"hr"}},
+	}}
+	swapped := make(map[string]*model.Model, len(reg))
+	for name, m := range reg {
+		swapped[name] = m
+	}
+	swapped["Employee"] = &employee
+	model.SwapRegistry(swapped)
+	t.Cleanup(func() { model.SwapRegistry(reg) })
+
+	payloads := []map[string]any{
+		{"model": "Employee", "filters": map[string]any{"position__cnt": "a"}},
+		{"model": "Employee", "unique_by": "position"},
+		{"model": "Employee", "aggregates": map[string]any{"top": map[string]any{"fn": "max", "field": "position"}}},
+	}
+	for _, payload := range payloads {
+		body, _ := json.Marshal(payload)
+		resp, err := (&http.Client{Timeout: 5 * time.Second}).Post(testBaseURL+"/api/stats", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		responseBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("%v: expected 403, got %d: %s", payload, resp.StatusCode, responseBody)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package meta
+
+import (
+	"sort"
+	"strings"
+
+	"YrestAPI/internal/model"
+)
+
+// Registry — описание связанного реестра для /api/meta.
+type Registry struct {
+	Models []Model `json:"models"`
+}
+
+// Model — модель после линковки: дефолтные FK/PK связей уже подставлены,
+// пресеты содержат поля после разворачивания extends.
+type Model struct {
+	Name         string                  `json:"name"`
+	Table        string                  `json:"table"`
+	PrimaryKeys  []string                `json:"primary_keys"`
+	Relations    map[string]Relation     `json:"relations"`
+	Aliases      map[string]string       `json:"aliases"`
+	Computable   map[string]Computable   `json:"computable"`
+	Aggregatable map[string]Aggregatable `json:"aggregatable"`
+	Presets      map[string]Preset       `json:"presets"`
+	Search       *Search                 `json:"search,omitempty"`
+}
+
+// Search — поля полнотекстового поиска (__fts, q, сортировка rank).
+type Search struct {
+	Config string   `json:"config"`
+	Fields []string `json:"fields"`
+}
+
+// Relation не раскрывает SQL из where/through_where — только структуру связи.
+type Relation struct {
+	Type        string `json:"type"`
+	Model       string `json:"model,omitempty"`
+	Table       string `json:"table,omitempty"`
+	FK          string `json:"fk"`
+	PK          string `json:"pk"`
+	Through     string `json:"through,omitempty"`
+	Polymorphic bool   `json:"polymorphic,omitempty"`
+	TypeColumn  string `json:"type_column,omitempty"`
+	Reentrant   bool   `json:"reentrant,omitempty"`
+	MaxDepth    int    `json:"max_depth,omitempty"`
+	Limit       int    `json:"limit,omitempty"`
+	Offset      int    `json:"offset,omitempty"`
+	ParentFK    string `json:"parent_fk,omitempty"`
+	Direction   string `json:"direction,omitempty"`
+}
+
+// Computable — имя и тип виртуального поля; выражение наружу не отдаётся.
+type Computable struct {
+	Type string `json:"type"`
+}
+
+type Aggregatable struct {
+	Type      string   `json:"type,omitempty"`
+	Functions []string `json:"functions"`
+}
+
+type Preset struct {
+	Extends string  `json:"extends,omitempty"`
+	Fields  []Field `json:"fields"`
+}
+
+// Field — поле пресета. Key — ключ в ответе (alias или source).
+type Field struct {
+	Key       string `json:"key"`
+	Source    string `json:"source"`
+	Type      string `json:"type"`
+	Preset    string `json:"preset,omitempty"`
+	Formatter string `json:"formatter,omitempty"`
+	Internal  bool   `json:"internal,omitempty"`
+	Localize  bool   `json:"localize,omitempty"`
+}
+
+// Build описывает все модели реестра в порядке имён.
+func Build(registry map[string]*model.Model) Registry {
+	names := make([]string, 0, len(registry))
+	for name, m := range registry {
+		if m != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := Registry{Models: make([]Model, 0, len(names))}
+	for _, name := range names {
+		out.Models = append(out.Models, Describe(name, registry[name]))
+	}
+	return out
+}
+
+// Describe описывает одну модель.
+func Describe(name string, m *model.Model) Model {
+	out := Model{
+		Name:         name,
+		Table:        m.Table,
+		PrimaryKeys:  m.GetPrimaryKeys(),
+		Relations:    map[string]Relation{},
+		Aliases:      map[string]string{},
+		Computable:   map[string]Computable{},
+		Aggregatable: map[string]Aggregatable{},
+		Presets:      map[string]Preset{},
+	}
+	if m.Search != nil {
+		out.Search = &Search{Config: m.SearchConfigName(), Fields: append([]string{}, m.Search.Fields...)}
+	}
+	for relName, rel := range m.Relations {
+		if rel == nil {
+			continue
+		}
+		r := Relation{
+			Type:        rel.Type,
+			Model:       rel.Model,
+			Table:       rel.Table,
+			FK:          rel.FK,
+			PK:          rel.PK,
+			Through:     rel.Through,
+			Polymorphic: rel.Polymorphic,
+			TypeColumn:  rel.TypeColumn,
+			Reentrant:   rel.Reentrant,
+			MaxDepth:    rel.MaxDepth,
+			Limit:       rel.Limit,
+			Offset:      rel.Offset,
+			ParentFK:    rel.ParentFK,
+		}
+		if rel.Type == "tree" {
+			r.Direction = rel.TreeDirection()
+		}
+		if target := rel.GetModelRef(); target != nil && r.Table == "" {
+			r.Table = target.Table
+		}
+		out.Relations[relName] = r
+	}
+	for alias, path := range m.Aliases {
+		out.Aliases[alias] = path
+	}
+	for compName, comp := range m.Computable {
+		if comp != nil {
+			out.Computable[compName] = Computable{Type: comp.Type}
+		}
+	}
+	for field, agg := range m.Aggregatable {
+		if agg == nil {
+			continue
+		}
+		fns := make([]string, 0, len(agg.Functions))
+		for _, fn := range agg.Functions {
+			if fn = strings.ToLower(strings.TrimSpace(fn)); fn != "" {
+				fns = append(fns, fn)
+			}
+		}
+		out.Aggregatable[field] = Aggregatable{Type: agg.Type, Functions: fns}
+	}
+	for presetName, p := range m.Presets {
+		if p == nil {
+			continue
+		}
+		fields := make([]Field, 0, len(p.Fields))
+		for _, f := range p.Fields {
+			key := f.Alias
+			if strings.TrimSpace(key) == "" {
+				key = f.Source
+			}
+			fields = append(fields, Field{
+				Key:       key,
+				Source:    f.Source,
+				Type:      f.Type,
+				Preset:    f.NestedPreset,
+				Formatter: f.Formatter,
+				Internal:  f.Internal,
+				Localize:  f.Localize,
+			})
+		}
+		out.Presets[presetName] = Preset{Extends: p.Extends, Fields: fields}
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package meta
+
+import (
+	"reflect"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+func loadTestRegistry(t *testing.T) map[string]*model.Model {
+	t.Helper()
+	if err := model.InitRegistry("../../test_db"); err != nil {
+		t.Fatalf("init registry: %v", err)
+	}
+	return model.Registry
+}
+
+func findModel(t *testing.T, reg Registry, name string) Model {
+	t.Helper()
+	for _, m := range reg.Models {
+		if m.Name == name {
+			return m
+		}
+	}
+	t.Fatalf("model %s missing", name)
+	return Model{}
+}
+
+func TestBuildDescribesLinkedRegistry(t *testing.T) {
+	reg := Build(loadTestRegistry(t))
+	for i := 1; i < len(reg.Models); i++ {
+		if reg.Models[i-1].Name >= reg.Models[i].Name {
+			t.Fatalf("models must be sorted: %s before %s", reg.Models[i-1].Name, reg.Models[i].Name)
+		}
+	}
+
+	person := findModel(t, reg, "Person")
+	if person.Table != "people" || !reflect.DeepEqual(person.PrimaryKeys, []string{"id"}) {
+		t.Fatalf("unexpected person: table=%q pks=%v", person.Table, person.PrimaryKeys)
+	}
+	// FK/PK по умолчанию подставлены линковщиком
+	want := Relation{Type: "has_many", Model: "Contact", Table: "contacts", FK: "person_id", PK: "id", Through: "PersonContact"}
+	if got := person.Relations["contacts"]; got != want {
+		t.Fatalf("unexpected contacts relation: %+v", got)
+	}
+
+	// поля card — после разворачивания extends: item
+	card := person.Presets["card"]
+	if card.Extends != "item" {
+		t.Fatalf("unexpected extends: %q", card.Extends)
+	}
+	keys := make([]string, 0, len(card.Fields))
+	for _, f := range card.Fields {
+		keys = append(keys, f.Key)
+	}
+	for _, key := range []string{"id", "last_name", "full_name", "person_id", "first_name"} {
+		found := false
+		for _, k := range keys {
+			found = found || k == key
+		}
+		if !found {
+			t.Fatalf("card must contain %s, got %v", key, keys)
+		}
+	}
+
+	employee := findModel(t, reg, "Employee")
+	agg := employee.Aggregatable["hired_at"]
+	if agg.Type != "date" || !reflect.DeepEqual(agg.Functions, []string{"min", "max"}) {
+		t.Fatalf("unexpected aggregatable: %+v", agg)
+	}
+}
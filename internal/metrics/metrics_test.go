@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCounterAndHistogramExposition(t *testing.T) {
+	c := &CounterVec{vec{name: "t_total", help: "Test counter.", labels: []string{"path"}, series: map[string]*series{}}}
+	c.Inc(`/a"b`)
+	c.Add(2, `/a"b`)
+	h := &HistogramVec{vec: vec{name: "t_seconds", help: "Test histogram.", labels: []string{"kind"}, series: map[string]*series{}}, bounds: []float64{0.1, 1}}
+	h.Observe(0.05, "root")
+	h.Observe(0.5, "root")
+	h.Observe(5, "root")
+
+	var b bytes.Buffer
+	c.write(&b)
+	h.write(&b)
+	want := `# HELP t_total Test counter.
+# TYPE t_total counter
+t_total{path="/a\"b"} 3
+# HELP t_seconds Test histogram.
+# TYPE t_seconds histogram
+t_seconds_bucket{kind="root",le="0.1"} 1
+t_seconds_bucket{kind="root",le="1"} 2
+t_seconds_bucket{kind="root",le="+Inf"} 3
+t_seconds_sum{kind="root"} 5.55
+t_seconds_count{kind="root"} 3
+`
+	if b.String() != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestObserveRequestUsesContextLabels(t *testing.T) {
+	ctx, labels := WithRequest(context.Background())
+	SetModelPreset(ctx, "Person", "item")
+	before := requestsTotal.Value("/api/index", "Person", "item", "500")
+	errsBefore := httpErrors.Value("/api/index", "500")
+
+	ObserveRequest("/api/index", labels, 500, 20*time.Millisecond)
+
+	if got := requestsTotal.Value("/api/index", "Person", "item", "500"); got != before+1 {
+		t.Fatalf("requests_total=%v, want %v", got, before+1)
+	}
+	if got := httpErrors.Value("/api/index", "500"); got != errsBefore+1 {
+		t.Fatalf("http_errors_total=%v, want %v", got, errsBefore+1)
+	}
+	if requestDuration.Count("/api/index", "Person", "item") == 0 {
+		t.Fatal("latency must be observed")
+	}
+
+	// без WithRequest метки пустые, SetModelPreset ничего не ломает
+	SetModelPreset(context.Background(), "X", "y")
+	ObserveRequest("/healthz", nil, 200, time.Millisecond)
+	var b bytes.Buffer
+	WriteAll(&b)
+	if !strings.Contains(b.String(), `yrest_requests_total{endpoint="/healthz",model="",preset="",status="200"}`) {
+		t.Fatalf("missing healthz series:\n%s", b.String())
+	}
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Метрики сервиса.
+var (
+	requestsTotal = NewCounterVec("yrest_requests_total",
+		"HTTP requests by endpoint, model, preset, and status.",
+		"endpoint", "model", "preset", "status")
+	requestDuration = NewHistogramVec("yrest_request_duration_seconds",
+		"HTTP request latency by endpoint, model, and preset.",
+		DefBuckets, "endpoint", "model", "preset")
+	httpErrors = NewCounterVec("yrest_http_errors_total",
+		"HTTP responses with status >= 400 by path and status.",
+		"path", "status")
+	queryDuration = NewHistogramVec("yrest_sql_query_duration_seconds",
+		"SQL query duration (execution and row scan) by kind: root, tail, count.",
+		DefBuckets, "kind")
+	tailWait = NewHistogramVec("yrest_tail_queue_wait_seconds",
+		"Time tail queries wait for a per-request or global concurrency slot.",
+		DefBuckets)
+)
+
+// Виды SQL-запросов для ObserveQuery.
+const (
+	QueryRoot  = "root"  // главный SELECT запроса
+	QueryTail  = "tail"  // догрузка has_one/has_many/полиморфных связей
+	QueryCount = "count" // COUNT / агрегаты / distinct
+)
+
+// RequestLabels — модель и пресет запроса. Обработчик заполняет их после
+// того, как модель найдена, поэтому в метки не попадают произвольные строки.
+type RequestLabels struct {
+	mu     sync.Mutex
+	model  string
+	preset string
+}
+
+type labelsKey struct{}
+
+// WithRequest кладёт в контекст пустые метки запроса.
+func WithRequest(ctx context.Context) (context.Context, *RequestLabels) {
+	l := &RequestLabels{}
+	return context.WithValue(ctx, labelsKey{}, l), l
+}
+
+// SetModelPreset задаёт метки текущего запроса (no-op вне WithRequest).
+func SetModelPreset(ctx context.Context, model, preset string) {
+	l, ok := ctx.Value(labelsKey{}).(*RequestLabels)
+	if !ok || l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.model, l.preset = model, preset
+	l.mu.Unlock()
+}
+
+// ObserveRequest учитывает завершённый HTTP-запрос.
+func ObserveRequest(path string, l *RequestLabels, status int, d time.Duration) {
+	var modelName, preset string
+	if l != nil {
+		l.mu.Lock()
+		modelName, preset = l.model, l.preset
+		l.mu.Unlock()
+	}
+	code := strconv.Itoa(status)
+	requestsTotal.Inc(path, modelName, preset, code)
+	requestDuration.Observe(d.Seconds(), path, modelName, preset)
+	if status >= 400 {
+		httpErrors.Inc(path, code)
+	}
+}
+
+// ObserveQuery учитывает длительность SQL-запроса вида kind.
+func ObserveQuery(kind string, d time.Duration) {
+	queryDuration.Observe(d.Seconds(), kind)
+}
+
+// ObserveTailWait учитывает ожидание слота tail-запросом.
+func ObserveTailWait(d time.Duration) {
+	tailWait.Observe(d.Seconds())
+}
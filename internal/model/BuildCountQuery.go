@@ -40,7 +40,7 @@ func (m *Model) BuildCountQuery(aliasMap *AliasMap, preset *DataPreset, filters
 		if join.Where != "" {
 			onClause = fmt.Sprintf("(%s) AND (%s)", join.On, join.Where)
 		}
-		sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause))
+		sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause), join.WhereArgs...)
 		if join.Distinct {
 			hasDistinct = true
 		}
@@ -81,7 +81,7 @@ func (m *Model) BuildCountQuery(aliasMap *AliasMap, preset *DataPreset, filters
 		if join.Where != "" {
 			onClause = fmt.Sprintf("(%s) AND (%s)", join.On, join.Where)
 		}
-		inner = inner.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause))
+		inner = inner.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause), join.WhereArgs...)
 	}
 	if wherePart != nil {
 		inner = inner.Where(wherePart)
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"YrestAPI/internal/logger"
+
 	"github.com/Masterminds/squirrel"
 )
 
@@ -42,18 +44,18 @@ func (m *Model) BuildIndexQuery(
 	preset *DataPreset, // выбранный пресет
 	offset, limit uint64, // пагинация
 ) (squirrel.SelectBuilder, error) {
+	sb, _, err := m.buildIndexQuery(aliasMap, filters, sorts, preset, offset, limit, nil, nil)
+	return sb, err
+}
 
-	sb := squirrel.SelectBuilder{}.PlaceholderFormat(squirrel.Dollar)
-
-	// 1. FROM
-	sb = sb.From(fmt.Sprintf("%s AS main", m.Table))
-
-	// 2. Определяем список полей для выборки c учётом пресета
-	if preset == nil {
-		return sb, fmt.Errorf("preset is nil for model '%s'", m.Table)
-	}
-
-	// 3. Определяем JOIN-ы по всем фильтрам, включая вложенные or/and-группы.
+// IndexJoins возвращает JOIN-ы, которые BuildIndexQuery добавит для фильтров,
+// сортировок и полей пресета (до замены has_many на CTE).
+func (m *Model) IndexJoins(
+	aliasMap *AliasMap,
+	filters map[string]interface{},
+	sorts []string,
+	preset *DataPreset,
+) ([]*JoinSpec, error) {
 	filterKeys := PathsFromFilters(filters)
 
 	sortFields := make([]string, len(sorts))
@@ -66,16 +68,39 @@ func (m *Model) BuildIndexQuery(
 	if len(compPaths) > 0 {
 		presetFieldPaths = append(presetFieldPaths, compPaths...)
 	}
-	joinSpecs, err := m.DetectJoins(aliasMap, filterKeys, sortFields, presetFieldPaths)
+	return m.DetectJoins(aliasMap, filterKeys, sortFields, presetFieldPaths)
+}
+
+func (m *Model) buildIndexQuery(
+	aliasMap *AliasMap,
+	filters map[string]interface{},
+	sorts []string,
+	preset *DataPreset,
+	offset, limit uint64,
+	seek *keysetSeek, // nil — обычная пагинация OFFSET/LIMIT
+	window *partitionWindow, // не nil — offset/limit на каждого родителя вместо всего запроса
+) (squirrel.SelectBuilder, []KeysetColumn, error) {
+
+	sb := squirrel.SelectBuilder{}.PlaceholderFormat(squirrel.Dollar)
+
+	// 1. FROM
+	sb = sb.From(fmt.Sprintf("%s AS main", m.Table))
+
+	// 2. Определяем список полей для выборки c учётом пресета
+	if preset == nil {
+		return sb, nil, fmt.Errorf("preset is nil for model '%s'", m.Table)
+	}
 
+	// 3. Определяем JOIN-ы по всем фильтрам, включая вложенные or/and-группы.
+	joinSpecs, err := m.IndexJoins(aliasMap, filters, sorts, preset)
 	if err != nil {
-		return sb, err
+		return sb, nil, err
 	}
 	cteSpecs, computableOverride, skipAliases := buildHasManyCTEs(m, preset, filters, sorts, aliasMap, joinSpecs)
 	if len(cteSpecs) > 0 {
 		prefixSQL, prefixArgs, err := buildCTEQueries(m, cteSpecs)
 		if err != nil {
-			return sb, err
+			return sb, nil, err
 		}
 		sb = sb.Prefix(prefixSQL, prefixArgs...)
 		for _, spec := range cteSpecs {
@@ -91,7 +116,7 @@ func (m *Model) BuildIndexQuery(
 		if join.Where != "" {
 			onClause = fmt.Sprintf("(%s) AND (%s)", join.On, join.Where)
 		}
-		sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause))
+		sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause), join.WhereArgs...)
 		if join.Distinct {
 			hasDistinct = true
 		}
@@ -152,7 +177,7 @@ func (m *Model) BuildIndexQuery(
 	// 4. WHERE фильтры
 	whereBuilder, havingBuilder, err := m.buildWhereClause(aliasMap, preset, filters, joinSpecs, computableOverride)
 	if err != nil {
-		return sb, err
+		return sb, nil, err
 	}
 	if whereBuilder != nil {
 		sb = sb.Where(whereBuilder)
@@ -171,6 +196,35 @@ func (m *Model) BuildIndexQuery(
 	}
 
 	orderExprs := make([]string, 0, len(sorts))
+	orderKeys := make([]KeysetColumn, 0, len(sorts))
+	orderArgs := make(map[int][]any) // параметры выражений ORDER BY (rank)
+	addOrder := func(path, baseExpr, dir string) {
+		orderExpr := baseExpr
+		if dir != "" {
+			orderExpr += " " + dir
+		}
+		orderExprs = append(orderExprs, orderExpr)
+		desc, nullsFirst := keysetDirection(dir)
+		orderKeys = append(orderKeys, KeysetColumn{Path: path, Expr: baseExpr, Desc: desc, NullsFirst: nullsFirst})
+	}
+	addSelectExpr := func(expr string) {
+		if !hasDistinct || expr == "" {
+			return
+		}
+		key := normalizeExpr(expr)
+		if _, ok := existingSelect[key]; ok {
+			return
+		}
+		selectCols = append(selectCols, SelectColumn{Expr: expr, Key: "", Type: ""})
+		colExprs = append(colExprs, expr)
+		existingSelect[key] = struct{}{}
+		if isSimpleColumnExpr(expr) {
+			col := baseColumnExpr(expr)
+			if !containsString(groupByCols, col) {
+				groupByCols = append(groupByCols, col)
+			}
+		}
+	}
 	for _, s := range sorts {
 		parts := strings.SplitN(s, " ", 2) // [path, direction?]
 		fieldPath := expandPathWithAliases(m, parts[0])
@@ -179,31 +233,23 @@ func (m *Model) BuildIndexQuery(
 			dir = strings.TrimSpace(parts[1])
 		}
 
-		addSelectExpr := func(expr string) {
-			if !hasDistinct || expr == "" {
-				return
+		if fieldPath == RankSortKey && m.Search != nil {
+			// релевантность поиска; в keyset и оконных запросах параметры ORDER BY не поддерживаются
+			expr, args, ok := m.rankOrder(preset, aliasMap, filters)
+			if !ok || seek != nil || window != nil {
+				logger.Warn("rank_sort_ignored", map[string]any{"model": m.Name})
+				continue
 			}
-			key := normalizeExpr(expr)
-			if _, ok := existingSelect[key]; ok {
-				return
-			}
-			selectCols = append(selectCols, SelectColumn{Expr: expr, Key: "", Type: ""})
-			colExprs = append(colExprs, expr)
-			existingSelect[key] = struct{}{}
-			if isSimpleColumnExpr(expr) {
-				col := baseColumnExpr(expr)
-				if !containsString(groupByCols, col) {
-					groupByCols = append(groupByCols, col)
-				}
+			if dir == "" {
+				dir = "DESC"
 			}
+			addOrder(fieldPath, expr, dir)
+			orderArgs[len(orderExprs)-1] = args
+			continue
 		}
 
 		if expr, ok := computableOverride[fieldPath]; ok {
-			orderExpr := expr
-			if dir != "" {
-				orderExpr += " " + dir
-			}
-			orderExprs = append(orderExprs, orderExpr)
+			addOrder(fieldPath, expr, dir)
 			addSelectExpr(expr)
 			continue
 		}
@@ -223,11 +269,7 @@ func (m *Model) BuildIndexQuery(
 						if baseExpr == "" {
 							continue
 						}
-						orderExpr := baseExpr
-						if dir != "" {
-							orderExpr += " " + dir
-						}
-						orderExprs = append(orderExprs, orderExpr)
+						addOrder(fieldPath, baseExpr, dir)
 						addSelectExpr(baseExpr)
 						handled = true
 						break
@@ -241,11 +283,7 @@ func (m *Model) BuildIndexQuery(
 
 		if expr, ok := m.resolveFieldExpression(preset, aliasMap, fieldPath); ok {
 			baseExpr := strings.TrimSpace(expr)
-			orderExpr := baseExpr
-			if dir != "" {
-				orderExpr += " " + dir
-			}
-			orderExprs = append(orderExprs, orderExpr)
+			addOrder(fieldPath, baseExpr, dir)
 			addSelectExpr(baseExpr)
 			continue
 		}
@@ -267,14 +305,32 @@ func (m *Model) BuildIndexQuery(
 		}
 
 		// Финальное выражение для ORDER BY
-		orderExpr := fmt.Sprintf("%s.%s", alias, fieldName)
-		if dir != "" {
-			orderExpr += " " + dir
-		}
-		orderExprs = append(orderExprs, orderExpr)
+		addOrder(fieldPath, fmt.Sprintf("%s.%s", alias, fieldName), dir)
 		addSelectExpr(fmt.Sprintf("%s.%s", alias, fieldName))
 	}
 
+	// 5.1. Keyset: скрытые колонки с ключами сортировки + условие "после курсора"
+	if seek != nil {
+		for i, key := range orderKeys {
+			addSelectExpr(key.Expr)
+			colExprs = append(colExprs, fmt.Sprintf("%s AS %s", key.Expr, quoteIdentifier(fmt.Sprintf("__keyset_%d", i))))
+		}
+		if seek.After != nil {
+			cond, agg, err := m.keysetCondition(orderKeys, seek.After)
+			if err != nil {
+				return sb, nil, err
+			}
+			if agg {
+				sb = sb.Having(cond)
+			} else {
+				sb = sb.Where(cond)
+			}
+		}
+	}
+
+	if window != nil {
+		colExprs = append(colExprs, window.rowNumberExpr(orderExprs))
+	}
 	sb = sb.Columns(colExprs...)
 	if hasDistinct && len(groupByCols) > 0 {
 		sb = sb.GroupBy(groupByCols...)
@@ -282,8 +338,11 @@ func (m *Model) BuildIndexQuery(
 		sb = sb.Distinct()
 	}
 
-	for _, expr := range orderExprs {
-		sb = sb.OrderBy(expr)
+	if window != nil {
+		return window.wrap(sb), orderKeys, nil
+	}
+	for i, expr := range orderExprs {
+		sb = sb.OrderByClause(expr, orderArgs[i]...)
 	}
 
 	// 6. LIMIT / OFFSET
@@ -294,7 +353,7 @@ func (m *Model) BuildIndexQuery(
 		sb = sb.Offset(offset)
 	}
 
-	return sb, nil
+	return sb, orderKeys, nil
 }
 
 // isSimpleColumnExpr определяет, является ли выражение простым обращением к колонке alias.column (с кавычками/без).
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -16,6 +17,26 @@ func isAggregateExpr(expr string) bool {
 	return aggregateRe.MatchString(expr)
 }
 
+// FilterError — фильтр нельзя применить: неизвестный оператор, неверное
+// значение квантора или поиска, связь не того типа. Запрос отклоняется (400), а не выполняется
+// без условия.
+type FilterError struct {
+	Message string
+}
+
+func (e *FilterError) Error() string { return e.Message }
+
+// filterError оборачивает ошибку условия field в FilterError; ошибки
+// политик (PolicyError) и уже готовые FilterError возвращаются как есть.
+func filterError(field string, err error) error {
+	var policyErr *PolicyError
+	var filterErr *FilterError
+	if errors.As(err, &policyErr) || errors.As(err, &filterErr) {
+		return err
+	}
+	return &FilterError{Message: fmt.Sprintf("invalid filter %q: %s", field, err)}
+}
+
 func (m *Model) buildWhereClause(
 	aliasMap *AliasMap,
 	preset *DataPreset,
@@ -23,6 +44,8 @@ func (m *Model) buildWhereClause(
 	joins []*JoinSpec,
 	computableOverride map[string]string,
 ) (squirrel.Sqlizer, squirrel.Sqlizer, error) {
+	// первая ошибка фильтра: условия строятся замыканиями без возврата ошибок
+	var condErr error
 	var buildGroupExpr func(map[string]any, string) (squirrel.Sqlizer, bool)
 	var buildGroupAnd func(map[string]any) (squirrel.Sqlizer, squirrel.Sqlizer)
 
@@ -80,11 +103,62 @@ func (m *Model) buildWhereClause(
 			baseOp = strings.TrimSuffix(baseOp, "_cs")
 			caseSensitive = true
 		}
+		if isQuantifierOp(baseOp) {
+			// квантор над has_one/has_many: коррелированный подзапрос без JOIN в главном запросе
+			cond, err := m.quantifierCond(aliasMap, field, baseOp, val)
+			if err != nil {
+				if condErr == nil {
+					condErr = filterError(field+"__"+op, err)
+				}
+				return nil, false
+			}
+			return []squirrel.Sqlizer{cond}, false
+		}
+		if baseOp == "descendants_of" || baseOp == "ancestors_of" {
+			// фильтр по tree-связи: строки из поддерева / цепочки родителей узлов val
+			if rel := m.Relations[field]; rel != nil && rel.Type == "tree" {
+				cond, err := m.treeFilterCond(rel, baseOp, val, aliasMap.Claims())
+				if err != nil {
+					if condErr == nil {
+						condErr = filterError(field+"__"+op, err)
+					}
+					return nil, false
+				}
+				return []squirrel.Sqlizer{cond}, false
+			}
+			if condErr == nil {
+				condErr = filterError(field+"__"+op, fmt.Errorf("%s needs a tree relation", baseOp))
+			}
+			return nil, false
+		}
+		if baseOp == searchOp {
+			// полнотекстовый поиск: составное поле — один документ из всех частей
+			exprs := make([]string, 0, len(fields))
+			for _, f := range fields {
+				if f == "" {
+					continue
+				}
+				if expr := resolveField(f); expr != "" {
+					exprs = append(exprs, expr)
+				}
+			}
+			cond, err := m.searchCond(exprs, val)
+			if err != nil {
+				if condErr == nil {
+					condErr = filterError(field+"__"+op, err)
+				}
+				return nil, false
+			}
+			return []squirrel.Sqlizer{cond}, false
+		}
 
 		for _, f := range fields {
 			expr := resolveField(f)
 			if expr == "" {
-				continue
+				if condErr == nil {
+					condErr = filterError(field+"__"+op, fmt.Errorf("field %q not found", f))
+				}
+				return nil, false
 			}
 			fieldType := resolveFilterFieldType(m, f)
 			sqlField := expr
@@ -114,7 +188,11 @@ func (m *Model) buildWhereClause(
 					cond = squirrel.Eq{sqlField: val}
 				}
 			case "in":
-				cond = squirrel.Eq{sqlField: val} // поддерживает slice
+				if ids, ok := val.(IDList); ok {
+					cond = squirrel.Expr(fmt.Sprintf("%s = ANY(?)", sqlField), ids.arrayParam())
+				} else {
+					cond = squirrel.Eq{sqlField: val} // поддерживает slice
+				}
 			case "lt":
 				cond = squirrel.Lt{sqlField: val}
 			case "lte":
@@ -185,11 +263,15 @@ func (m *Model) buildWhereClause(
 				cond = squirrel.Expr(fmt.Sprintf("%s IS NOT NULL", sqlField))
 			}
 
-			if cond != nil {
-				parts = append(parts, cond)
-			} else {
-				logger.Warn("unknown_filter_operator", map[string]any{"op": op, "field": field})
+			if cond == nil {
+				// неизвестный оператор или значение не того типа: условие не
+				// отбрасывается — иначе запрос (и политика) шёл бы без него
+				if condErr == nil {
+					condErr = filterError(field+"__"+op, fmt.Errorf("unsupported operator or value %v", val))
+				}
+				return nil, false
 			}
+			parts = append(parts, cond)
 		}
 
 		if len(parts) == 0 {
@@ -375,6 +457,9 @@ func (m *Model) buildWhereClause(
 	}
 
 	where, having := buildGroupAnd(filters)
+	if condErr != nil {
+		return nil, nil, condErr
+	}
 	if where == nil && having == nil {
 		return nil, nil, nil
 	}
@@ -34,7 +34,11 @@ func (m *Model) CreateAliasMap(model *Model, preset *DataPreset, filters map[str
 // Построить FieldsAliasMap и FieldPaths для ВСЕХ пресетов во всём Registry.
 // Запускать ПОСЛЕ: LoadModelsFromDir(...) → линковка _ModelRef → ValidateAllPresets().
 func BuildPresetAliasMaps() error {
-	for _, m := range Registry {
+	return buildPresetAliasMaps(Registry)
+}
+
+func buildPresetAliasMaps(reg map[string]*Model) error {
+	for _, m := range reg {
 		if err := BuildPresetAliasMapsForModel(m); err != nil {
 			return fmt.Errorf("build alias maps for model %s: %w", m.Name, err)
 		}
@@ -75,43 +79,63 @@ func buildPresetAliasMapsForModel(m *Model, visited map[*Model]bool) error {
 
 	// Затем — строим карты для всех пресетов текущей модели (как раньше)
 	for presetName, dp := range m.Presets {
-		paths, err := collectPresetRelationPaths(m, presetName)
-		if err != nil {
+		if err := buildPresetAliasMap(m, dp); err != nil {
 			return fmt.Errorf("%s.%s: %w", m.Name, presetName, err)
 		}
-		sort.Slice(paths, func(i, j int) bool {
-			di, dj := strings.Count(paths[i], "."), strings.Count(paths[j], ".")
-			if di != dj {
-				return di < dj
-			}
-			return paths[i] < paths[j]
-		})
-		ptoa, atop := map[string]string{}, map[string]string{}
-		for i, p := range paths {
-			a := fmt.Sprintf("t%d", i)
-			ptoa[p] = a
-			atop[a] = p
+	}
+	return nil
+}
+
+// BuildSyntheticPresetAliasMap заполняет FieldsAliasMap пресета, собранного в
+// рантайме (не из YAML). Вложенные пресеты такого пресета задаются ссылками
+// (Field.SetPresetRef), поэтому их не нужно регистрировать в модели.
+func BuildSyntheticPresetAliasMap(m *Model, dp *DataPreset) error {
+	if m == nil || dp == nil {
+		return fmt.Errorf("nil model or preset")
+	}
+	return buildPresetAliasMap(m, dp)
+}
+
+func buildPresetAliasMap(m *Model, dp *DataPreset) error {
+	paths, err := collectPresetRelationPaths(m, dp)
+	if err != nil {
+		return err
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		di, dj := strings.Count(paths[i], "."), strings.Count(paths[j], ".")
+		if di != dj {
+			return di < dj
 		}
-		//dp.FieldPaths = paths
-		dp.FieldsAliasMap = &AliasMap{PathToAlias: ptoa, AliasToPath: atop}
+		return paths[i] < paths[j]
+	})
+	ptoa, atop := map[string]string{}, map[string]string{}
+	for i, p := range paths {
+		a := fmt.Sprintf("t%d", i)
+		ptoa[p] = a
+		atop[a] = p
 	}
+	//dp.FieldPaths = paths
+	dp.FieldsAliasMap = &AliasMap{PathToAlias: ptoa, AliasToPath: atop}
 	return nil
 }
 
 // Собирает все relation-пути ("a", "a.b", "a.b.c") из NestedPreset-полей данного пресета.
 // Учитывает политику ре-энтри по МОДЕЛИ: rel.Reentrant и лимит посещений модели effMax.
 // При отсутствии field.max_depth и relation.max_depth используется defaultReentrantMaxDepth.
-func collectPresetRelationPaths(root *Model, presetName string) ([]string, error) {
+func collectPresetRelationPaths(root *Model, preset *DataPreset) ([]string, error) {
 	set := make(map[string]struct{})
-	var dfs func(curr *Model, pName, currPath string, stack []*Model) error
-	dfs = func(curr *Model, pName, currPath string, stack []*Model) error {
-		pr := curr.Presets[pName]
+	var dfs func(curr *Model, pr *DataPreset, currPath string, stack []*Model) error
+	dfs = func(curr *Model, pr *DataPreset, currPath string, stack []*Model) error {
+		if pr == nil {
+			return nil
+		}
 		for _, f := range pr.Fields {
 			if strings.ToLower(strings.TrimSpace(f.Type)) != "preset" || f.NestedPreset == "" {
 				continue
 			}
 			rel := curr.Relations[f.Source]
-			if rel == nil || rel.Polymorphic {
+			if rel == nil || rel.Polymorphic || rel.Type == "tree" {
+				// tree догружается отдельным рекурсивным запросом — алиас не нужен
 				continue
 			}
 			next := rel._ModelRef
@@ -139,13 +163,17 @@ func collectPresetRelationPaths(root *Model, presetName string) ([]string, error
 			}
 			// --------------------------------
 
-			if err := dfs(next, f.NestedPreset, nextPath, append(stack, next)); err != nil {
+			nested := f._PresetRef
+			if nested == nil && next != nil {
+				nested = next.Presets[f.NestedPreset]
+			}
+			if err := dfs(next, nested, nextPath, append(stack, next)); err != nil {
 				return err
 			}
 		}
 		return nil
 	}
-	if err := dfs(root, presetName, "", []*Model{root}); err != nil {
+	if err := dfs(root, preset, "", []*Model{root}); err != nil {
 		return nil, err
 	}
 	// собрать отсортированный срез
@@ -101,6 +101,9 @@ func detectJoinsRecursive(
 					JoinType: "LEFT JOIN",
 					Where:    replaceTableWithAlias(rel.ThroughWhere, throughAlias),
 				}
+				if err := applyJoinPolicy(joinMap[throughAlias], rel._ThroughRef, aliasMap); err != nil {
+					return joins, err
+				}
 				joins = append(joins, joinMap[throughAlias])
 
 				// связь через промежуточную → ищем финальную
@@ -123,6 +126,9 @@ func detectJoinsRecursive(
 					Where:    replaceTableWithAlias(rel.Where, alias),
 					Distinct: rel.Type == "has_many" || rel.Type == "has_one",
 				}
+				if err := applyJoinPolicy(joinMap[alias], rel._ModelRef, aliasMap); err != nil {
+					return joins, err
+				}
 				joins = append(joins, joinMap[alias])
 			} else {
 				// Обычный JOIN
@@ -146,6 +152,9 @@ func detectJoinsRecursive(
 					Where:    replaceTableWithAlias(rel.Where, alias),
 					Distinct: rel.Type == "has_many" || rel.Type == "has_one",
 				}
+				if err := applyJoinPolicy(joinMap[alias], rel._ModelRef, aliasMap); err != nil {
+					return joins, err
+				}
 				joins = append(joins, joinMap[alias])
 			}
 		}
@@ -691,3 +691,39 @@ presets:
 		mustLoadAndExpectValidateErr(t, dir, "points to unknown model")
 	})
 }
+
+func TestLoadModelsFromDir_Inheritance_Consistent(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Order.yml", `
+table: orders
+presets:
+  base:
+    consistent: true
+    fields:
+      - source: id
+        type: int
+  item:
+    extends: base
+    fields:
+      - source: total
+        type: float
+  plain:
+    fields:
+      - source: id
+        type: int
+`)
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err != nil {
+		t.Fatalf("LoadModelsFromDir: %v", err)
+	}
+	m := getModel(t, "Order")
+	if !m.Presets["item"].Consistent {
+		t.Fatal("item must inherit consistent from base")
+	}
+	if m.Presets["plain"].Consistent {
+		t.Fatal("plain must stay non-consistent")
+	}
+}
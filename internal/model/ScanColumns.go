@@ -9,7 +9,7 @@ import (
 //   - обычные поля: <alias>.<field>
 //   - computable: expr/subquery с alias
 //   - belongs_to: рекурсивный проход по nested preset связанной модели
-//   - has_one/has_many: добавить РОВНО ОДИН ключ родителя — <parentAlias>.<rel.PK>
+//   - has_one/has_many/tree: добавить РОВНО ОДИН ключ родителя — <parentAlias>.<rel.PK>
 func (m *Model) ScanColumns(preset *DataPreset, aliasMap *AliasMap, prefix string) []SelectColumn {
 
 	if preset == nil {
@@ -90,7 +90,7 @@ func (m *Model) ScanColumns(preset *DataPreset, aliasMap *AliasMap, prefix strin
 				sub := rel._ModelRef.ScanColumns(nested, aliasMap, nextPrefix)
 				cols = append(cols, sub...)
 
-			case "has_one", "has_many":
+			case "has_one", "has_many", "tree":
 				// ДОБАВЛЯЕМ РОВНО ОДИН ключ родителя для дальнейшей догрузки has_ по ID
 				parentAlias := aliasFor(prefix)
 				pk := rel.PK
@@ -13,30 +13,46 @@ import (
 // Ключи строятся как "<path>.<column>" или просто "column" для корня.
 // Путь берём из m._AliasMap.AliasToPath (для "main" путь пустой).
 func (m *Model) ScanFlatRows(rows pgx.Rows, preset *DataPreset, aliasMap *AliasMap) ([]map[string]any, error) {
+	out, _, err := m.scanFlatRows(rows, preset, aliasMap, 0)
+	return out, err
+}
+
+// ScanKeysetRows работает как ScanFlatRows для запроса BuildKeysetIndexQuery:
+// последние keyCount колонок строки — значения ключей сортировки. Возвращает
+// их для последней строки (nil, если строк нет) — из них строится курсор.
+func (m *Model) ScanKeysetRows(rows pgx.Rows, preset *DataPreset, aliasMap *AliasMap, keyCount int) ([]map[string]any, []any, error) {
+	return m.scanFlatRows(rows, preset, aliasMap, keyCount)
+}
+
+func (m *Model) scanFlatRows(rows pgx.Rows, preset *DataPreset, aliasMap *AliasMap, keyCount int) ([]map[string]any, []any, error) {
 	if rows == nil {
-		return nil, fmt.Errorf("rows is nil")
+		return nil, nil, fmt.Errorf("rows is nil")
 	}
 	if aliasMap == nil {
-		return nil, fmt.Errorf("alias map is nil (AttachAliasMap must be called)")
+		return nil, nil, fmt.Errorf("alias map is nil (AttachAliasMap must be called)")
 	}
 
 	// 1) Восстанавливаем список выражений колонок так же, как их формировал BuildIndexQuery
 	cols := m.ScanColumns(preset, aliasMap, "")
 	if len(cols) == 0 {
-		return nil, fmt.Errorf("no columns resolved for scan (preset=%v)", preset != nil)
+		return nil, nil, fmt.Errorf("no columns resolved for scan (preset=%v)", preset != nil)
 	}
 
 	// 2) Читаем строки
 	out := make([]map[string]any, 0, 64)
+	var lastKeys []any
 	for rows.Next() {
 		vals, err := rows.Values()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		row := make(map[string]any, len(cols))
-		if len(vals) < len(cols) {
-			return nil, fmt.Errorf("expected %d columns, got %d", len(cols), len(vals))
+		if len(vals) < len(cols)+keyCount {
+			return nil, nil, fmt.Errorf("expected %d columns, got %d", len(cols)+keyCount, len(vals))
+		}
+		if keyCount > 0 {
+			lastKeys = vals[len(vals)-keyCount:]
 		}
 		for i, col := range cols {
 			v := vals[i]
@@ -81,9 +97,9 @@ func (m *Model) ScanFlatRows(rows pgx.Rows, preset *DataPreset, aliasMap *AliasM
 		out = append(out, row)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return out, nil
+	return out, lastKeys, nil
 }
 
 // FoldFlatRowByPreset сворачивает плоский row вида
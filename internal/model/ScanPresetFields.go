@@ -67,6 +67,14 @@ func (m *Model) ScanPresetFields(preset *DataPreset, prefix string) []string {
 		}
 		appendOnce(path)
 
+		// Синтетический пресет (собранный в рантайме) приходит готовой ссылкой
+		if f._PresetRef != nil && rel._ModelRef != nil {
+			for _, p := range rel._ModelRef.ScanPresetFields(f._PresetRef, path) {
+				appendOnce(p)
+			}
+			continue
+		}
+
 		// Если указан nested_preset (Preset внутри связанной модели), рекурсивно обходим его
 		if f.NestedPreset != "" {
 			// nestedPreset может быть "ModelName.preset" или просто "preset"
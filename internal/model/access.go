@@ -0,0 +1,305 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccessRule — роли, которым доступна модель или пресет.
+//
+//	access:
+//	  roles: [admin, manager]
+//
+// Роли вызывающего берутся из claim JWT (по умолчанию "roles", см. AUTH_ROLES_CLAIM).
+// Достаточно совпадения любой одной роли.
+type AccessRule struct {
+	Roles StringList `yaml:"roles"`
+}
+
+// AccessError — модель или пресет недоступны вызывающему. Сообщение одинаково
+// для скрытых и несуществующих пресетов, чтобы ответ не выдавал их наличие.
+type AccessError struct{}
+
+func (e *AccessError) Error() string {
+	return "access denied"
+}
+
+// Allows сообщает, что правило пропускает вызывающего с ролями roles.
+// Пустое правило (nil) ничего не ограничивает.
+func (r *AccessRule) Allows(roles []string) bool {
+	if r == nil {
+		return true
+	}
+	for _, want := range r.Roles {
+		for _, have := range roles {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VisibleFor сообщает, что значение поля можно показать вызывающему с ролями
+// roles. Поле без visible_to видно всем.
+func (f *Field) VisibleFor(roles []string) bool {
+	if len(f.VisibleTo) == 0 {
+		return true
+	}
+	return (&AccessRule{Roles: f.VisibleTo}).Allows(roles)
+}
+
+// hiddenFields возвращает имена полей модели (source и alias), значения
+// которых скрыты от ролей roles через visible_to хотя бы в одном пресете.
+// Фильтр, сортировка или агрегат по такому полю выдали бы значение косвенно.
+func (m *Model) hiddenFields(roles []string) map[string]bool {
+	var hidden map[string]bool
+	for _, p := range m.Presets {
+		if p == nil {
+			continue
+		}
+		for i := range p.Fields {
+			f := &p.Fields[i]
+			if f.Type == "preset" || f.Type == "formatter" || f.VisibleFor(roles) {
+				continue
+			}
+			if hidden == nil {
+				hidden = map[string]bool{}
+			}
+			hidden[f.Source] = true
+			if alias := strings.TrimSpace(f.Alias); alias != "" {
+				hidden[alias] = true
+			}
+		}
+	}
+	return hidden
+}
+
+// fieldHidden сообщает, что путь "rel.rel.field" ведёт к полю, скрытому от
+// ролей roles в модели, которой он заканчивается.
+func (m *Model) fieldHidden(path string, roles []string) bool {
+	segs := strings.Split(ExpandAliasPath(m, strings.TrimSpace(path)), ".")
+	curr := m
+	for _, seg := range segs[:len(segs)-1] {
+		rel := curr.Relations[seg]
+		if rel == nil || rel._ModelRef == nil {
+			return false
+		}
+		curr = rel._ModelRef
+	}
+	return curr.hiddenFields(roles)[segs[len(segs)-1]]
+}
+
+// CheckFieldVisibility отклоняет фильтры и поля (сортировки, unique_by,
+// агрегаты), которые ссылаются на поля, скрытые от ролей roles через
+// visible_to: иначе значение подбиралось бы фильтром. Ошибка — *AccessError
+// (403), без имени поля, как и при отказе в доступе к пресету.
+func CheckFieldVisibility(m *Model, roles []string, filters map[string]any, fields ...string) error {
+	if m == nil {
+		return nil
+	}
+	for _, f := range fields {
+		if parts := strings.Fields(f); len(parts) > 0 {
+			f = parts[0] // сортировка "field DESC"
+		}
+		if f == "" || f == RankSortKey {
+			continue
+		}
+		names, _ := ParseCompositeField(f)
+		for _, name := range names {
+			if m.fieldHidden(name, roles) {
+				return &AccessError{}
+			}
+		}
+	}
+	return m.checkFilterVisibility(filters, roles)
+}
+
+func (m *Model) checkFilterVisibility(v any, roles []string) error {
+	if m == nil {
+		return nil
+	}
+	switch val := v.(type) {
+	case []any:
+		for _, item := range val {
+			if err := m.checkFilterVisibility(item, roles); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for key, raw := range val {
+			if key == "or" || key == "and" {
+				if err := m.checkFilterVisibility(raw, roles); err != nil {
+					return err
+				}
+				continue
+			}
+			field, op := key, ""
+			if i := strings.Index(key, "__"); i >= 0 {
+				field, op = key[:i], key[i+2:]
+			}
+			if rel := m.Relations[field]; rel != nil && isQuantifierOp(strings.TrimSuffix(op, "_cs")) {
+				// фильтры квантора относятся к дочерней модели
+				if err := rel._ModelRef.checkFilterVisibility(raw, roles); err != nil {
+					return err
+				}
+				continue
+			}
+			if field == "" && op == searchOp && m.Search != nil {
+				field = strings.Join(m.Search.Fields, "_or_")
+			}
+			if err := CheckFieldVisibility(m, roles, nil, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UsesAccess сообщает, что модель или хотя бы один её пресет ограничены ролями.
+// Для таких моделей неизвестный пресет отвечает так же, как запрещённый.
+func (m *Model) UsesAccess() bool {
+	if m == nil {
+		return false
+	}
+	if m.Access != nil {
+		return true
+	}
+	for _, p := range m.Presets {
+		if p != nil && p.Access != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAccess проверяет доступ к модели, пресету и всем моделям и пресетам,
+// вложенным в него через связи (включая промежуточные through-модели).
+// Полиморфные связи здесь не проверяются: целевая модель известна только
+// после чтения строк, её access проверяет резолвер хвоста.
+func CheckAccess(m *Model, p *DataPreset, roles []string) error {
+	return checkAccess(m, p, roles, map[*DataPreset]bool{})
+}
+
+func checkAccess(m *Model, p *DataPreset, roles []string, seen map[*DataPreset]bool) error {
+	if m == nil {
+		return nil
+	}
+	if !m.Access.Allows(roles) {
+		return &AccessError{}
+	}
+	if p == nil || seen[p] {
+		return nil
+	}
+	seen[p] = true
+	if !p.Access.Allows(roles) {
+		return &AccessError{}
+	}
+	for i := range p.Fields {
+		f := &p.Fields[i]
+		if f.Type != "preset" {
+			continue
+		}
+		rel := m.Relations[f.Source]
+		if rel == nil || rel.Polymorphic {
+			continue
+		}
+		if through := rel.GetThroughRef(); through != nil && !through.Access.Allows(roles) {
+			return &AccessError{}
+		}
+		target := rel.GetModelRef()
+		if target == nil {
+			continue
+		}
+		nested := f.GetPresetRef()
+		if nested == nil && f.NestedPreset != "" {
+			nested = target.Presets[f.NestedPreset]
+		}
+		if err := checkAccess(target, nested, roles, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VisibleRegistry возвращает реестр в том виде, в каком его видит вызывающий
+// с ролями roles: без недоступных моделей и пресетов. Используется описаниями
+// API (/api/meta, /openapi.json, схема GraphQL). Если access нигде не задан,
+// возвращается reg как есть.
+func VisibleRegistry(reg map[string]*Model, roles []string) map[string]*Model {
+	restricted := false
+	for _, m := range reg {
+		if m.UsesAccess() {
+			restricted = true
+			break
+		}
+	}
+	if !restricted {
+		return reg
+	}
+	out := make(map[string]*Model, len(reg))
+	for name, m := range reg {
+		if !m.Access.Allows(roles) {
+			continue
+		}
+		cp := *m
+		cp.Presets = make(map[string]*DataPreset, len(m.Presets))
+		for pname, p := range m.Presets {
+			if CheckAccess(m, p, roles) == nil {
+				cp.Presets[pname] = p
+			}
+		}
+		out[name] = &cp
+	}
+	return out
+}
+
+// validateAccessRules отклоняет access и visible_to без ролей: такое правило
+// закрыло бы модель, пресет или поле для всех, что почти наверняка опечатка.
+// mask без visible_to не имеет смысла.
+func validateAccessRules(m *Model) error {
+	if m.Access != nil && len(m.Access.Roles) == 0 {
+		return fmt.Errorf("access.roles is empty in model")
+	}
+	for name, p := range m.Presets {
+		if p == nil {
+			continue
+		}
+		if p.Access != nil && len(p.Access.Roles) == 0 {
+			return fmt.Errorf("access.roles is empty in preset '%s'", name)
+		}
+		for _, f := range p.Fields {
+			if f.VisibleTo != nil && len(f.VisibleTo) == 0 {
+				return fmt.Errorf("visible_to is empty for field '%s' in preset '%s'", f.Source, name)
+			}
+			if f.Mask != "" && len(f.VisibleTo) == 0 {
+				return fmt.Errorf("mask requires visible_to for field '%s' in preset '%s'", f.Source, name)
+			}
+		}
+	}
+	return nil
+}
+
+// inheritAccess вычисляет access пресета без собственного правила по его
+// родителям из extends: доступны только роли, разрешённые всеми
+// ограниченными родителями.
+func inheritAccess(parents []*DataPreset) *AccessRule {
+	var rule *AccessRule
+	for _, parent := range parents {
+		if parent == nil || parent.Access == nil {
+			continue
+		}
+		if rule == nil {
+			rule = &AccessRule{Roles: append(StringList(nil), parent.Access.Roles...)}
+			continue
+		}
+		kept := StringList{}
+		for _, role := range rule.Roles {
+			if parent.Access.Allows([]string{role}) {
+				kept = append(kept, role)
+			}
+		}
+		rule.Roles = kept
+	}
+	return rule
+}
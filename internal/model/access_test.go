@@ -0,0 +1,252 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadModelsFromDir_Access(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Salary.yml", `
+table: salaries
+access:
+  roles: [admin, hr]
+presets:
+  item:
+    fields:
+      - source: id
+        type: int
+  audit:
+    access:
+      roles: admin, auditor
+    fields:
+      - source: amount
+        type: float
+  audit_full:
+    extends: audit
+    fields:
+      - source: comment
+        type: string
+  open:
+    extends: item
+`)
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err != nil {
+		t.Fatalf("LoadModelsFromDir: %v", err)
+	}
+	m := getModel(t, "Salary")
+	if !m.Access.Allows([]string{"hr"}) || m.Access.Allows([]string{"guest"}) {
+		t.Fatalf("unexpected model access: %#v", m.Access)
+	}
+	if got := m.Presets["audit"].Access.Roles; len(got) != 2 || got[1] != "auditor" {
+		t.Fatalf("unexpected preset roles: %#v", got)
+	}
+	// пресет без собственного access не шире родителя
+	if !m.Presets["audit_full"].Access.Allows([]string{"auditor"}) || m.Presets["audit_full"].Access.Allows([]string{"hr"}) {
+		t.Fatalf("audit_full should inherit access from audit: %#v", m.Presets["audit_full"].Access)
+	}
+	if m.Presets["open"].Access != nil {
+		t.Fatalf("open should stay unrestricted: %#v", m.Presets["open"].Access)
+	}
+
+	write(t, dir, "Salary.yml", "table: salaries\naccess:\n  users: [bob]\n")
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "unknown key 'users' in access") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+
+	write(t, dir, "Salary.yml", "table: salaries\npresets:\n  item:\n    access:\n      roles: []\n")
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "access.roles is empty in preset 'item'") {
+		t.Fatalf("expected empty roles error, got %v", err)
+	}
+}
+
+func TestInheritAccess_IntersectsParents(t *testing.T) {
+	rule := inheritAccess([]*DataPreset{
+		{Access: &AccessRule{Roles: StringList{"admin", "manager"}}},
+		{},
+		{Access: &AccessRule{Roles: StringList{"manager", "auditor"}}},
+	})
+	if rule == nil || len(rule.Roles) != 1 || rule.Roles[0] != "manager" {
+		t.Fatalf("unexpected rule: %#v", rule)
+	}
+	if inheritAccess([]*DataPreset{{}, nil}) != nil {
+		t.Fatalf("unrestricted parents must not produce a rule")
+	}
+}
+
+func accessFixture() map[string]*Model {
+	secret := &Model{
+		Name:   "Secret",
+		Access: &AccessRule{Roles: StringList{"admin"}},
+		Presets: map[string]*DataPreset{
+			"item": {Name: "item", Fields: []Field{{Source: "id", Type: "int"}}},
+		},
+	}
+	rel := &ModelRelation{Type: "has_many", Model: "Secret"}
+	rel.SetModelRef(secret)
+	person := &Model{
+		Name:      "Person",
+		Relations: map[string]*ModelRelation{"secrets": rel},
+		Presets: map[string]*DataPreset{
+			"item": {Name: "item", Fields: []Field{{Source: "id", Type: "int"}}},
+			"card": {Name: "card", Access: &AccessRule{Roles: StringList{"manager", "admin"}}, Fields: []Field{{Source: "id", Type: "int"}}},
+			"with_secrets": {Name: "with_secrets", Fields: []Field{
+				{Source: "id", Type: "int"},
+				{Source: "secrets", Type: "preset", NestedPreset: "item"},
+			}},
+		},
+	}
+	return map[string]*Model{"Person": person, "Secret": secret}
+}
+
+func TestCheckAccess_WalksNestedPresets(t *testing.T) {
+	reg := accessFixture()
+	person := reg["Person"]
+	var accessErr *AccessError
+
+	if err := CheckAccess(person, person.Presets["item"], nil); err != nil {
+		t.Fatalf("open preset denied: %v", err)
+	}
+	if err := CheckAccess(person, person.Presets["card"], []string{"guest"}); !errors.As(err, &accessErr) {
+		t.Fatalf("expected AccessError for card, got %v", err)
+	}
+	if err := CheckAccess(person, person.Presets["card"], []string{"manager"}); err != nil {
+		t.Fatalf("manager denied card: %v", err)
+	}
+	if err := CheckAccess(person, person.Presets["with_secrets"], []string{"manager"}); !errors.As(err, &accessErr) {
+		t.Fatalf("expected AccessError for nested Secret, got %v", err)
+	}
+	if err := CheckAccess(person, person.Presets["with_secrets"], []string{"admin"}); err != nil {
+		t.Fatalf("admin denied with_secrets: %v", err)
+	}
+	if err := CheckAccess(reg["Secret"], nil, nil); !errors.As(err, &accessErr) {
+		t.Fatalf("expected AccessError for model, got %v", err)
+	}
+	if accessErr.Error() != "access denied" {
+		t.Fatalf("unexpected message: %q", accessErr.Error())
+	}
+}
+
+func TestVisibleRegistry_HidesDeniedModelsAndPresets(t *testing.T) {
+	reg := accessFixture()
+
+	visible := VisibleRegistry(reg, []string{"manager"})
+	if _, ok := visible["Secret"]; ok {
+		t.Fatalf("Secret should be hidden")
+	}
+	presets := visible["Person"].Presets
+	if presets["item"] == nil || presets["card"] == nil || presets["with_secrets"] != nil {
+		t.Fatalf("unexpected visible presets: %v", presets)
+	}
+	if len(reg["Person"].Presets) != 3 {
+		t.Fatalf("source registry must not change")
+	}
+
+	open := map[string]*Model{"Person": {Name: "Person"}}
+	if got := VisibleRegistry(open, nil); got["Person"] != open["Person"] {
+		t.Fatalf("registry without access rules should be returned as is")
+	}
+}
+
+func TestLoadModelsFromDir_FieldVisibility(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Employee.yml", `
+table: employees
+presets:
+  card:
+    fields:
+      - source: salary
+        type: float
+        visible_to: [hr]
+      - source: passport
+        type: string
+        visible_to: hr, admin
+        mask: "***"
+`)
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err != nil {
+		t.Fatalf("LoadModelsFromDir: %v", err)
+	}
+	fields := getModel(t, "Employee").Presets["card"].Fields
+	if !fields[0].VisibleFor([]string{"hr"}) || fields[0].VisibleFor(nil) {
+		t.Fatalf("unexpected visible_to: %#v", fields[0].VisibleTo)
+	}
+	if fields[1].Mask != "***" || !fields[1].VisibleFor([]string{"admin"}) {
+		t.Fatalf("unexpected masked field: %#v", fields[1])
+	}
+
+	write(t, dir, "Employee.yml", "table: employees\npresets:\n  card:\n    fields:\n      - source: salary\n        type: float\n        mask: \"***\"\n")
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "mask requires visible_to") {
+		t.Fatalf("expected mask error, got %v", err)
+	}
+
+	write(t, dir, "Employee.yml", "table: employees\npresets:\n  card:\n    fields:\n      - source: salary\n        type: float\n        visible_to: []\n")
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "visible_to is empty") {
+		t.Fatalf("expected empty visible_to error, got %v", err)
+	}
+}
+
+func TestCheckFieldVisibility(t *testing.T) {
+	department := &Model{Name: "Department", Table: "departments", Presets: map[string]*DataPreset{
+		"item": {Name: "item", Fields: []Field{{Source: "budget", Type: "float", VisibleTo: StringList{"hr"}}}},
+	}}
+	employee := &Model{
+		Name:    "Employee",
+		Table:   "employees",
+		Aliases: map[string]string{"dep": "department"},
+		Search:  &SearchConfig{Fields: StringList{"name", "salary"}},
+		Relations: map[string]*ModelRelation{
+			"department": {Type: "belongs_to", _ModelRef: department},
+		},
+		Presets: map[string]*DataPreset{
+			"card": {Name: "card", Fields: []Field{
+				{Source: "name", Type: "string"},
+				{Source: "salary", Alias: "pay", Type: "float", VisibleTo: StringList{"hr"}},
+			}},
+		},
+	}
+	team := &Model{Name: "Team", Table: "teams", Relations: map[string]*ModelRelation{
+		"employees": {Type: "has_many", _ModelRef: employee},
+	}}
+
+	cases := []struct {
+		name    string
+		m       *Model
+		filters map[string]any
+		fields  []string
+		hidden  bool
+	}{
+		{"visible filter", employee, map[string]any{"name__eq": "Ann"}, nil, false},
+		{"hidden filter", employee, map[string]any{"salary__gt": 100}, nil, true},
+		{"hidden alias", employee, map[string]any{"pay__gt": 100}, nil, true},
+		{"composite", employee, map[string]any{"name_or_salary__cnt": "1"}, nil, true},
+		{"nested or", employee, map[string]any{"or": []any{map[string]any{"name__eq": "Ann"}, map[string]any{"salary__lt": 1}}}, nil, true},
+		{"relation path", employee, map[string]any{"dep.budget__gt": 1}, nil, true},
+		{"search", employee, map[string]any{"__fts": "ann"}, nil, true},
+		{"quantifier", team, map[string]any{"employees__any": map[string]any{"salary__gt": 100}}, nil, true},
+		{"sort", employee, nil, []string{"name ASC", "salary DESC"}, true},
+		{"rank sort", employee, nil, []string{"rank DESC"}, false},
+		{"unique_by or aggregate", employee, nil, []string{"department.budget"}, true},
+	}
+	for _, tc := range cases {
+		err := CheckFieldVisibility(tc.m, []string{"staff"}, tc.filters, tc.fields...)
+		var accessErr *AccessError
+		if tc.hidden != errors.As(err, &accessErr) {
+			t.Fatalf("%s: hidden=%v, got %v", tc.name, tc.hidden, err)
+		}
+		if err := CheckFieldVisibility(tc.m, []string{"hr"}, tc.filters, tc.fields...); err != nil {
+			t.Fatalf("%s: hr must see every field, got %v", tc.name, err)
+		}
+	}
+}
@@ -31,6 +31,33 @@ type aliasMapCache struct {
 	lastSweep  time.Time
 	totalBytes int64
 	maxBytes   int64
+	hits       uint64
+	misses     uint64
+	evictions  uint64 // удалены по TTL или сбросом кеша
+}
+
+// AliasCacheStats — снимок счётчиков кеша карт алиасов для /metrics.
+type AliasCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+	MaxBytes  int64
+}
+
+func GetAliasCacheStats() AliasCacheStats {
+	globalAliasCache.mu.Lock()
+	defer globalAliasCache.mu.Unlock()
+	c := globalAliasCache
+	return AliasCacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Entries:   len(c.items),
+		Bytes:     c.totalBytes,
+		MaxBytes:  c.maxBytes,
+	}
 }
 
 var globalAliasCache = &aliasMapCache{
@@ -43,18 +70,32 @@ func SetAliasCacheMaxBytes(maxBytes int64) {
 	globalAliasCache.maxBytes = maxBytes
 }
 
+// ResetAliasCache очищает кеш карт алиасов (после перезагрузки реестра).
+func ResetAliasCache() {
+	globalAliasCache.mu.Lock()
+	defer globalAliasCache.mu.Unlock()
+	globalAliasCache.evictions += uint64(len(globalAliasCache.items))
+	globalAliasCache.items = make(map[string]*aliasCacheEntry)
+	globalAliasCache.totalBytes = 0
+}
+
 func (c *aliasMapCache) get(key string, now time.Time) (*AliasMap, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.maybeSweepLocked(now)
 	entry, ok := c.items[key]
 	if !ok {
+		c.misses++
 		return nil, false
 	}
 	if now.Sub(entry.lastUsed) > aliasCacheTTL {
 		delete(c.items, key)
+		c.totalBytes -= estimateAliasMapBytes(entry.aliasMap)
+		c.evictions++
+		c.misses++
 		return nil, false
 	}
+	c.hits++
 	entry.lastUsed = now
 	return entry.aliasMap, true
 }
@@ -110,6 +151,7 @@ func (c *aliasMapCache) maybeSweepLocked(now time.Time) {
 		if now.Sub(entry.lastUsed) > aliasCacheTTL {
 			delete(c.items, key)
 			c.totalBytes -= estimateAliasMapBytes(entry.aliasMap)
+			c.evictions++
 		}
 	}
 	c.lastSweep = now
@@ -123,7 +165,10 @@ func aliasCacheKey(modelName string, preset *DataPreset, filters map[string]any,
 		presetName = "none"
 	}
 
+	// generation: карта, собранная запросом по старому реестру во время
+	// перезагрузки, не попадёт в выдачу для нового
 	payload := map[string]any{
+		"gen":     registryGen.Load(),
 		"model":   modelName,
 		"preset":  presetName,
 		"filters": filters,
@@ -88,6 +88,13 @@ func NormalizeFiltersWithAliases(m *Model, filters map[string]any) map[string]an
 					field = k[:i]
 					op = k[i+2:]
 				}
+				if field == "" && op == searchOp {
+					// "__fts" — поиск по полям search модели
+					if key := m.searchFieldKey(); key != "" {
+						field = key
+						changed = true
+					}
+				}
 				fields, comb := ParseCompositeField(field)
 				for i := range fields {
 					fields[i] = ExpandAliasPath(m, fields[i])
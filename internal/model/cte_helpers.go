@@ -127,7 +127,7 @@ func buildCTEQueries(m *Model, specs []cteSpec) (string, []any, error) {
 			if spec.Join.Where != "" {
 				onClause = fmt.Sprintf("(%s) AND (%s)", spec.Join.On, spec.Join.Where)
 			}
-			sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", spec.Join.Table, spec.Join.Alias, onClause))
+			sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", spec.Join.Table, spec.Join.Alias, onClause), spec.Join.WhereArgs...)
 		}
 
 		sb = sb.Column("main.id")
@@ -84,7 +84,7 @@ func (m *Model) BuildDistinctValuesQuery(aliasMap *AliasMap, filters map[string]
 		if join.Where != "" {
 			onClause = fmt.Sprintf("(%s) AND (%s)", join.On, join.Where)
 		}
-		base = base.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause))
+		base = base.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause), join.WhereArgs...)
 	}
 
 	wherePart, havingPart, err := m.buildWhereClause(aliasMap, nil, filters, joins, computableOverride)
@@ -0,0 +1,45 @@
+package model
+
+// IDList — значение фильтра __in, которое уходит в SQL одним параметром-массивом
+// (col = ANY($1)) вместо плейсхолдера на каждый элемент. Так резолвер передаёт
+// ключи родителей хвостам: длина списка не упирается в предел 65535 параметров
+// и не раздувает текст запроса.
+type IDList []any
+
+// arrayParam приводит однородный список к типизированному срезу: []int64 для
+// целых (в том числе целых float64 из JSON), []string для строк (UUID
+// приходят из ScanFlatRows строками). Иначе остаётся []any, и тип элементов
+// выводит pgx.
+func (l IDList) arrayParam() any {
+	ints := make([]int64, 0, len(l))
+	strs := make([]string, 0, len(l))
+	for _, v := range l {
+		switch x := v.(type) {
+		case int:
+			ints = append(ints, int64(x))
+		case int16:
+			ints = append(ints, int64(x))
+		case int32:
+			ints = append(ints, int64(x))
+		case int64:
+			ints = append(ints, x)
+		case float64:
+			// числа из JSON-фильтров приходят как float64
+			if x != float64(int64(x)) {
+				return []any(l)
+			}
+			ints = append(ints, int64(x))
+		case string:
+			strs = append(strs, x)
+		default:
+			return []any(l)
+		}
+	}
+	switch {
+	case len(ints) == len(l):
+		return ints
+	case len(strs) == len(l):
+		return strs
+	}
+	return []any(l)
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIDListUsesSingleArrayParam(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	ids := make(IDList, 70000)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+	sb, err := m.BuildIndexQuery(aliasMap, map[string]any{"id__in": ids}, nil, preset, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	if !strings.Contains(sql, "main.id = ANY($1)") || len(args) != 1 {
+		t.Fatalf("expected one array parameter, got %d args: %s", len(args), sql)
+	}
+	if arr, ok := args[0].([]int64); !ok || len(arr) != len(ids) {
+		t.Fatalf("expected []int64 parameter, got %T", args[0])
+	}
+}
+
+func TestIDListArrayParam(t *testing.T) {
+	cases := []struct {
+		in   IDList
+		want any
+	}{
+		{IDList{int32(1), int64(2)}, []int64{1, 2}},
+		{IDList{"a1", "b2"}, []string{"a1", "b2"}},
+		{IDList{int64(1), "b2"}, []any{int64(1), "b2"}},
+	}
+	for _, tc := range cases {
+		if got := tc.in.arrayParam(); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("arrayParam(%v) = %#v, want %#v", tc.in, got, tc.want)
+		}
+	}
+}
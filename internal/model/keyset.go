@@ -0,0 +1,192 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// KeysetColumn описывает один ключ сортировки keyset-пагинации.
+type KeysetColumn struct {
+	Path       string // путь сортировки из запроса ("id", "person.last_name")
+	Expr       string // SQL-выражение, по которому идёт ORDER BY
+	Desc       bool
+	NullsFirst bool // фактическое положение NULL (по умолчанию PostgreSQL: ASC → LAST, DESC → FIRST)
+}
+
+// keysetSeek — параметры seek-условия; After == nil означает первую страницу.
+type keysetSeek struct {
+	After []any
+}
+
+// KeysetSorts дополняет сортировки первичными ключами модели, чтобы порядок
+// был строгим и курсор однозначно указывал на строку. Направление добавленных
+// ключей берётся из последней сортировки (по умолчанию ASC) — так все колонки
+// чаще идут в одну сторону и seek остаётся сравнением кортежей.
+func (m *Model) KeysetSorts(sorts []string) []string {
+	out := make([]string, 0, len(sorts)+1)
+	present := make(map[string]struct{}, len(sorts))
+	dir := "ASC"
+	for _, s := range sorts {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		parts := strings.Fields(s)
+		present[parts[0]] = struct{}{}
+		dir = "ASC"
+		if len(parts) > 1 && strings.EqualFold(parts[1], "DESC") {
+			dir = "DESC"
+		}
+		out = append(out, s)
+	}
+	for _, pk := range m.GetPrimaryKeys() {
+		if _, ok := present[pk]; ok {
+			continue
+		}
+		out = append(out, pk+" "+dir)
+	}
+	return out
+}
+
+// BuildKeysetIndexQuery строит SELECT для keyset-пагинации: те же колонки, что и
+// BuildIndexQuery, плюс скрытые "__keyset_N" со значениями ключей сортировки в
+// конце строки. Если after задан — добавляется условие "строго после курсора"
+// вместо OFFSET (offset допустим только для первой страницы). sorts должны
+// быть уже дополнены через KeysetSorts.
+func (m *Model) BuildKeysetIndexQuery(
+	aliasMap *AliasMap,
+	filters map[string]interface{},
+	sorts []string,
+	preset *DataPreset,
+	after []any,
+	offset, limit uint64,
+) (squirrel.SelectBuilder, []KeysetColumn, error) {
+	return m.buildIndexQuery(aliasMap, filters, sorts, preset, offset, limit, &keysetSeek{After: after}, nil)
+}
+
+// keysetCondition строит условие "строка идёт после after" в порядке keys.
+// Второй результат сообщает, что условие ссылается на агрегаты и должно идти в HAVING.
+func (m *Model) keysetCondition(keys []KeysetColumn, after []any) (squirrel.Sqlizer, bool, error) {
+	if len(keys) != len(after) {
+		return nil, false, fmt.Errorf("cursor has %d values, sort has %d keys", len(after), len(keys))
+	}
+	agg := false
+	for _, k := range keys {
+		if isAggregateExpr(k.Expr) {
+			agg = true
+		}
+	}
+	if cond, ok := m.keysetRowCondition(keys, after); ok {
+		return cond, agg, nil
+	}
+
+	// Общий случай (разные направления, NULL в курсоре, явные NULLS FIRST/LAST):
+	// OR по k из (ключи до k равны AND ключ k "после" значения курсора).
+	or := squirrel.Or{}
+	for i, k := range keys {
+		gt := keysetAfter(k, after[i])
+		if gt == nil {
+			continue
+		}
+		and := squirrel.And{}
+		for j := 0; j < i; j++ {
+			and = append(and, keysetEqual(keys[j], after[j]))
+		}
+		and = append(and, gt)
+		or = append(or, and)
+	}
+	if len(or) == 0 {
+		return squirrel.Expr("FALSE"), agg, nil
+	}
+	return or, agg, nil
+}
+
+// keysetRowCondition — быстрый путь: все ключи в одну сторону с порядком NULL
+// по умолчанию и без NULL в курсоре → сравнение кортежей, которое PostgreSQL
+// умеет обслуживать составным индексом. Для ASC строки с NULL (идут последними)
+// кортеж не пропускает, поэтому они добавляются отдельными условиями.
+func (m *Model) keysetRowCondition(keys []KeysetColumn, after []any) (squirrel.Sqlizer, bool) {
+	desc := keys[0].Desc
+	for i, k := range keys {
+		if k.Desc != desc || k.NullsFirst != desc || after[i] == nil {
+			return nil, false
+		}
+	}
+	exprs := make([]string, len(keys))
+	marks := make([]string, len(keys))
+	for i, k := range keys {
+		exprs[i] = k.Expr
+		marks[i] = "?"
+	}
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	row := squirrel.Expr(fmt.Sprintf("(%s) %s (%s)", strings.Join(exprs, ", "), op, strings.Join(marks, ", ")), after...)
+	if desc {
+		return row, true
+	}
+
+	pks := make(map[string]struct{})
+	for _, pk := range m.GetPrimaryKeys() {
+		pks["main."+pk] = struct{}{}
+	}
+	or := squirrel.Or{row}
+	for i, k := range keys {
+		if _, ok := pks[k.Expr]; ok {
+			continue
+		}
+		and := squirrel.And{}
+		for j := 0; j < i; j++ {
+			and = append(and, keysetEqual(keys[j], after[j]))
+		}
+		and = append(and, squirrel.Expr(k.Expr+" IS NULL"))
+		or = append(or, and)
+	}
+	if len(or) == 1 {
+		return row, true
+	}
+	return or, true
+}
+
+func keysetEqual(k KeysetColumn, v any) squirrel.Sqlizer {
+	if v == nil {
+		return squirrel.Expr(k.Expr + " IS NULL")
+	}
+	return squirrel.Expr(k.Expr+" = ?", v)
+}
+
+// keysetAfter возвращает условие "значение ключа идёт после v" или nil, если таких нет.
+func keysetAfter(k KeysetColumn, v any) squirrel.Sqlizer {
+	if v == nil {
+		if k.NullsFirst {
+			return squirrel.Expr(k.Expr + " IS NOT NULL")
+		}
+		return nil
+	}
+	op := ">"
+	if k.Desc {
+		op = "<"
+	}
+	cmp := squirrel.Expr(fmt.Sprintf("%s %s ?", k.Expr, op), v)
+	if k.NullsFirst {
+		return cmp
+	}
+	return squirrel.Or{cmp, squirrel.Expr(k.Expr + " IS NULL")}
+}
+
+// keysetDirection разбирает направление сортировки ("DESC", "ASC NULLS FIRST", ...).
+func keysetDirection(dir string) (desc, nullsFirst bool) {
+	d := strings.ToUpper(strings.Join(strings.Fields(dir), " "))
+	desc = strings.HasPrefix(d, "DESC")
+	nullsFirst = desc
+	switch {
+	case strings.HasSuffix(d, "NULLS FIRST"):
+		nullsFirst = true
+	case strings.HasSuffix(d, "NULLS LAST"):
+		nullsFirst = false
+	}
+	return desc, nullsFirst
+}
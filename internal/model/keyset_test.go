@@ -0,0 +1,135 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeysetSortsAppendsPrimaryKey(t *testing.T) {
+	m := &Model{Table: "people"}
+	if got := m.KeysetSorts(nil); !reflect.DeepEqual(got, []string{"id ASC"}) {
+		t.Fatalf("unexpected sorts: %v", got)
+	}
+	if got := m.KeysetSorts([]string{"name DESC"}); !reflect.DeepEqual(got, []string{"name DESC", "id DESC"}) {
+		t.Fatalf("unexpected sorts: %v", got)
+	}
+	if got := m.KeysetSorts([]string{"id DESC"}); !reflect.DeepEqual(got, []string{"id DESC"}) {
+		t.Fatalf("primary key must not be duplicated: %v", got)
+	}
+}
+
+func TestBuildKeysetIndexQuery_FirstPage(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	sorts := m.KeysetSorts([]string{"name DESC"})
+
+	sb, keys, err := m.BuildKeysetIndexQuery(aliasMap, nil, sorts, preset, nil, 0, 20)
+	if err != nil {
+		t.Fatalf("BuildKeysetIndexQuery: %v", err)
+	}
+	sql, _, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Expr != "main.name" || !keys[0].Desc || keys[1].Expr != "main.id" {
+		t.Fatalf("unexpected keys: %#v", keys)
+	}
+	for _, want := range []string{`main.name AS "__keyset_0"`, `main.id AS "__keyset_1"`, "ORDER BY main.name DESC, main.id DESC", "LIMIT 20"} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL: %s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "WHERE") || strings.Contains(sql, "OFFSET") {
+		t.Fatalf("first page must not seek or offset: %s", sql)
+	}
+}
+
+func TestBuildKeysetIndexQuery_RowComparison(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	sorts := m.KeysetSorts([]string{"name DESC"})
+
+	sb, _, err := m.BuildKeysetIndexQuery(aliasMap, nil, sorts, preset, []any{"Smith", "42"}, 0, 20)
+	if err != nil {
+		t.Fatalf("BuildKeysetIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	if !strings.Contains(sql, "WHERE (main.name, main.id) < ($1, $2)") {
+		t.Fatalf("expected row comparison seek, got SQL: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []any{"Smith", "42"}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestBuildKeysetIndexQuery_AscIncludesTrailingNulls(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	sorts := m.KeysetSorts([]string{"name ASC"})
+
+	sb, _, err := m.BuildKeysetIndexQuery(aliasMap, nil, sorts, preset, []any{"Smith", "42"}, 0, 20)
+	if err != nil {
+		t.Fatalf("BuildKeysetIndexQuery: %v", err)
+	}
+	sql, _, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	want := "WHERE ((main.name, main.id) > ($1, $2) OR (main.name IS NULL))"
+	if !strings.Contains(sql, want) {
+		t.Fatalf("expected %q in SQL: %s", want, sql)
+	}
+}
+
+func TestBuildKeysetIndexQuery_MixedDirectionsAndNullCursor(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	sorts := m.KeysetSorts([]string{"name DESC", "id ASC"})
+
+	sb, _, err := m.BuildKeysetIndexQuery(aliasMap, nil, sorts, preset, []any{nil, "7"}, 0, 20)
+	if err != nil {
+		t.Fatalf("BuildKeysetIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	// DESC ставит NULL первыми: после NULL идут все непустые имена,
+	// а среди NULL — строки с большим id.
+	want := "WHERE ((main.name IS NOT NULL) OR (main.name IS NULL AND (main.id > $1 OR main.id IS NULL)))"
+	if !strings.Contains(sql, want) {
+		t.Fatalf("expected %q in SQL: %s", want, sql)
+	}
+	if !reflect.DeepEqual(args, []any{"7"}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestBuildKeysetIndexQuery_CursorLengthMismatch(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	sorts := m.KeysetSorts(nil)
+	if _, _, err := m.BuildKeysetIndexQuery(aliasMap, nil, sorts, preset, []any{"1", "2"}, 0, 20); err == nil {
+		t.Fatal("expected error for cursor/sort length mismatch")
+	}
+}
+
+func TestScanKeysetRowsReturnsLastKeys(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	rows := &stubRows{data: [][]any{
+		{int64(1), "Adams", "Adams", int64(1)},
+		{int64(2), "Brown", "Brown", int64(2)},
+	}}
+	items, keys, err := m.ScanKeysetRows(rows, preset, aliasMap, 2)
+	if err != nil {
+		t.Fatalf("ScanKeysetRows: %v", err)
+	}
+	if len(items) != 2 || items[1]["name"] != "Brown" {
+		t.Fatalf("unexpected items: %#v", items)
+	}
+	if _, leaked := items[1]["__keyset_0"]; leaked {
+		t.Fatalf("keyset column leaked into item: %#v", items[1])
+	}
+	if !reflect.DeepEqual(keys, []any{"Brown", int64(2)}) {
+		t.Fatalf("unexpected last keys: %#v", keys)
+	}
+}
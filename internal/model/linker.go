@@ -12,7 +12,11 @@ import (
 var formatterSrcRe = regexp.MustCompile(`\{[^}]+\}`)
 
 func LinkModelRelations() error {
-	for modelName, model := range Registry {
+	return linkModelRelations(Registry)
+}
+
+func linkModelRelations(reg map[string]*Model) error {
+	for modelName, model := range reg {
 		// 1. Link & validate relations
 		for relName, rel := range model.Relations {
 			if rel.Polymorphic {
@@ -24,8 +28,17 @@ func LinkModelRelations() error {
 				}
 				// FK/PK fallbacks below
 			} else {
+				// tree — связь таблицы с самой собой: модель по умолчанию текущая
+				if rel.Type == "tree" {
+					if rel.Model == "" {
+						rel.Model = modelName
+					}
+					if rel.Model != modelName {
+						return fmt.Errorf("invalid relation: tree relation '%s.%s' must point to its own model", modelName, relName)
+					}
+				}
 				// Модель должна существовать
-				targetModel, ok := Registry[rel.Model]
+				targetModel, ok := reg[rel.Model]
 				if !ok {
 					return fmt.Errorf("invalid relation: model '%s' not found in '%s.%s'",
 						rel.Model, modelName, relName)
@@ -55,7 +68,7 @@ func LinkModelRelations() error {
 				if rel.Polymorphic {
 					return fmt.Errorf("polymorphic relation '%s.%s' cannot use through", modelName, relName)
 				}
-				throughModel, ok := Registry[rel.Through]
+				throughModel, ok := reg[rel.Through]
 				if !ok {
 					return fmt.Errorf("invalid through: model '%s' not found in '%s.%s'",
 						rel.Through, modelName, relName)
@@ -77,8 +90,8 @@ func LinkModelRelations() error {
 			}
 
 			// Проверка типа связи
-			if rel.Type != "has_many" && rel.Type != "has_one" && rel.Type != "belongs_to" {
-				return fmt.Errorf("relation '%s.%s' has invalid type '%s' (must be has_many, has_one, belongs_to)",
+			if rel.Type != "has_many" && rel.Type != "has_one" && rel.Type != "belongs_to" && rel.Type != "tree" {
+				return fmt.Errorf("relation '%s.%s' has invalid type '%s' (must be has_many, has_one, belongs_to, tree)",
 					modelName, relName, rel.Type)
 			}
 			if rel.Type == "belongs_to" && rel.Polymorphic && rel.PK == "" {
@@ -170,6 +183,16 @@ func LinkModelRelations() error {
 			}
 		}
 	}
+
+	// 3. Пути search и условия политик проверяются, когда все связи уже слинкованы
+	for modelName, model := range reg {
+		if err := validateSearchPaths(model); err != nil {
+			return fmt.Errorf("invalid search in model '%s': %w", modelName, err)
+		}
+		if err := validatePolicies(model); err != nil {
+			return fmt.Errorf("invalid policies.filter in model '%s': %w", modelName, err)
+		}
+	}
 	return nil
 }
 
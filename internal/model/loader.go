@@ -10,6 +10,11 @@ import (
 )
 
 func LoadModelsFromDir(dir string) error {
+	return loadModelsInto(Registry, dir)
+}
+
+// loadModelsInto читает YAML-модели из dir в реестр reg.
+func loadModelsInto(reg map[string]*Model, dir string) error {
 	files, err := filepath.Glob(filepath.Join(dir, "*.yml"))
 	if err != nil {
 		return err
@@ -41,6 +46,21 @@ func LoadModelsFromDir(dir string) error {
 		if err := root.Decode(&model); err != nil {
 			return fmt.Errorf("unmarshal error in %s: %w", path, err)
 		}
+		if err := validateAccessRules(&model); err != nil {
+			return fmt.Errorf("validation error in %s: %w", path, err)
+		}
+		if err := validateRateLimit(&model); err != nil {
+			return fmt.Errorf("validation error in %s: %w", path, err)
+		}
+		if err := validateTimeouts(&model); err != nil {
+			return fmt.Errorf("validation error in %s: %w", path, err)
+		}
+		if err := validateTreeRelations(&model); err != nil {
+			return fmt.Errorf("validation error in %s: %w", path, err)
+		}
+		if err := validateSearch(&model); err != nil {
+			return fmt.Errorf("validation error in %s: %w", path, err)
+		}
 
 		if err := applyTemplateIncludes(dir, &model); err != nil {
 			return fmt.Errorf("include error in %s: %w", path, err)
@@ -50,11 +70,14 @@ func LoadModelsFromDir(dir string) error {
 		if err := resolvePresetInheritance(&model); err != nil {
 			return fmt.Errorf("inheritance error: %w", err)
 		}
+		if err := validateTailWindows(&model); err != nil {
+			return fmt.Errorf("validation error in %s: %w", path, err)
+		}
 
 		// 3. Регистрируем модель
 		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 		model.Name = name
-		Registry[name] = &model
+		reg[name] = &model
 	}
 	return nil
 }
@@ -145,6 +168,16 @@ func applyTemplateIncludes(baseDir string, m *Model) error {
 		if dst.TypeColumn == "" {
 			dst.TypeColumn = src.TypeColumn
 		}
+		if dst.Limit == 0 {
+			dst.Limit = src.Limit
+			dst.Offset = src.Offset
+		}
+		if dst.ParentFK == "" {
+			dst.ParentFK = src.ParentFK
+		}
+		if dst.Direction == "" {
+			dst.Direction = src.Direction
+		}
 	}
 
 	for _, inc := range m.Includes {
@@ -167,6 +200,9 @@ func applyTemplateIncludes(baseDir string, m *Model) error {
 		if err := node.Decode(&tpl); err != nil {
 			return fmt.Errorf("unmarshal template %s: %w", tplPath, err)
 		}
+		if err := validateAccessRules(&tpl); err != nil {
+			return fmt.Errorf("template validation %s: %w", tplPath, err)
+		}
 		if err := resolvePresetInheritance(&tpl); err != nil {
 			return fmt.Errorf("inheritance in template %s: %w", tplPath, err)
 		}
@@ -194,6 +230,13 @@ func applyTemplateIncludes(baseDir string, m *Model) error {
 			for name, tp := range tpl.Presets {
 				if existing, ok := m.Presets[name]; ok {
 					existing.Fields = mergeFields(tp.Fields, existing.Fields)
+					if existing.Access == nil {
+						existing.Access = tp.Access
+					}
+					if existing.TimeoutMS == 0 {
+						existing.TimeoutMS = tp.TimeoutMS
+					}
+					existing.Consistent = existing.Consistent || tp.Consistent
 					m.Presets[name] = existing
 				} else {
 					// copy to avoid sharing template struct
@@ -290,6 +333,7 @@ func resolvePresetInheritance(m *Model) error {
 
 		// 1) Наследуемся от каждого родителя слева направо
 		if parents := parseParents(strings.TrimSpace(p.Extends)); len(parents) > 0 {
+			parentPresets := make([]*DataPreset, 0, len(parents))
 			for _, parent := range parents {
 				parentFields, err := dfs(parent)
 				if err != nil {
@@ -297,6 +341,19 @@ func resolvePresetInheritance(m *Model) error {
 				}
 				// ВАЖНО: копируем parentFields, чтобы не трогать кэш
 				result = mergeFields(result, append([]Field(nil), parentFields...))
+				parentPresets = append(parentPresets, m.Presets[parent])
+			}
+			// без собственного access пресет не шире родителей
+			if p.Access == nil {
+				p.Access = inheritAccess(parentPresets)
+			}
+			if p.TimeoutMS == 0 {
+				p.TimeoutMS = inheritTimeout(parentPresets)
+			}
+			for _, parent := range parentPresets {
+				if parent != nil && parent.Consistent {
+					p.Consistent = true
+				}
 			}
 		}
 
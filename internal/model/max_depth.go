@@ -14,3 +14,10 @@ func resolveMaxDepth(fieldMax, relMax int) (int, bool) {
 	}
 	return defaultReentrantMaxDepth, true
 }
+
+// EffectiveMaxDepth returns how many times the target model of rel may appear
+// on one preset path when rel is reentrant (see resolveMaxDepth).
+func (f *Field) EffectiveMaxDepth(rel *ModelRelation) int {
+	eff, _ := resolveMaxDepth(f.MaxDepth, rel.MaxDepth)
+	return eff
+}
@@ -13,10 +13,10 @@ func readAllocBytes() uint64 {
 	return ms.HeapAlloc
 }
 
-func countPresetsAndComputable() (int, int) {
+func countPresetsAndComputable(reg map[string]*Model) (int, int) {
 	presets := 0
 	computable := 0
-	for _, m := range Registry {
+	for _, m := range reg {
 		presets += len(m.Presets)
 		computable += len(m.Computable)
 	}
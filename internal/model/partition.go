@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// partitionRowColumn — скрытая колонка с номером строки внутри родителя.
+// Идёт последней, поэтому ScanFlatRows её не читает.
+const partitionRowColumn = "__row_in_parent"
+
+// partitionWindow — offset/limit на каждую группу строк с одинаковым main.<By>.
+type partitionWindow struct {
+	By            string
+	Offset, Limit uint64
+}
+
+// BuildPartitionedIndexQuery строит SELECT дочернего has_many-запроса, в котором
+// offset и limit действуют на каждого родителя отдельно: строки нумеруются
+// ROW_NUMBER() OVER (PARTITION BY main.<by> ORDER BY <sorts>) и внешний
+// запрос оставляет номера (offset, offset+limit].
+func (m *Model) BuildPartitionedIndexQuery(
+	aliasMap *AliasMap,
+	filters map[string]interface{},
+	sorts []string,
+	preset *DataPreset,
+	by string,
+	offset, limit uint64,
+) (squirrel.SelectBuilder, error) {
+	if strings.TrimSpace(by) == "" || limit == 0 {
+		return squirrel.SelectBuilder{}, fmt.Errorf("partitioned query for '%s' needs a partition column and a limit", m.Table)
+	}
+	sb, _, err := m.buildIndexQuery(aliasMap, filters, sorts, preset, 0, 0, nil,
+		&partitionWindow{By: strings.TrimSpace(by), Offset: offset, Limit: limit})
+	return sb, err
+}
+
+// rowNumberExpr — колонка номера строки внутри родителя в порядке orderExprs.
+func (w *partitionWindow) rowNumberExpr(orderExprs []string) string {
+	over := "PARTITION BY main." + w.By
+	if len(orderExprs) > 0 {
+		over += " ORDER BY " + strings.Join(orderExprs, ", ")
+	}
+	return fmt.Sprintf("ROW_NUMBER() OVER (%s) AS %s", over, quoteIdentifier(partitionRowColumn))
+}
+
+// wrap оставляет из пронумерованного запроса окно строк каждого родителя.
+// Порядок внутри родителя сохраняется сортировкой по номеру строки.
+func (w *partitionWindow) wrap(inner squirrel.SelectBuilder) squirrel.SelectBuilder {
+	rn := "partitioned." + quoteIdentifier(partitionRowColumn)
+	return squirrel.Select("*").
+		PlaceholderFormat(squirrel.Dollar).
+		FromSelect(inner, "partitioned").
+		Where(rn+" > ?", w.Offset).
+		Where(rn+" <= ?", w.Offset+w.Limit).
+		OrderBy(rn)
+}
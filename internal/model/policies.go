@@ -0,0 +1,418 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// ModelPolicies — политики доступа к строкам модели.
+//
+//	policies:
+//	  filter:
+//	    org_id__eq: "{claims.org_id}"
+//
+// Filter записывается в формате фильтров /api/index; строки могут содержать
+// плейсхолдеры {claims.<path>}, которые подставляются из JWT текущего запроса.
+type ModelPolicies struct {
+	Filter map[string]any `yaml:"filter"`
+}
+
+// PolicyError — политику нельзя применить к запросу (нет claims или нужного
+// claim). Запрос должен быть отклонён, а не выполнен без фильтра.
+type PolicyError struct {
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+var claimPlaceholderRe = regexp.MustCompile(`\{claims\.([^{}]+)\}`)
+
+// HasPolicy сообщает, что у модели есть фильтр строк.
+func (m *Model) HasPolicy() bool {
+	return m != nil && m.Policies != nil && len(m.Policies.Filter) > 0
+}
+
+// ApplyPolicy объединяет filters с фильтром политики модели через "and".
+// prefix — путь связи, через которую модель присоединена к корню запроса
+// (напр. "contact."); ключи политики переписываются относительно корня.
+// Без политики filters возвращаются как есть.
+func (m *Model) ApplyPolicy(filters map[string]any, claims map[string]any, prefix string) (map[string]any, error) {
+	if !m.HasPolicy() {
+		return filters, nil
+	}
+	bound, err := bindClaims(m.Policies.Filter, claims, m.Name)
+	if err != nil {
+		return nil, err
+	}
+	policy := caseSensitivePolicy(NormalizeFiltersWithAliases(m, bound.(map[string]any)))
+	if prefix != "" {
+		policy = prefixFilterKeys(policy, prefix)
+	}
+	if len(filters) == 0 {
+		return policy, nil
+	}
+	return map[string]any{"and": []any{policy, filters}}, nil
+}
+
+// caseSensitivePolicy переписывает равенства политики (ключ без оператора
+// и __eq) в __eq_cs: строковый __eq сравнивает через LOWER, и тенант
+// "Acme" совпал бы с "acme".
+func caseSensitivePolicy(filters map[string]any) map[string]any {
+	out := make(map[string]any, len(filters))
+	for k, v := range filters {
+		if k == "or" || k == "and" {
+			switch sub := v.(type) {
+			case map[string]any:
+				out[k] = caseSensitivePolicy(sub)
+			case []any:
+				arr := make([]any, len(sub))
+				for i, item := range sub {
+					if m, ok := item.(map[string]any); ok {
+						arr[i] = caseSensitivePolicy(m)
+					} else {
+						arr[i] = item
+					}
+				}
+				out[k] = arr
+			default:
+				out[k] = v
+			}
+			continue
+		}
+		field, op, ok := strings.Cut(k, "__")
+		switch {
+		case !ok:
+			k = field + "__eq_cs"
+		case op == "eq":
+			k = field + "__eq_cs"
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// validatePolicies проверяет после линковки, что каждое условие политики
+// компилируется: известный оператор, существующие связи пути, кванторы по
+// has_one/has_many. Иначе buildWhereClause не смог бы построить условие, и
+// запрос выполнился бы без ограничения строк.
+func validatePolicies(m *Model) error {
+	if !m.HasPolicy() {
+		return nil
+	}
+	return validatePolicyFilters(m, m.Policies.Filter)
+}
+
+func validatePolicyFilters(m *Model, filters map[string]any) error {
+	for key, val := range filters {
+		if key == "or" || key == "and" {
+			switch sub := val.(type) {
+			case map[string]any:
+				if err := validatePolicyFilters(m, sub); err != nil {
+					return err
+				}
+			case []any:
+				for _, item := range sub {
+					group, ok := item.(map[string]any)
+					if !ok {
+						return fmt.Errorf("%q group items must be objects of filters", key)
+					}
+					if err := validatePolicyFilters(m, group); err != nil {
+						return err
+					}
+				}
+			default:
+				return fmt.Errorf("%q must be an object or an array of filters", key)
+			}
+			continue
+		}
+		field, op, ok := strings.Cut(key, "__")
+		if !ok {
+			op = "eq"
+		}
+		if err := validatePolicyCond(m, field, op, val); err != nil {
+			return fmt.Errorf("filter %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func validatePolicyCond(m *Model, field, op string, val any) error {
+	switch {
+	case isQuantifierOp(op):
+		rel, err := policyRelation(m, ExpandAliasPath(m, field))
+		if err != nil {
+			return err
+		}
+		if rel.Type != "has_many" && rel.Type != "has_one" {
+			return fmt.Errorf("quantifier needs a has_one or has_many relation")
+		}
+		if _, ok := countOps[op]; ok {
+			if _, isStr := val.(string); isStr {
+				return nil // плейсхолдер claims
+			}
+			if _, ok := countValue(val); !ok {
+				return fmt.Errorf("quantifier expects a non-negative whole number")
+			}
+			return nil
+		}
+		switch v := val.(type) {
+		case map[string]any:
+			return validatePolicyFilters(rel._ModelRef, v)
+		case nil:
+			return nil
+		}
+		return fmt.Errorf("quantifier expects an object of filters")
+	case op == "descendants_of" || op == "ancestors_of":
+		if rel := m.Relations[field]; rel == nil || rel.Type != "tree" {
+			return fmt.Errorf("%s needs a tree relation", op)
+		}
+		return nil
+	case op == searchOp && field == "":
+		if m.Search == nil {
+			return fmt.Errorf("model has no search config")
+		}
+		return nil
+	}
+	switch strings.TrimSuffix(op, "_cs") {
+	case "eq", "in", "lt", "lte", "gt", "gte", "start", "end", "cnt", "not_cnt",
+		"null", "is_null", "not_null", searchOp:
+	default:
+		return fmt.Errorf("unknown operator %q", op)
+	}
+	fields, _ := ParseCompositeField(field)
+	for _, f := range fields {
+		f = ExpandAliasPath(m, f)
+		column := f
+		if idx := strings.LastIndex(f, "."); idx != -1 {
+			if _, err := policyRelation(m, f[:idx]); err != nil {
+				return err
+			}
+			column = f[idx+1:]
+		}
+		if !identRe.MatchString(column) {
+			return fmt.Errorf("invalid field %q", f)
+		}
+	}
+	return nil
+}
+
+// policyRelation возвращает последнюю связь пути path модели m.
+func policyRelation(m *Model, path string) (*ModelRelation, error) {
+	var rel *ModelRelation
+	curr := m
+	for _, seg := range strings.Split(path, ".") {
+		if curr == nil {
+			return nil, fmt.Errorf("relation path %q not found", path)
+		}
+		rel = curr.Relations[seg]
+		if rel == nil || rel.Polymorphic || rel._ModelRef == nil {
+			return nil, fmt.Errorf("relation path %q not found", path)
+		}
+		curr = rel._ModelRef
+	}
+	return rel, nil
+}
+
+// policyKeyQuery возвращает подзапрос ключей keys строк модели, видимых по
+// её политике с claims: "SELECT main.<key> FROM <table> AS main ... WHERE
+// <политика>". Без политики — пустая строка. JOIN-ы внутри подзапроса
+// политики других моделей не получают.
+func (m *Model) policyKeyQuery(keys []string, claims map[string]any) (string, []any, error) {
+	policy, err := m.ApplyPolicy(nil, claims, "")
+	if err != nil || policy == nil {
+		return "", nil, err
+	}
+	aliasMap, err := BuildAliasMap(m, nil, policy, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	aliasMap.claims = claims
+	joins, err := m.DetectJoins(aliasMap, PathsFromFilters(policy), nil, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	where, _, err := m.buildWhereClause(aliasMap, nil, policy, joins, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	cols := make([]string, len(keys))
+	for i, k := range keys {
+		cols[i] = "main." + k
+	}
+	sb := squirrel.Select(cols...).From(fmt.Sprintf("%s AS main", m.Table))
+	for _, join := range joins {
+		onClause := join.On
+		if join.Where != "" {
+			onClause = fmt.Sprintf("(%s) AND (%s)", join.On, join.Where)
+		}
+		sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause), join.WhereArgs...)
+	}
+	if where != nil {
+		sb = sb.Where(where)
+	}
+	return sb.ToSql()
+}
+
+// applyJoinPolicy ограничивает JOIN модели target строками, видимыми по её
+// политике: "(<alias>.<pk>) IN (<policyKeyQuery>)" добавляется к Where JOIN-а,
+// так что скрытые строки присоединяются как NULL и не проходят фильтры.
+func applyJoinPolicy(join *JoinSpec, target *Model, aliasMap *AliasMap) error {
+	if aliasMap == nil || !aliasMap.scoped || !target.HasPolicy() {
+		return nil
+	}
+	keys := target.GetPrimaryKeys()
+	sql, args, err := target.policyKeyQuery(keys, aliasMap.claims)
+	if err != nil || sql == "" {
+		return err
+	}
+	cols := make([]string, len(keys))
+	for i, k := range keys {
+		cols[i] = join.Alias + "." + k
+	}
+	cond := fmt.Sprintf("(%s) IN (%s)", strings.Join(cols, ", "), sql)
+	if join.Where != "" {
+		cond = fmt.Sprintf("(%s) AND %s", join.Where, cond)
+	}
+	join.Where = cond
+	join.WhereArgs = append(join.WhereArgs, args...)
+	return nil
+}
+
+// bindClaims копирует значение фильтра с подставленными claims.
+// Строка, целиком состоящая из плейсхолдера, заменяется значением claim
+// как есть (число, массив для __in); внутри строки — его текстом.
+func bindClaims(v any, claims map[string]any, modelName string) (any, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			bound, err := bindClaims(item, claims, modelName)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = bound
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			bound, err := bindClaims(item, claims, modelName)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = bound
+		}
+		return out, nil
+	case string:
+		matches := claimPlaceholderRe.FindAllStringSubmatchIndex(val, -1)
+		if len(matches) == 0 {
+			return val, nil
+		}
+		if claims == nil {
+			return nil, &PolicyError{Message: fmt.Sprintf("access policy of model %s requires an authenticated request", modelName)}
+		}
+		resolve := func(path string) (any, error) {
+			claim, ok := lookupClaim(claims, path)
+			if !ok || claim == nil {
+				return nil, &PolicyError{Message: fmt.Sprintf("access policy of model %s requires claim %q", modelName, path)}
+			}
+			return normalizeClaim(claim), nil
+		}
+		if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(val) {
+			return resolve(val[matches[0][2]:matches[0][3]])
+		}
+		var b strings.Builder
+		last := 0
+		for _, mm := range matches {
+			claim, err := resolve(val[mm[2]:mm[3]])
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(val[last:mm[0]])
+			fmt.Fprint(&b, claim)
+			last = mm[1]
+		}
+		b.WriteString(val[last:])
+		return b.String(), nil
+	default:
+		return v, nil
+	}
+}
+
+// lookupClaim ищет claim сначала по полному имени (namespaced claims вида
+// "https://example.com/org"), затем по пути через точку.
+func lookupClaim(claims map[string]any, path string) (any, bool) {
+	if v, ok := claims[path]; ok {
+		return v, true
+	}
+	var cur any = claims
+	for _, seg := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[seg]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// normalizeClaim переводит целые JSON-числа в int64, чтобы они сравнивались
+// с целочисленными колонками без приведения типов.
+func normalizeClaim(v any) any {
+	switch val := v.(type) {
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return int64(val)
+		}
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = normalizeClaim(item)
+		}
+		return out
+	}
+	return v
+}
+
+func prefixFilterKeys(filters map[string]any, prefix string) map[string]any {
+	out := make(map[string]any, len(filters))
+	for k, v := range filters {
+		if k == "or" || k == "and" {
+			switch sub := v.(type) {
+			case map[string]any:
+				out[k] = prefixFilterKeys(sub, prefix)
+			case []any:
+				arr := make([]any, len(sub))
+				for i, item := range sub {
+					if m, ok := item.(map[string]any); ok {
+						arr[i] = prefixFilterKeys(m, prefix)
+					} else {
+						arr[i] = item
+					}
+				}
+				out[k] = arr
+			default:
+				out[k] = v
+			}
+			continue
+		}
+		field, op := k, ""
+		if i := strings.Index(k, "__"); i >= 0 {
+			field, op = k[:i], k[i:]
+		}
+		fields, comb := ParseCompositeField(field)
+		for i := range fields {
+			fields[i] = prefix + fields[i]
+		}
+		out[strings.Join(fields, comb)+op] = v
+	}
+	return out
+}
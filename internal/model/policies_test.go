@@ -0,0 +1,288 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadModelsFromDir_Policies(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Project.yml", `
+table: projects
+policies:
+  filter:
+    org_id__eq: "{claims.org_id}"
+    or:
+      - public: true
+      - owner_id__in: "{claims.teams}"
+presets:
+  item:
+    fields:
+      - source: id
+        type: int
+`)
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err != nil {
+		t.Fatalf("LoadModelsFromDir: %v", err)
+	}
+	m := getModel(t, "Project")
+	if !m.HasPolicy() || m.Policies.Filter["org_id__eq"] != "{claims.org_id}" {
+		t.Fatalf("unexpected policies: %#v", m.Policies)
+	}
+
+	write(t, dir, "Project.yml", "table: projects\npolicies:\n  where: org_id = 1\n")
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "unknown key 'where' in policies") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestApplyPolicy_BindsClaims(t *testing.T) {
+	m := &Model{Name: "Project", Policies: &ModelPolicies{Filter: map[string]any{
+		"org_id__eq":   "{claims.org_id}",
+		"region__eq":   "eu-{claims.tenant.region}",
+		"owner_id__in": "{claims.teams}",
+	}}}
+	claims := map[string]any{
+		"org_id": float64(42),
+		"tenant": map[string]any{"region": "west"},
+		"teams":  []any{float64(1), float64(2)},
+	}
+
+	got, err := m.ApplyPolicy(map[string]any{"name__cnt": "x"}, claims, "")
+	if err != nil {
+		t.Fatalf("ApplyPolicy: %v", err)
+	}
+	want := map[string]any{"and": []any{
+		map[string]any{
+			"org_id__eq_cs": int64(42),
+			"region__eq_cs": "eu-west",
+			"owner_id__in":  []any{int64(1), int64(2)},
+		},
+		map[string]any{"name__cnt": "x"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected filters:\n got %#v\nwant %#v", got, want)
+	}
+
+	// без фильтров запроса — только политика
+	only, err := m.ApplyPolicy(nil, claims, "")
+	if err != nil || only["org_id__eq_cs"] != int64(42) {
+		t.Fatalf("unexpected policy-only filters: %#v, %v", only, err)
+	}
+}
+
+func TestApplyPolicy_DeniesWithoutClaims(t *testing.T) {
+	m := &Model{Name: "Project", Policies: &ModelPolicies{Filter: map[string]any{"org_id": "{claims.org_id}"}}}
+	var policyErr *PolicyError
+
+	if _, err := m.ApplyPolicy(nil, nil, ""); !errors.As(err, &policyErr) {
+		t.Fatalf("expected PolicyError without claims, got %v", err)
+	}
+	if _, err := m.ApplyPolicy(nil, map[string]any{"sub": "u1"}, ""); !errors.As(err, &policyErr) || !strings.Contains(err.Error(), `"org_id"`) {
+		t.Fatalf("expected PolicyError for missing claim, got %v", err)
+	}
+
+	// модель без политики не трогает фильтры
+	plain := &Model{Name: "Country"}
+	filters := map[string]any{"id": 1}
+	if got, err := plain.ApplyPolicy(filters, nil, ""); err != nil || !reflect.DeepEqual(got, filters) {
+		t.Fatalf("unexpected result: %#v, %v", got, err)
+	}
+}
+
+func TestApplyPolicy_PrefixAndWhereClause(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	org := &Model{
+		Name:  "Organization",
+		Table: "organizations",
+		Presets: map[string]*DataPreset{
+			"item": {Fields: []Field{{Source: "id", Type: "int"}, {Source: "tenant_id", Type: "int"}}},
+		},
+		Policies: &ModelPolicies{Filter: map[string]any{"tenant_id": "{claims.tenant}"}},
+	}
+	emp := &Model{
+		Name:  "Employee",
+		Table: "employees",
+		Relations: map[string]*ModelRelation{
+			"org": {Type: "belongs_to", Model: "Organization"},
+		},
+		Presets: map[string]*DataPreset{
+			"item": {Fields: []Field{{Source: "id", Type: "int"}, {Source: "tenant_id", Type: "int"}}},
+		},
+		Policies: &ModelPolicies{Filter: map[string]any{"tenant_id": "{claims.tenant}"}},
+	}
+	Registry = map[string]*Model{"Organization": org, "Employee": emp}
+	if err := LinkModelRelations(); err != nil {
+		t.Fatalf("LinkModelRelations: %v", err)
+	}
+	if err := BuildPresetAliasMaps(); err != nil {
+		t.Fatalf("BuildPresetAliasMaps: %v", err)
+	}
+
+	claims := map[string]any{"tenant": float64(7)}
+	filters, err := emp.ApplyPolicy(nil, claims, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filters, err = org.ApplyPolicy(filters, claims, "org."); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"org.tenant_id__eq_cs": int64(7)}; !reflect.DeepEqual(filters["and"].([]any)[0], want) {
+		t.Fatalf("prefixed policy mismatch: %#v", filters)
+	}
+
+	preset := emp.Presets["item"]
+	am, err := emp.CreateAliasMap(emp, preset, filters, nil)
+	if err != nil {
+		t.Fatalf("CreateAliasMap: %v", err)
+	}
+	sb, err := emp.BuildIndexQuery(am, filters, nil, preset, 0, 10)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, "main.tenant_id = $") || !strings.Contains(sql, ".tenant_id = $") || strings.Count(sql, "tenant_id = $") != 2 {
+		t.Fatalf("policy conditions missing from WHERE: %s", sql)
+	}
+	if len(args) < 2 || args[0] != int64(7) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+// Политика модели, присоединённой JOIN-ом (фильтр или поле belongs_to),
+// применяется к её строкам: скрытая организация присоединяется как NULL.
+func TestApplyPolicy_JoinedTarget(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	org := &Model{
+		Name:     "Organization",
+		Table:    "organizations",
+		Policies: &ModelPolicies{Filter: map[string]any{"tenant_id": "{claims.tenant}"}},
+	}
+	emp := &Model{
+		Name:  "Employee",
+		Table: "employees",
+		Relations: map[string]*ModelRelation{
+			"org": {Type: "belongs_to", Model: "Organization"},
+		},
+		Presets: map[string]*DataPreset{
+			"item": {Fields: []Field{{Source: "id", Type: "int"}}},
+		},
+	}
+	Registry = map[string]*Model{"Organization": org, "Employee": emp}
+	if err := LinkModelRelations(); err != nil {
+		t.Fatalf("LinkModelRelations: %v", err)
+	}
+	if err := BuildPresetAliasMaps(); err != nil {
+		t.Fatalf("BuildPresetAliasMaps: %v", err)
+	}
+
+	preset := emp.Presets["item"]
+	filters := map[string]any{"org.name__eq": "Acme"}
+	am, err := emp.CreateAliasMap(emp, preset, filters, nil)
+	if err != nil {
+		t.Fatalf("CreateAliasMap: %v", err)
+	}
+	sb, err := emp.BuildIndexQuery(am.WithClaims(map[string]any{"tenant": float64(7)}), filters, nil, preset, 0, 10)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias := am.PathToAlias["org"]
+	want := "LEFT JOIN organizations AS " + alias + " ON (main.org_id = " + alias + ".id) AND ((" + alias + ".id) IN (SELECT main.id FROM organizations AS main WHERE (main.tenant_id = $1)))"
+	if !strings.Contains(sql, want) || !reflect.DeepEqual(args[:2], []any{int64(7), "Acme"}) {
+		t.Fatalf("expected policy on the joined organization %q: %s %v", want, sql, args)
+	}
+
+	// без claims политика не применяется молча — запрос отклоняется
+	_, err = emp.BuildIndexQuery(am.WithClaims(nil), filters, nil, preset, 0, 10)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected PolicyError without claims, got %v", err)
+	}
+}
+
+// Условие политики, которое не компилируется, отклоняет загрузку реестра:
+// иначе buildWhereClause отбросил бы его, и запрос шёл бы без тенанта.
+func TestLinkModelRelations_ValidatesPolicies(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	cases := map[string]map[string]any{
+		`unknown operator "equals"`:   {"org_id__equals": "{claims.org_id}"},
+		`relation path "owner" not`:   {"owner.org_id": "{claims.org_id}"},
+		`invalid field "org id"`:      {"org id": 1},
+		"needs a has_one or has_many": {"org__any": map[string]any{"id": 1}},
+		"needs a tree relation":       {"org__descendants_of": 1},
+		`"or" must be an object`:      {"or": "x"},
+		`unknown operator "eqq"`:      {"members__any": map[string]any{"user_id__eqq": "{claims.sub}"}},
+	}
+	for want, filter := range cases {
+		Registry = map[string]*Model{
+			"Organization": {Name: "Organization", Table: "organizations"},
+			"Member":       {Name: "Member", Table: "members"},
+			"Project": {
+				Name:  "Project",
+				Table: "projects",
+				Relations: map[string]*ModelRelation{
+					"org":     {Type: "belongs_to", Model: "Organization"},
+					"members": {Type: "has_many", Model: "Member"},
+				},
+				Policies: &ModelPolicies{Filter: filter},
+			},
+		}
+		if err := LinkModelRelations(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%v: expected %q, got %v", filter, want, err)
+		}
+	}
+}
+
+// Равенство политики сравнивается с учётом регистра, а условие, которое не
+// строится из значения claim, отклоняет запрос, а не пропадает из WHERE.
+func TestApplyPolicy_FailsClosed(t *testing.T) {
+	m := &Model{Name: "Project", Table: "projects", Policies: &ModelPolicies{Filter: map[string]any{
+		"org_id__eq":   "{claims.org}",
+		"name__start":  "{claims.prefix}",
+		"owner_id__in": "{claims.teams}",
+	}}}
+	build := func(claims map[string]any) (string, error) {
+		filters, err := m.ApplyPolicy(map[string]any{"name__cnt": "x"}, claims, "")
+		if err != nil {
+			return "", err
+		}
+		where, _, err := m.buildWhereClause(&AliasMap{PathToAlias: map[string]string{}}, nil, filters, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		sql, _, err := where.ToSql()
+		return sql, err
+	}
+
+	sql, err := build(map[string]any{"org": "Acme", "prefix": "p", "teams": []any{float64(1)}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if !strings.Contains(sql, "main.org_id = ?") || strings.Contains(sql, "LOWER(") {
+		t.Fatalf("policy equality must be case-sensitive: %s", sql)
+	}
+
+	var filterErr *FilterError
+	if _, err := build(map[string]any{"org": "Acme", "prefix": float64(5), "teams": []any{float64(1)}}); !errors.As(err, &filterErr) {
+		t.Fatalf("expected FilterError for a policy condition that cannot be built, got %v", err)
+	}
+}
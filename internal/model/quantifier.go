@@ -0,0 +1,214 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Кванторы над has_one/has_many связью: "<связь>__any": {...},
+// "<связь>__none": {...}, "<связь>__all": {...} и "<связь>__count_gte": 3.
+// Условие компилируется в коррелированный подзапрос (EXISTS / NOT EXISTS /
+// COUNT) по дочерней таблице, поэтому главный запрос не получает JOIN,
+// DISTINCT и группировку.
+var countOps = map[string]string{
+	"count_eq":  "=",
+	"count_gt":  ">",
+	"count_gte": ">=",
+	"count_lt":  "<",
+	"count_lte": "<=",
+}
+
+func isQuantifierOp(op string) bool {
+	switch op {
+	case "any", "none", "all":
+		return true
+	}
+	_, ok := countOps[op]
+	return ok
+}
+
+// quantifierCond строит условие квантора op по связи path для значения val:
+// вложенных фильтров дочерней модели (any/none/all) или числа (count_*).
+// path может идти через belongs_to/has_one/has_many ("org.contacts"): тогда
+// подзапрос коррелируется с алиасом родителя из aliasMap, а не с main.
+func (m *Model) quantifierCond(aliasMap *AliasMap, path, op string, val any) (squirrel.Sqlizer, error) {
+	parent, parentAlias := m, "main"
+	relName := path
+	if idx := strings.LastIndex(path, "."); idx != -1 {
+		prefix := path[:idx]
+		relName = path[idx+1:]
+		alias, ok := aliasMap.pathAlias(prefix)
+		if !ok {
+			return nil, fmt.Errorf("relation path %q not found", prefix)
+		}
+		parentAlias = alias
+		for _, seg := range strings.Split(prefix, ".") {
+			rel := parent.Relations[seg]
+			if rel == nil || rel._ModelRef == nil {
+				return nil, fmt.Errorf("relation path %q not found", prefix)
+			}
+			parent = rel._ModelRef
+		}
+	}
+	rel := parent.Relations[relName]
+	if rel == nil || (rel.Type != "has_many" && rel.Type != "has_one") || rel._ModelRef == nil {
+		return nil, fmt.Errorf("quantifier %q needs a has_one or has_many relation", op)
+	}
+
+	var filters map[string]any
+	var count int64
+	if _, ok := countOps[op]; ok {
+		n, ok := countValue(val)
+		if !ok {
+			return nil, fmt.Errorf("quantifier %q expects a non-negative whole number", op)
+		}
+		count = n
+	} else {
+		switch v := val.(type) {
+		case map[string]any:
+			filters = v
+		case nil:
+		default:
+			return nil, fmt.Errorf("quantifier %q expects an object of filters", op)
+		}
+	}
+
+	inner, args, err := rel.childKeyQuery(filters, op == "all", aliasMap.Claims())
+	if err != nil {
+		return nil, err
+	}
+	correlated := fmt.Sprintf("FROM (%s) AS quantified WHERE quantified.parent_key = %s.%s", inner, parentAlias, rel.PK)
+	switch op {
+	case "any":
+		return squirrel.Expr("EXISTS (SELECT 1 "+correlated+")", args...), nil
+	case "none", "all":
+		return squirrel.Expr("NOT EXISTS (SELECT 1 "+correlated+")", args...), nil
+	}
+	return squirrel.Expr(fmt.Sprintf("(SELECT COUNT(*) %s) %s ?", correlated, countOps[op]), append(args, count)...), nil
+}
+
+// childKeyQuery возвращает SELECT ключа родителя (parent_key) по дочерним
+// строкам связи, подходящим под filters; negate выбирает строки, для которых
+// условие не выполнено (квантор all). Дочерняя таблица получает алиас main,
+// как в обычном запросе, поэтому фильтры и вложенные кванторы строятся тем же
+// buildWhereClause; наружу из производной таблицы виден только parent_key.
+// Политика дочерней модели (с claims запроса) добавляется отдельным условием:
+// квантор all не должен её отрицать, а count_* — считать скрытые строки.
+func (rel *ModelRelation) childKeyQuery(filters map[string]any, negate bool, claims map[string]any) (string, []any, error) {
+	child := rel._ModelRef
+	var conds []squirrel.Sqlizer
+	finalKey := "" // путь к конечной модели для through
+	policy, err := child.ApplyPolicy(nil, claims, "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	if rel.Through != "" {
+		// строки промежуточной модели; фильтры относятся к конечной — через belongs_to
+		through := rel._ThroughRef
+		if through == nil {
+			return "", nil, fmt.Errorf("through model %q is not linked", rel.Through)
+		}
+		for key, r := range through.Relations {
+			if r != nil && r.Type == "belongs_to" && r._ModelRef == child {
+				finalKey = key
+				break
+			}
+		}
+		if finalKey == "" {
+			return "", nil, fmt.Errorf("no belongs_to from %s to %s found", through.Table, child.Table)
+		}
+		filters = prefixFilterKeys(filters, finalKey+".")
+		if policy != nil {
+			policy = prefixFilterKeys(policy, finalKey+".")
+		}
+		child = through
+		if rel.ThroughWhere != "" {
+			conds = append(conds, squirrel.Expr(replaceTableWithAlias(rel.ThroughWhere, "main")))
+		}
+	} else if rel.Where != "" {
+		conds = append(conds, squirrel.Expr(replaceTableWithAlias(rel.Where, "main")))
+	}
+
+	filters = NormalizeFiltersWithAliases(child, filters)
+	aliasMap, err := BuildAliasMap(child, nil, map[string]any{"and": []any{filters, policy}}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	aliasMap.claims, aliasMap.scoped = claims, true
+	paths := mergeAndSortPaths(PathsFromFilters(filters), PathsFromFilters(policy))
+	if finalKey != "" && rel.Where != "" {
+		// where связи проверяется на конечной модели — её JOIN нужен всегда
+		nextIdx := detectNextAliasIndex(aliasMap)
+		if err := ensureAliasPath(child, aliasMap, finalKey, &nextIdx); err != nil {
+			return "", nil, err
+		}
+		paths = append(paths, finalKey)
+		conds = append(conds, squirrel.Expr(replaceTableWithAlias(rel.Where, aliasMap.PathToAlias[finalKey])))
+	}
+	joins, err := child.DetectJoins(aliasMap, paths, nil, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if policy != nil {
+		policyWhere, _, err := child.buildWhereClause(aliasMap, nil, policy, joins, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		if policyWhere != nil {
+			conds = append(conds, policyWhere)
+		}
+	}
+	where, having, err := child.buildWhereClause(aliasMap, nil, filters, joins, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if having != nil {
+		return "", nil, fmt.Errorf("aggregate conditions are not supported inside quantifiers")
+	}
+	if negate {
+		if where == nil {
+			// all по пустому условию выполняется всегда
+			where = squirrel.Expr("TRUE")
+		}
+		sql, args, err := where.ToSql()
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, squirrel.Expr(fmt.Sprintf("(%s) IS NOT TRUE", sql), args...))
+	} else if where != nil {
+		conds = append(conds, where)
+	}
+
+	sb := squirrel.Select(fmt.Sprintf("main.%s AS parent_key", rel.FK)).From(fmt.Sprintf("%s AS main", child.Table))
+	for _, join := range joins {
+		onClause := join.On
+		if join.Where != "" {
+			onClause = fmt.Sprintf("(%s) AND (%s)", join.On, join.Where)
+		}
+		sb = sb.LeftJoin(fmt.Sprintf("%s AS %s ON %s", join.Table, join.Alias, onClause), join.WhereArgs...)
+	}
+	if len(conds) > 0 {
+		sb = sb.Where(squirrel.And(conds))
+	}
+	return sb.ToSql()
+}
+
+func countValue(val any) (int64, bool) {
+	switch v := val.(type) {
+	case int:
+		return int64(v), v >= 0
+	case int64:
+		return v, v >= 0
+	case float64:
+		return int64(v), v >= 0 && v == float64(int64(v))
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil && n >= 0
+	}
+	return 0, false
+}
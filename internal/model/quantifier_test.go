@@ -0,0 +1,212 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func quantifierSQL(t *testing.T, filters map[string]any) (string, []any) {
+	t.Helper()
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	return quantifierQuery(t, reg["Person"], filters, nil)
+}
+
+func quantifierQuery(t *testing.T, m *Model, filters, claims map[string]any) (string, []any) {
+	t.Helper()
+	preset := m.Presets["item"]
+	aliasMap, err := m.CreateAliasMap(m, preset, filters, nil)
+	if err != nil {
+		t.Fatalf("CreateAliasMap: %v", err)
+	}
+	sb, err := m.BuildIndexQuery(aliasMap.WithClaims(claims), filters, nil, preset, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	return sql, args
+}
+
+func TestQuantifierAnyCompilesToExists(t *testing.T) {
+	sql, args := quantifierSQL(t, map[string]any{
+		"employees__any": map[string]any{"position__cnt": "Engineer"},
+	})
+	want := "EXISTS (SELECT 1 FROM (SELECT main.person_id AS parent_key FROM employees AS main WHERE ((main.position ILIKE $1))) AS quantified WHERE quantified.parent_key = main.id)"
+	if !strings.Contains(sql, want) {
+		t.Fatalf("expected %q in SQL: %s", want, sql)
+	}
+	if strings.Contains(sql, "JOIN employees") || strings.Contains(sql, "DISTINCT") {
+		t.Fatalf("quantifier must not join the child into the main query: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []any{"%Engineer%"}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestQuantifierNoneAndAll(t *testing.T) {
+	sql, _ := quantifierSQL(t, map[string]any{"employees__none": map[string]any{}})
+	if !strings.Contains(sql, "NOT EXISTS (SELECT 1 FROM (SELECT main.person_id AS parent_key FROM employees AS main) AS quantified") {
+		t.Fatalf("unexpected none SQL: %s", sql)
+	}
+
+	sql, args := quantifierSQL(t, map[string]any{"employees__all": map[string]any{"organization_id__eq": 1}})
+	if !strings.Contains(sql, "NOT EXISTS (SELECT 1 FROM (SELECT main.person_id AS parent_key FROM employees AS main WHERE (((main.organization_id = $1)) IS NOT TRUE))") {
+		t.Fatalf("unexpected all SQL: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []any{1}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestQuantifierCount(t *testing.T) {
+	sql, args := quantifierSQL(t, map[string]any{"employees__count_gte": float64(2)})
+	if !strings.Contains(sql, "(SELECT COUNT(*) FROM (SELECT main.person_id AS parent_key FROM employees AS main) AS quantified WHERE quantified.parent_key = main.id) >= $1") {
+		t.Fatalf("unexpected count SQL: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []any{int64(2)}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+// Неверный квантор отклоняется FilterError, а не выполняется без условия.
+func TestQuantifierInvalidIsFilterError(t *testing.T) {
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	m := reg["Person"]
+	preset := m.Presets["item"]
+	for _, filters := range []map[string]any{
+		{"employees__count_gte": -1.5},
+		{"employees__any": "Engineer"},
+		{"first_name__any": map[string]any{}},
+		{"or": []any{map[string]any{"id__eq": 1}, map[string]any{"employees__all": map[string]any{"missing.name__eq": "x"}}}},
+	} {
+		aliasMap, err := m.CreateAliasMap(m, preset, filters, nil)
+		if err != nil {
+			t.Fatalf("CreateAliasMap: %v", err)
+		}
+		_, err = m.BuildIndexQuery(aliasMap, filters, nil, preset, 0, 0)
+		var filterErr *FilterError
+		if !errors.As(err, &filterErr) {
+			t.Fatalf("%v: expected FilterError, got %v", filters, err)
+		}
+	}
+}
+
+// Квантор по пути через belongs_to коррелируется с алиасом родителя.
+func TestQuantifierDottedPathUsesParentAlias(t *testing.T) {
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	m := reg["Employee"]
+	filters := map[string]any{"person.employees__count_gte": 2}
+	aliasMap, err := m.CreateAliasMap(m, nil, filters, nil)
+	if err != nil {
+		t.Fatalf("CreateAliasMap: %v", err)
+	}
+	sb, err := m.BuildIndexQuery(aliasMap, filters, nil, &DataPreset{Name: "q", Fields: []Field{{Source: "id", Type: "int"}}}, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, _, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	alias := aliasMap.PathToAlias["person"]
+	want := "AS quantified WHERE quantified.parent_key = " + alias + ".id"
+	if alias == "" || !strings.Contains(sql, want) || !strings.Contains(sql, "LEFT JOIN people AS "+alias) {
+		t.Fatalf("expected correlation with %q and its join: %s", want, sql)
+	}
+}
+
+// Политика дочерней модели попадает во внутренний запрос квантора отдельным
+// условием: all её не отрицает, count_* не считает скрытые строки.
+func TestQuantifierAppliesChildPolicy(t *testing.T) {
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	employee := *reg["Employee"]
+	employee.Policies = &ModelPolicies{Filter: map[string]any{"organization_id__eq": "{claims.org_id}"}}
+	person := *reg["Person"]
+	person.Relations = map[string]*ModelRelation{}
+	for name, rel := range reg["Person"].Relations {
+		person.Relations[name] = rel
+	}
+	rel := *reg["Person"].Relations["employees"]
+	rel._ModelRef = &employee
+	person.Relations["employees"] = &rel
+	claims := map[string]any{"org_id": float64(7)}
+
+	sql, args := quantifierQuery(t, &person, map[string]any{"employees__all": map[string]any{"position__eq": "CTO"}}, claims)
+	want := "FROM employees AS main WHERE ((main.organization_id = $1) AND ((LOWER(main.position) = LOWER($2))) IS NOT TRUE)"
+	if !strings.Contains(sql, want) || !reflect.DeepEqual(args, []any{int64(7), "CTO"}) {
+		t.Fatalf("expected policy outside the negated filters %q: %s %v", want, sql, args)
+	}
+	sql, args = quantifierQuery(t, &person, map[string]any{"employees__count_gte": 1}, claims)
+	if !strings.Contains(sql, "FROM employees AS main WHERE ((main.organization_id = $1))") || !reflect.DeepEqual(args, []any{int64(7), int64(1)}) {
+		t.Fatalf("count must see only rows allowed by the policy: %s %v", sql, args)
+	}
+
+	aliasMap, err := person.CreateAliasMap(&person, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateAliasMap: %v", err)
+	}
+	_, err = person.BuildIndexQuery(aliasMap, map[string]any{"employees__any": map[string]any{}}, nil, person.Presets["item"], 0, 0)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("policy without claims must be a PolicyError, got %v", err)
+	}
+}
+
+func TestQuantifierThroughAppliesRelationWhere(t *testing.T) {
+	sql, args := quantifierSQL(t, map[string]any{
+		"email__any": map[string]any{"value__end": "@example.com"},
+	})
+	for _, want := range []string{
+		"SELECT main.person_id AS parent_key FROM person_contacts AS main LEFT JOIN contacts AS t0 ON main.contact_id = t0.id",
+		"t0.kind = 'email'",
+		"t0.value ILIKE $1",
+		"quantified.parent_key = main.id",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL: %s", want, sql)
+		}
+	}
+	if !reflect.DeepEqual(args, []any{"%@example.com"}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestQuantifierNested(t *testing.T) {
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	m := reg["Organization"]
+	if rel := m.Relations["departments"]; rel == nil || rel.Type != "has_many" {
+		t.Fatalf("Organization.departments must be has_many, got %#v", rel)
+	}
+	cond, err := m.quantifierCond(nil, "departments", "any", map[string]any{
+		"employees__count_gte": 1,
+	})
+	if err != nil {
+		t.Fatalf("quantifierCond: %v", err)
+	}
+	sql, args, err := cond.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	if strings.Count(sql, "AS quantified WHERE quantified.parent_key = main.id") != 2 || !reflect.DeepEqual(args, []any{int64(1)}) {
+		t.Fatalf("expected nested correlated subqueries, got %s %v", sql, args)
+	}
+}
@@ -0,0 +1,48 @@
+package model
+
+import "fmt"
+
+// RateLimit — лимиты запросов к модели на одного субъекта (sub из JWT или
+// API-ключа, иначе IP клиента). Действуют вместе с общими лимитами из env.
+//
+//	rate_limit:
+//	  rps: 5          # запросов в секунду
+//	  burst: 10       # размер всплеска, по умолчанию max(1, rps)
+//	  concurrency: 2  # одновременных запросов
+//
+// Нулевое значение снимает соответствующее ограничение.
+type RateLimit struct {
+	RPS         float64 `yaml:"rps"`
+	Burst       int     `yaml:"burst"`
+	Concurrency int     `yaml:"concurrency"`
+}
+
+// Enabled сообщает, что задано хотя бы одно ограничение.
+func (l *RateLimit) Enabled() bool {
+	return l != nil && (l.RPS > 0 || l.Concurrency > 0)
+}
+
+// HasRateLimits сообщает, что хотя бы у одной модели реестра задан
+// rate_limit: иначе модель запроса для лимитов определять не нужно.
+func HasRateLimits(reg map[string]*Model) bool {
+	for _, m := range reg {
+		if m != nil && m.RateLimit.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRateLimit(m *Model) error {
+	l := m.RateLimit
+	if l == nil {
+		return nil
+	}
+	if l.RPS < 0 || l.Burst < 0 || l.Concurrency < 0 {
+		return fmt.Errorf("rate_limit values must not be negative")
+	}
+	if l.Burst > 0 && l.RPS == 0 {
+		return fmt.Errorf("rate_limit.burst requires rps")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadModelsFromDir_RateLimit(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Report.yml", "table: reports\nrate_limit:\n  rps: 2.5\n  burst: 5\n  concurrency: 1\n")
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err != nil {
+		t.Fatalf("LoadModelsFromDir: %v", err)
+	}
+	got := getModel(t, "Report").RateLimit
+	if got == nil || got.RPS != 2.5 || got.Burst != 5 || got.Concurrency != 1 || !got.Enabled() {
+		t.Fatalf("unexpected rate_limit: %#v", got)
+	}
+
+	cases := map[string]string{
+		"table: reports\nrate_limit:\n  per_minute: 10\n": "unknown key 'per_minute' in rate_limit",
+		"table: reports\nrate_limit:\n  burst: 10\n":      "rate_limit.burst requires rps",
+		"table: reports\nrate_limit:\n  rps: -1\n":        "must not be negative",
+	}
+	for body, want := range cases {
+		write(t, dir, "Report.yml", body)
+		Registry = map[string]*Model{}
+		if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+}
@@ -4,35 +4,56 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"sync"
+	"sync/atomic"
 
 	"YrestAPI/internal/logger"
 )
 
+// Registry — текущий реестр моделей. После публикации не изменяется:
+// перезагрузка собирает новый map и подменяет его целиком (SwapRegistry).
 var Registry = map[string]*Model{}
 
-func InitRegistry(dir string) error {
-	runtime.GC() // baseline before loading
-	before := readAllocBytes()
+var (
+	registryMu  sync.RWMutex
+	registryGen atomic.Uint64 // растёт при каждой подмене; входит в ключ кеша алиасов
+)
 
-	if err := LoadModelsFromDir(dir); err != nil {
-		return fmt.Errorf("load error: %w", err)
+// BuildRegistry загружает, линкует и валидирует модели из dir в новый реестр,
+// не затрагивая текущий.
+func BuildRegistry(dir string) (map[string]*Model, error) {
+	reg := map[string]*Model{}
+	if err := loadModelsInto(reg, dir); err != nil {
+		return nil, fmt.Errorf("load error: %w", err)
 	}
-	if err := LinkModelRelations(); err != nil {
-		return fmt.Errorf("link error: %w", err)
+	if err := linkModelRelations(reg); err != nil {
+		return nil, fmt.Errorf("link error: %w", err)
 	}
-	if err := ValidateAllPresets(); err != nil {
-		return fmt.Errorf("validation error: %w", err)
+	if err := validateAllPresets(reg); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
 	}
-	if err := BuildPresetAliasMaps(); err != nil {
-		return fmt.Errorf("build preset alias maps: %w", err)
+	if err := buildPresetAliasMaps(reg); err != nil {
+		return nil, fmt.Errorf("build preset alias maps: %w", err)
 	}
+	return reg, nil
+}
+
+func InitRegistry(dir string) error {
+	runtime.GC() // baseline before loading
+	before := readAllocBytes()
+
+	reg, err := BuildRegistry(dir)
+	if err != nil {
+		return err
+	}
+	SwapRegistry(reg)
 
 	after := readAllocBytes() // heap right after load (without GC)
 	regUsage := int64(after) - int64(before)
-	totalPresets, totalComputable := countPresetsAndComputable()
+	totalPresets, totalComputable := countPresetsAndComputable(reg)
 	limitBytes, limitSrc := detectMemoryLimit()
 	logger.Info("registry_initialized", map[string]any{
-		"models":     len(Registry),
+		"models":     len(reg),
 		"presets":    totalPresets,
 		"computable": totalComputable,
 		"heap_now":   formatBytes(after),
@@ -41,11 +62,64 @@ func InitRegistry(dir string) error {
 		"limit_src":  limitSrc,
 	})
 	log.Printf("📦 Registry initialized: models=%d, presets=%d, computable=%d, heap now≈%s, delta≈%s, limit≈%s (source: %s)",
-		len(Registry), totalPresets, totalComputable, formatBytes(after), formatBytes(uint64(max64(regUsage, 0))), formatBytes(limitBytes), limitSrc)
+		len(reg), totalPresets, totalComputable, formatBytes(after), formatBytes(uint64(max64(regUsage, 0))), formatBytes(limitBytes), limitSrc)
+
+	return nil
+}
 
+// ReloadRegistry собирает реестр из dir заново и подменяет текущий.
+// При ошибке текущий реестр продолжает обслуживать запросы.
+func ReloadRegistry(dir string) error {
+	reg, err := BuildRegistry(dir)
+	if err != nil {
+		logger.Error("registry_reload_failed", map[string]any{
+			"dir":   dir,
+			"error": err.Error(),
+		})
+		return err
+	}
+	SwapRegistry(reg)
+	ResetAliasCache()
+	totalPresets, totalComputable := countPresetsAndComputable(reg)
+	logger.Info("registry_reloaded", map[string]any{
+		"models":     len(reg),
+		"presets":    totalPresets,
+		"computable": totalComputable,
+		"generation": registryGen.Load(),
+	})
 	return nil
 }
 
+// SwapRegistry публикует полностью собранный реестр.
+func SwapRegistry(reg map[string]*Model) {
+	registryMu.Lock()
+	Registry = reg
+	registryGen.Add(1)
+	registryMu.Unlock()
+}
+
+// CurrentRegistry возвращает текущий реестр. Map нельзя изменять:
+// он может одновременно читаться другими запросами.
+func CurrentRegistry() map[string]*Model {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return Registry
+}
+
+// RegistrySnapshot возвращает текущий реестр вместе с его поколением:
+// по поколению можно кешировать то, что построено из реестра.
+func RegistrySnapshot() (map[string]*Model, uint64) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return Registry, registryGen.Load()
+}
+
+// LookupModel ищет модель в текущем реестре.
+func LookupModel(name string) (*Model, bool) {
+	m, ok := CurrentRegistry()[name]
+	return m, ok && m != nil
+}
+
 func (m *Model) GetPreset(name string) *DataPreset {
 	if p, ok := m.Presets[name]; ok {
 		return p
@@ -61,7 +135,7 @@ func (m *Model) GetRelation(alias string) *ModelRelation {
 }
 
 func GetModelName(m *Model) string {
-	for name, model := range Registry {
+	for name, model := range CurrentRegistry() {
 		if model == m {
 			return name
 		}
@@ -12,9 +12,13 @@ import (
 // 1) корректность ссылок и типов,
 // 2) допустимые (или недопустимые) циклы согласно политикам Reentrant/MaxDepth.
 func ValidateAllPresets() error {
-	for modelName, model := range Registry {
+	return validateAllPresets(Registry)
+}
+
+func validateAllPresets(reg map[string]*Model) error {
+	for modelName, model := range reg {
 		for presetName := range model.Presets {
-			if err := validatePresetGraph(modelName, presetName); err != nil {
+			if err := validatePresetGraph(reg, modelName, presetName); err != nil {
 				return err
 			}
 		}
@@ -24,7 +28,7 @@ func ValidateAllPresets() error {
 
 // validatePresetGraph запускает DFS-обход графа NestedPreset'ов с политиками циклов.
 // path хранит цепочку узлов "Model" для сообщений об ошибках и подсчёта повторов.
-func validatePresetGraph(modelName, presetName string) error {
+func validatePresetGraph(reg map[string]*Model, modelName, presetName string) error {
 	node := modelName + "." + presetName
 	// pathNodes — ради читаемых сообщений
 	pathNodes := []string{node}
@@ -33,15 +37,15 @@ func validatePresetGraph(modelName, presetName string) error {
 	// сколько раз модель встречалась на текущем пути
 	modelCounts := map[string]int{modelName: 1}
 	warnedDefaults := map[string]bool{}
-	return dfsPresetWithPolicy(modelName, presetName, pathNodes, pathModels, modelCounts, warnedDefaults)
+	return dfsPresetWithPolicy(reg, modelName, presetName, pathNodes, pathModels, modelCounts, warnedDefaults)
 
 }
 
 // dfsPresetWithPolicy — основной обход.
 // Разрешает возвращаться к уже встреченным узлам ТОЛЬКО если связь reentrant
 // и не превышен эффективный maxDepth (берётся из поля или связи).
-func dfsPresetWithPolicy(modelName, presetName string, pathNodes, pathModels []string, modelCounts map[string]int, warnedDefaults map[string]bool) error {
-	model, ok := Registry[modelName]
+func dfsPresetWithPolicy(reg map[string]*Model, modelName, presetName string, pathNodes, pathModels []string, modelCounts map[string]int, warnedDefaults map[string]bool) error {
+	model, ok := reg[modelName]
 	if !ok {
 		return fmt.Errorf("model not found: %s", modelName)
 	}
@@ -72,11 +76,11 @@ func dfsPresetWithPolicy(modelName, presetName string, pathNodes, pathModels []s
 			)
 		}
 		switch rel.Type {
-		case "has_one", "has_many", "belongs_to":
+		case "has_one", "has_many", "belongs_to", "tree":
 			// ok
 		default:
 			return fmt.Errorf(
-				"field %q in %s.%s refers to relation %q of unsupported type %q (allowed: has_one, has_many, belongs_to)",
+				"field %q in %s.%s refers to relation %q of unsupported type %q (allowed: has_one, has_many, belongs_to, tree)",
 				fieldNameForMsg(f), modelName, presetName, f.Source, rel.Type,
 			)
 		}
@@ -88,7 +92,7 @@ func dfsPresetWithPolicy(modelName, presetName string, pathNodes, pathModels []s
 
 		// 3) определить целевую модель/пресет
 		nestedModelName := rel.Model
-		nestedModel, ok := Registry[nestedModelName]
+		nestedModel, ok := reg[nestedModelName]
 		if !ok {
 			return fmt.Errorf(
 				"relation %q in %s points to unknown model %q",
@@ -102,6 +106,10 @@ func dfsPresetWithPolicy(modelName, presetName string, pathNodes, pathModels []s
 				nestedPresetName, nestedModelName, modelName, presetName, fieldNameForMsg(f),
 			)
 		}
+		if rel.Type == "tree" {
+			// tree раскрывается одним рекурсивным запросом, а не вложением пресетов
+			continue
+		}
 
 		// 4) проверка ре-энтри по МОДЕЛИ (а не по узлу Model.Preset)
 		seen := modelCounts[nestedModelName] // 0 если не встречалась
@@ -142,7 +150,7 @@ func dfsPresetWithPolicy(modelName, presetName string, pathNodes, pathModels []s
 		newCounts := cloneCounts(modelCounts)
 		newCounts[nestedModelName] = seen + 1
 
-		if err := dfsPresetWithPolicy(nestedModelName, nestedPresetName, newPathNodes, newPathModels, newCounts, warnedDefaults); err != nil {
+		if err := dfsPresetWithPolicy(reg, nestedModelName, nestedPresetName, newPathNodes, newPathModels, newCounts, warnedDefaults); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"YrestAPI/internal/logger"
+)
+
+// WatchModelsDir опрашивает YAML-файлы в dir (и dir/templates) раз в interval
+// и перезагружает реестр, когда меняется их набор, размер или mtime.
+// Неудачная сборка логируется один раз на изменение; старый реестр остаётся.
+// Работает до закрытия stop.
+func WatchModelsDir(dir string, interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+	last, err := modelsFingerprint(dir)
+	if err != nil {
+		logger.Warn("models_watch_failed", map[string]any{"dir": dir, "error": err.Error()})
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		cur, err := modelsFingerprint(dir)
+		if err != nil {
+			logger.Warn("models_watch_failed", map[string]any{"dir": dir, "error": err.Error()})
+			continue
+		}
+		if cur == last {
+			continue
+		}
+		last = cur
+		logger.Info("models_changed", map[string]any{"dir": dir})
+		_ = ReloadRegistry(dir)
+	}
+}
+
+// modelsFingerprint — отпечаток файлов, из которых собирается реестр.
+func modelsFingerprint(dir string) (string, error) {
+	var files []string
+	for _, pattern := range []string{
+		filepath.Join(dir, "*.yml"),
+		filepath.Join(dir, "templates", "*.yml"),
+	} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	var b strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // удалён между Glob и Stat
+			}
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const reloadPersonYAML = `
+table: people
+presets:
+  item:
+    fields:
+      - source: id
+        type: int
+`
+
+func TestReloadRegistry_SwapsAndKeepsOldOnError(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Person.yml", reloadPersonYAML)
+	if err := InitRegistry(dir); err != nil {
+		t.Fatalf("InitRegistry: %v", err)
+	}
+	first, _ := LookupModel("Person")
+	gen := registryGen.Load()
+
+	// новая модель появляется после перезагрузки, старый map не меняется
+	old := CurrentRegistry()
+	write(t, dir, "Country.yml", "table: countries\npresets:\n  item:\n    fields:\n      - source: id\n        type: int\n")
+	if err := ReloadRegistry(dir); err != nil {
+		t.Fatalf("ReloadRegistry: %v", err)
+	}
+	if _, ok := LookupModel("Country"); !ok {
+		t.Fatal("Country must be loaded after reload")
+	}
+	if _, ok := old["Country"]; ok {
+		t.Fatal("previous registry must not be mutated")
+	}
+	if second, _ := LookupModel("Person"); second == first {
+		t.Fatal("reload must build fresh models")
+	}
+	if registryGen.Load() != gen+1 {
+		t.Fatalf("generation must grow: %d -> %d", gen, registryGen.Load())
+	}
+
+	// невалидный YAML: ошибка, текущий реестр продолжает работать
+	current := CurrentRegistry()
+	write(t, dir, "Broken.yml", "table: broken\nrelations:\n  owner:\n    type: belongs_to\n    model: Missing\n")
+	if err := ReloadRegistry(dir); err == nil {
+		t.Fatal("expected link error")
+	}
+	if _, ok := LookupModel("Country"); !ok || len(CurrentRegistry()) != len(current) {
+		t.Fatal("failed reload must keep the previous registry")
+	}
+}
+
+func TestAliasCacheKey_DependsOnGeneration(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	p := &DataPreset{Name: "item"}
+	before, err := aliasCacheKey("Person", p, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SwapRegistry(Registry)
+	after, err := aliasCacheKey("Person", p, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Fatal("alias cache key must change after registry swap")
+	}
+}
+
+func TestWatchModelsDir_ReloadsOnChange(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Person.yml", reloadPersonYAML)
+	if err := InitRegistry(dir); err != nil {
+		t.Fatalf("InitRegistry: %v", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		WatchModelsDir(dir, 10*time.Millisecond, stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// даём вотчеру снять исходный отпечаток
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "Country.yml"), []byte("table: countries\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := LookupModel("Country"); ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("watcher did not reload the registry")
+}
@@ -0,0 +1,197 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// SearchConfig — полнотекстовый поиск по модели:
+//
+//	search:
+//	  config: english            # конфигурация text search, по умолчанию simple
+//	  fields: [name, org.name]   # поля и пути belongs_to/has_one
+//
+// Фильтр "__fts" (и поле q запроса) ищет по склейке fields, "<поле>__fts" —
+// по одному полю. Сортировка "rank" упорядочивает по ts_rank.
+type SearchConfig struct {
+	Config string     `yaml:"config"`
+	Fields StringList `yaml:"fields"`
+}
+
+const (
+	defaultSearchConfig = "simple"
+	searchOp            = "fts"
+	// SearchFilterKey — ключ фильтра поиска по полям search модели.
+	SearchFilterKey = "__" + searchOp
+	// RankSortKey — сортировка по релевантности поиска.
+	RankSortKey = "rank"
+)
+
+var searchConfigRe = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// SearchConfigName возвращает конфигурацию text search модели.
+func (m *Model) SearchConfigName() string {
+	if m != nil && m.Search != nil && m.Search.Config != "" {
+		return m.Search.Config
+	}
+	return defaultSearchConfig
+}
+
+// searchFieldKey — поля search модели в виде составного ключа фильтра
+// ("first_name_or_last_name"), которым NormalizeFiltersWithAliases заменяет
+// пустое поле в "__fts": так JOIN-ы путей находятся как у обычных фильтров.
+func (m *Model) searchFieldKey() string {
+	if m == nil || m.Search == nil {
+		return ""
+	}
+	fields := make([]string, len(m.Search.Fields))
+	for i, f := range m.Search.Fields {
+		fields[i] = ExpandAliasPath(m, f)
+	}
+	return strings.Join(fields, "_or_")
+}
+
+// searchVector склеивает выражения полей в документ to_tsvector. Конфигурация
+// вставляется литералом (она проверена при загрузке), чтобы выражение совпадало
+// с индексом вида
+//
+//	CREATE INDEX ... USING gin (to_tsvector('simple', coalesce(name::text, '')))
+func searchVector(config string, exprs []string) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = fmt.Sprintf("coalesce(%s::text, '')", e)
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", config, strings.Join(parts, " || ' ' || "))
+}
+
+func searchQuery(config string) string {
+	return fmt.Sprintf("websearch_to_tsquery('%s', ?)", config)
+}
+
+// searchCond — условие "<документ> @@ websearch_to_tsquery(...)".
+func (m *Model) searchCond(exprs []string, val any) (squirrel.Sqlizer, error) {
+	q, ok := val.(string)
+	if !ok || strings.TrimSpace(q) == "" {
+		return nil, fmt.Errorf("search expects a non-empty string")
+	}
+	if len(exprs) == 0 {
+		return nil, fmt.Errorf("model %s has no search fields", m.Name)
+	}
+	cfg := m.SearchConfigName()
+	return squirrel.Expr(searchVector(cfg, exprs)+" @@ "+searchQuery(cfg), q), nil
+}
+
+// SearchText возвращает строку поиска по полям search модели ("__fts" или
+// q после NormalizeFiltersWithAliases). Ищет на верхнем уровне и в группах
+// "and", куда её помещают политики; под "or" поиск не обязателен для строки,
+// и ранжировать по нему нельзя.
+func (m *Model) SearchText(filters map[string]any) (string, bool) {
+	key := m.searchFieldKey()
+	if key == "" {
+		return "", false
+	}
+	var find func(v any) (string, bool)
+	find = func(v any) (string, bool) {
+		switch val := v.(type) {
+		case map[string]any:
+			if q, ok := val[key+SearchFilterKey].(string); ok && strings.TrimSpace(q) != "" {
+				return q, true
+			}
+			return find(val["and"])
+		case []any:
+			for _, item := range val {
+				if q, ok := find(item); ok {
+					return q, true
+				}
+			}
+		}
+		return "", false
+	}
+	return find(filters)
+}
+
+// IsRankSort сообщает, что сортировка s — по релевантности поиска.
+func IsRankSort(s string) bool {
+	parts := strings.Fields(s)
+	return len(parts) > 0 && parts[0] == RankSortKey
+}
+
+func validateSearch(m *Model) error {
+	s := m.Search
+	if s == nil {
+		return nil
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("search.fields must not be empty")
+	}
+	if s.Config != "" && !searchConfigRe.MatchString(s.Config) {
+		return fmt.Errorf("search.config %q is not a valid text search configuration name", s.Config)
+	}
+	for _, f := range s.Fields {
+		if strings.Contains(f, "__") || strings.Contains(f, "_or_") || strings.Contains(f, "_and_") {
+			return fmt.Errorf("search field %q must be a plain field or path", f)
+		}
+	}
+	return nil
+}
+
+// validateSearchPaths проверяет после линковки, что пути search ведут по
+// belongs_to/has_one: has_many размножил бы строки главного запроса.
+func validateSearchPaths(m *Model) error {
+	if m.Search == nil {
+		return nil
+	}
+	for _, f := range m.Search.Fields {
+		segs := strings.Split(ExpandAliasPath(m, f), ".")
+		curr := m
+		for i := 0; i < len(segs)-1; i++ {
+			rel := curr.Relations[segs[i]]
+			if rel == nil || rel.Polymorphic || rel.GetModelRef() == nil {
+				return fmt.Errorf("search field %q: relation %q not found", f, strings.Join(segs[:i+1], "."))
+			}
+			if rel.Type != "belongs_to" && rel.Type != "has_one" {
+				return fmt.Errorf("search field %q traverses %s relation %q", f, rel.Type, strings.Join(segs[:i+1], "."))
+			}
+			curr = rel.GetModelRef()
+		}
+	}
+	return nil
+}
+
+// rankOrder строит выражение ts_rank по полям search модели и строке поиска:
+// заданной резолвером в aliasMap (WithSearchText), иначе из фильтров;
+// ok=false, если поиск в запросе не задан.
+func (m *Model) rankOrder(preset *DataPreset, aliasMap *AliasMap, filters map[string]any) (string, []any, bool) {
+	q := ""
+	if aliasMap != nil {
+		q = aliasMap.searchText
+	}
+	if strings.TrimSpace(q) == "" {
+		var ok bool
+		if q, ok = m.SearchText(filters); !ok {
+			return "", nil, false
+		}
+	}
+	exprs := make([]string, 0, len(m.Search.Fields))
+	for _, f := range m.Search.Fields {
+		f = ExpandAliasPath(m, f)
+		if expr, ok := m.resolveFieldExpression(preset, aliasMap, f); ok {
+			exprs = append(exprs, expr)
+			continue
+		}
+		alias := "main"
+		if idx := strings.LastIndex(f, "."); idx != -1 {
+			var ok bool
+			if alias, ok = aliasMap.PathToAlias[f[:idx]]; !ok {
+				return "", nil, false
+			}
+			f = f[idx+1:]
+		}
+		exprs = append(exprs, fmt.Sprintf("%s.%s", alias, f))
+	}
+	cfg := m.SearchConfigName()
+	return fmt.Sprintf("ts_rank(%s, %s)", searchVector(cfg, exprs), searchQuery(cfg)), []any{q}, true
+}
@@ -0,0 +1,177 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func searchSQL(t *testing.T, filters map[string]any, sorts []string) (string, []any) {
+	t.Helper()
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	m := reg["Person"]
+	preset := m.Presets["item"]
+	filters = NormalizeFiltersWithAliases(m, filters)
+	aliasMap, err := m.CreateAliasMap(m, preset, filters, sorts)
+	if err != nil {
+		t.Fatalf("CreateAliasMap: %v", err)
+	}
+	sb, err := m.BuildIndexQuery(aliasMap, filters, sorts, preset, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	return sql, args
+}
+
+func TestSearchFilterUsesModelFields(t *testing.T) {
+	sql, args := searchSQL(t, map[string]any{"__fts": "serge dev"}, nil)
+	for _, want := range []string{
+		"LEFT JOIN person_profiles AS t0 ON",
+		"to_tsvector('simple', coalesce(main.first_name::text, '') || ' ' || coalesce(main.last_name::text, '') || ' ' || coalesce(t0.bio::text, '')) @@ websearch_to_tsquery('simple', $1)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL: %s", want, sql)
+		}
+	}
+	if !reflect.DeepEqual(args, []any{"serge dev"}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestSearchFilterOnField(t *testing.T) {
+	sql, _ := searchSQL(t, map[string]any{"last_name__fts": "tan"}, nil)
+	if !strings.Contains(sql, "to_tsvector('simple', coalesce(main.last_name::text, '')) @@ websearch_to_tsquery('simple', $1)") {
+		t.Fatalf("unexpected SQL: %s", sql)
+	}
+}
+
+// Некорректный поиск — ошибка клиента, а не молча отброшенный фильтр.
+func TestSearchFilterInvalid(t *testing.T) {
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	for _, tc := range []struct {
+		model   string
+		filters map[string]any
+	}{
+		{"Person", map[string]any{"last_name__fts": 5}},
+		{"Person", map[string]any{"__fts": "  "}},
+		{"Contragent", map[string]any{"__fts": "data"}},
+	} {
+		m := reg[tc.model]
+		preset := m.Presets["item"]
+		filters := NormalizeFiltersWithAliases(m, tc.filters)
+		aliasMap, err := m.CreateAliasMap(m, preset, filters, nil)
+		if err != nil {
+			t.Fatalf("CreateAliasMap: %v", err)
+		}
+		_, err = m.BuildIndexQuery(aliasMap, filters, nil, preset, 0, 0)
+		var filterErr *FilterError
+		if !errors.As(err, &filterErr) {
+			t.Fatalf("%s %v: expected FilterError, got %v", tc.model, tc.filters, err)
+		}
+	}
+}
+
+func TestRankSort(t *testing.T) {
+	sql, args := searchSQL(t, map[string]any{"id__gt": 0, "__fts": "data"}, []string{"rank", "id ASC"})
+	if !strings.Contains(sql, "ORDER BY ts_rank(to_tsvector('simple', ") || !strings.Contains(sql, "websearch_to_tsquery('simple', $3)) DESC, main.id ASC") {
+		t.Fatalf("unexpected rank SQL: %s", sql)
+	}
+	if len(args) != 3 || args[2] != "data" {
+		t.Fatalf("rank argument must follow WHERE arguments: %v", args)
+	}
+
+	// политика оборачивает фильтры в "and" — rank всё равно строится
+	sql, args = searchSQL(t, map[string]any{"and": []any{
+		map[string]any{"id__gt": 0},
+		map[string]any{"__fts": "data"},
+	}}, []string{"rank"})
+	if !strings.Contains(sql, "ORDER BY ts_rank(") || args[len(args)-1] != "data" {
+		t.Fatalf("rank must survive wrapped filters: %s %v", sql, args)
+	}
+
+	// без поиска rank пропускается
+	sql, _ = searchSQL(t, nil, []string{"rank"})
+	if strings.Contains(sql, "ts_rank") {
+		t.Fatalf("rank without search must be ignored: %s", sql)
+	}
+}
+
+func TestLoadModelsFromDir_Search(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	cases := map[string]string{
+		"table: notes\nsearch:\n  fields: [title]\n  weight: 1\n":        "unknown key 'weight' in search",
+		"table: notes\nsearch:\n  config: english\n":                     "search.fields must not be empty",
+		"table: notes\nsearch:\n  config: \"x'; --\"\n  fields: title\n": "not a valid text search configuration",
+		"table: notes\nsearch:\n  fields: [title__cnt]\n":                "must be a plain field or path",
+	}
+	for body, want := range cases {
+		write(t, dir, "Note.yml", body)
+		Registry = map[string]*Model{}
+		if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+}
+
+func TestSearchPathsMustNotTraverseHasMany(t *testing.T) {
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	m := reg["Person"]
+	m.Search = &SearchConfig{Fields: StringList{"employees.position"}}
+	if err := validateSearchPaths(m); err == nil || !strings.Contains(err.Error(), "traverses has_many") {
+		t.Fatalf("expected has_many error, got %v", err)
+	}
+}
+
+// Резолвер передаёт строку поиска клиента в aliasMap: rank строится, как
+// бы политика ни обернула фильтры.
+func TestRankSortWithPolicy(t *testing.T) {
+	reg, err := BuildRegistry("../../test_db")
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	m := *reg["Person"]
+	m.Policies = &ModelPolicies{Filter: map[string]any{"id__gt": "{claims.min_id}"}}
+	preset := m.Presets["item"]
+	sorts := []string{"rank"}
+	requestFilters := NormalizeFiltersWithAliases(&m, map[string]any{"__fts": "data"})
+	filters, err := m.ApplyPolicy(requestFilters, map[string]any{"min_id": float64(0)}, "")
+	if err != nil {
+		t.Fatalf("ApplyPolicy: %v", err)
+	}
+	aliasMap, err := m.CreateAliasMap(&m, preset, filters, sorts)
+	if err != nil {
+		t.Fatalf("CreateAliasMap: %v", err)
+	}
+	q, _ := m.SearchText(requestFilters)
+	sb, err := m.BuildIndexQuery(aliasMap.WithSearchText(q), filters, sorts, preset, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	if !strings.Contains(sql, "main.id > $1") || !strings.Contains(sql, "ORDER BY ts_rank(") {
+		t.Fatalf("expected policy filter and rank sort: %s", sql)
+	}
+	if args[len(args)-1] != "data" {
+		t.Fatalf("rank argument must be the search text: %v", args)
+	}
+}
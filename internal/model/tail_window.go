@@ -0,0 +1,61 @@
+package model
+
+import "fmt"
+
+// TailWindow возвращает смещение и предел строк has_many на одного родителя:
+// limit/offset поля пресета приоритетнее limit/offset связи. limit 0 —
+// окно не задано, хвост читается одним запросом без разбиения по родителям.
+func (f *Field) TailWindow(rel *ModelRelation) (offset, limit uint64) {
+	if rel == nil || rel.Type != "has_many" {
+		return 0, 0
+	}
+	lim, off := rel.Limit, rel.Offset
+	if f != nil && f.Limit > 0 {
+		lim = f.Limit
+	}
+	if f != nil && f.Offset > 0 {
+		off = f.Offset
+	}
+	if lim <= 0 {
+		return 0, 0
+	}
+	return uint64(off), uint64(lim)
+}
+
+func validateTailWindows(m *Model) error {
+	for name, rel := range m.Relations {
+		if rel == nil {
+			continue
+		}
+		if rel.Limit < 0 || rel.Offset < 0 {
+			return fmt.Errorf("relation '%s': limit and offset must not be negative", name)
+		}
+		if (rel.Limit > 0 || rel.Offset > 0) && rel.Type != "has_many" {
+			return fmt.Errorf("relation '%s': limit and offset are supported only for has_many", name)
+		}
+		if rel.Offset > 0 && rel.Limit == 0 {
+			return fmt.Errorf("relation '%s': offset requires limit", name)
+		}
+	}
+	for pname, p := range m.Presets {
+		if p == nil {
+			continue
+		}
+		for _, f := range p.Fields {
+			if f.Limit == 0 && f.Offset == 0 {
+				continue
+			}
+			if f.Limit < 0 || f.Offset < 0 {
+				return fmt.Errorf("preset '%s' field '%s': limit and offset must not be negative", pname, f.Source)
+			}
+			rel := m.Relations[f.Source]
+			if f.Type != "preset" || rel == nil || rel.Type != "has_many" {
+				return fmt.Errorf("preset '%s' field '%s': limit and offset are supported only for has_many preset fields", pname, f.Source)
+			}
+			if f.Offset > 0 && f.Limit == 0 && rel.Limit == 0 {
+				return fmt.Errorf("preset '%s' field '%s': offset requires limit", pname, f.Source)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildPartitionedIndexQuery(t *testing.T) {
+	m, preset, aliasMap := stringFilterFixture()
+	filters := map[string]any{"id__in": []any{1, 2}}
+
+	sb, err := m.BuildPartitionedIndexQuery(aliasMap, filters, []string{"name DESC", "id ASC"}, preset, "org_id", 2, 5)
+	if err != nil {
+		t.Fatalf("BuildPartitionedIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	for _, want := range []string{
+		`ROW_NUMBER() OVER (PARTITION BY main.org_id ORDER BY main.name DESC, main.id ASC) AS "__row_in_parent"`,
+		`) AS partitioned WHERE partitioned."__row_in_parent" > $3 AND partitioned."__row_in_parent" <= $4`,
+		`ORDER BY partitioned."__row_in_parent"`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL: %s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "LIMIT") || strings.Contains(sql, "OFFSET") {
+		t.Fatalf("window must replace LIMIT/OFFSET: %s", sql)
+	}
+	if !reflect.DeepEqual(args, []any{1, 2, uint64(2), uint64(7)}) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestFieldTailWindow(t *testing.T) {
+	rel := &ModelRelation{Type: "has_many", Limit: 5, Offset: 1}
+	if off, lim := (&Field{}).TailWindow(rel); off != 1 || lim != 5 {
+		t.Fatalf("relation window: got %d,%d", off, lim)
+	}
+	if off, lim := (&Field{Limit: 2}).TailWindow(rel); off != 1 || lim != 2 {
+		t.Fatalf("field limit must override: got %d,%d", off, lim)
+	}
+	if _, lim := (&Field{Limit: 2}).TailWindow(&ModelRelation{Type: "has_one"}); lim != 0 {
+		t.Fatalf("has_one has no window, got limit %d", lim)
+	}
+}
+
+func TestLoadModelsFromDir_TailWindowValidation(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	cases := map[string]string{
+		"negative": `
+table: orgs
+relations:
+  people:
+    type: has_many
+    model: Person
+    limit: -1
+`,
+		"has_one": `
+table: orgs
+relations:
+  boss:
+    type: has_one
+    model: Person
+    limit: 1
+`,
+		"scalar field": `
+table: orgs
+presets:
+  item:
+    fields:
+      - source: id
+        type: int
+        limit: 3
+`,
+	}
+	for name, src := range cases {
+		dir := t.TempDir()
+		write(t, dir, "Org.yml", src)
+		Registry = map[string]*Model{}
+		if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "limit") {
+			t.Fatalf("%s: expected limit validation error, got %v", name, err)
+		}
+	}
+}
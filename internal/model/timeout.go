@@ -0,0 +1,45 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// QueryTimeout возвращает предельное время запроса: timeout_ms пресета,
+// иначе модели, иначе fallback. 0 — без ограничения.
+func (m *Model) QueryTimeout(p *DataPreset, fallback time.Duration) time.Duration {
+	if p != nil && p.TimeoutMS > 0 {
+		return time.Duration(p.TimeoutMS) * time.Millisecond
+	}
+	if m != nil && m.TimeoutMS > 0 {
+		return time.Duration(m.TimeoutMS) * time.Millisecond
+	}
+	return fallback
+}
+
+func validateTimeouts(m *Model) error {
+	if m.TimeoutMS < 0 {
+		return fmt.Errorf("timeout_ms must not be negative")
+	}
+	for name, p := range m.Presets {
+		if p != nil && p.TimeoutMS < 0 {
+			return fmt.Errorf("timeout_ms must not be negative in preset '%s'", name)
+		}
+	}
+	return nil
+}
+
+// inheritTimeout берёт для пресета без собственного timeout_ms самый строгий
+// таймаут его родителей из extends.
+func inheritTimeout(parents []*DataPreset) int {
+	out := 0
+	for _, parent := range parents {
+		if parent == nil || parent.TimeoutMS <= 0 {
+			continue
+		}
+		if out == 0 || parent.TimeoutMS < out {
+			out = parent.TimeoutMS
+		}
+	}
+	return out
+}
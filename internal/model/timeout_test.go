@@ -0,0 +1,54 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadModelsFromDir_Timeouts(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	dir := t.TempDir()
+	write(t, dir, "Report.yml", `
+table: reports
+timeout_ms: 5000
+presets:
+  item:
+    fields:
+      - source: id
+        type: int
+  heavy:
+    timeout_ms: 1500
+    fields:
+      - source: body
+        type: string
+  heavy_full:
+    extends: item, heavy
+`)
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err != nil {
+		t.Fatalf("LoadModelsFromDir: %v", err)
+	}
+	m := getModel(t, "Report")
+	cases := map[string]time.Duration{
+		"item":       5 * time.Second,
+		"heavy":      1500 * time.Millisecond,
+		"heavy_full": 1500 * time.Millisecond, // наследуется через extends
+	}
+	for name, want := range cases {
+		if got := m.QueryTimeout(m.Presets[name], time.Minute); got != want {
+			t.Fatalf("preset %s: got %v, want %v", name, got, want)
+		}
+	}
+	if got := (&Model{}).QueryTimeout(nil, time.Minute); got != time.Minute {
+		t.Fatalf("fallback: got %v", got)
+	}
+
+	write(t, dir, "Report.yml", "table: reports\npresets:\n  item:\n    timeout_ms: -1\n")
+	Registry = map[string]*Model{}
+	if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "timeout_ms must not be negative in preset 'item'") {
+		t.Fatalf("expected negative timeout error, got %v", err)
+	}
+}
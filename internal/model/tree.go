@@ -0,0 +1,144 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Направления обхода tree-связи.
+const (
+	TreeDescendants = "descendants" // поддерево: дети, их дети и т.д. (по умолчанию)
+	TreeAncestors   = "ancestors"   // цепочка родителей до корня
+)
+
+// TreeDirection возвращает направление обхода связи type: tree.
+func (r *ModelRelation) TreeDirection() string {
+	if strings.TrimSpace(r.Direction) == TreeAncestors {
+		return TreeAncestors
+	}
+	return TreeDescendants
+}
+
+// TreeDepth — предел глубины обхода: max_depth поля, иначе связи; 0 — без
+// ограничения (циклы отсекаются по пути обхода).
+func (f *Field) TreeDepth(rel *ModelRelation) int {
+	if f != nil && f.MaxDepth > 0 {
+		return f.MaxDepth
+	}
+	return max(rel.MaxDepth, 0)
+}
+
+// treeCTE строит "WITH RECURSIVE tree(root, id, parent, depth, path)" — обход
+// таблицы связи от корней roots, переданных одним параметром-массивом. root —
+// ключ исходной строки, depth — расстояние от неё (1 — прямые дети или
+// родитель). Повторный заход в узел на пути обхода отсекается, поэтому циклы
+// в данных не зацикливают запрос. Если у модели есть политика, обход идёт
+// только по видимым с claims узлам: скрытый узел обрывает поддерево или
+// цепочку родителей.
+func (m *Model) treeCTE(rel *ModelRelation, direction string, maxDepth int, roots IDList, claims map[string]any) (string, []any, error) {
+	table, pk, parent := m.Table, rel.PK, rel.ParentFK
+	visible, visibleArgs, err := m.policyKeyQuery([]string{pk}, claims)
+	if err != nil {
+		return "", nil, err
+	}
+	nodeCond := ""
+	if visible != "" {
+		nodeCond = fmt.Sprintf(" AND n.%s IN (%s)", pk, visible)
+	}
+	var seed, step string
+	if direction == TreeAncestors {
+		seed = fmt.Sprintf(
+			"SELECT c.%[2]s, n.%[2]s, n.%[3]s, 1, ARRAY[c.%[2]s, n.%[2]s] FROM %[1]s c JOIN %[1]s n ON n.%[2]s = c.%[3]s WHERE c.%[2]s = ANY(?)",
+			table, pk, parent)
+		step = fmt.Sprintf(
+			"SELECT t.root, n.%[2]s, n.%[3]s, t.depth + 1, t.path || n.%[2]s FROM %[1]s n JOIN tree t ON n.%[2]s = t.parent WHERE n.%[2]s <> ALL(t.path)",
+			table, pk, parent)
+	} else {
+		seed = fmt.Sprintf(
+			"SELECT n.%[3]s, n.%[2]s, n.%[3]s, 1, ARRAY[n.%[3]s, n.%[2]s] FROM %[1]s n WHERE n.%[3]s = ANY(?)",
+			table, pk, parent)
+		step = fmt.Sprintf(
+			"SELECT t.root, n.%[2]s, n.%[3]s, t.depth + 1, t.path || n.%[2]s FROM %[1]s n JOIN tree t ON n.%[3]s = t.id WHERE n.%[2]s <> ALL(t.path)",
+			table, pk, parent)
+	}
+	if maxDepth > 0 {
+		step += fmt.Sprintf(" AND t.depth < %d", maxDepth)
+	}
+	args := append([]any{roots.arrayParam()}, visibleArgs...)
+	args = append(args, visibleArgs...)
+	return "WITH RECURSIVE tree(root, id, parent, depth, path) AS (" + seed + nodeCond + " UNION ALL " + step + nodeCond + ")", args, nil
+}
+
+// BuildTreeQuery строит запрос рёбер tree-связи для корней roots: строки
+// (root, id, parent, depth), по которым резолвер собирает поддеревья или
+// цепочки родителей. claims — для политики модели узлов.
+func (m *Model) BuildTreeQuery(rel *ModelRelation, roots IDList, maxDepth int, claims map[string]any) (string, []any, error) {
+	if rel == nil || rel.Type != "tree" {
+		return "", nil, fmt.Errorf("relation is not a tree")
+	}
+	cte, args, err := m.treeCTE(rel, rel.TreeDirection(), maxDepth, roots, claims)
+	if err != nil {
+		return "", nil, err
+	}
+	out, args, err := squirrel.Expr(cte+" SELECT root, id, parent, depth FROM tree", args...).ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	out, err = squirrel.Dollar.ReplacePlaceholders(out)
+	return out, args, err
+}
+
+// treeFilterCond — условие фильтров <связь>__descendants_of / __ancestors_of:
+// ключ строки входит в поддерево (цепочку родителей) заданных узлов, сами
+// узлы не включаются.
+func (m *Model) treeFilterCond(rel *ModelRelation, op string, val any, claims map[string]any) (squirrel.Sqlizer, error) {
+	direction := TreeDescendants
+	if op == "ancestors_of" {
+		direction = TreeAncestors
+	}
+	var roots IDList
+	switch v := val.(type) {
+	case IDList:
+		roots = v
+	case []any:
+		roots = IDList(v)
+	default:
+		roots = IDList{v}
+	}
+	cte, args, err := m.treeCTE(rel, direction, 0, roots, claims)
+	if err != nil {
+		return nil, err
+	}
+	return squirrel.Expr(fmt.Sprintf("main.%s IN (%s SELECT id FROM tree)", rel.PK, cte), args...), nil
+}
+
+func validateTreeRelations(m *Model) error {
+	for name, rel := range m.Relations {
+		if rel == nil {
+			continue
+		}
+		if rel.Type != "tree" {
+			if rel.ParentFK != "" || rel.Direction != "" {
+				return fmt.Errorf("relation '%s': parent_fk and direction are supported only for type: tree", name)
+			}
+			continue
+		}
+		if strings.TrimSpace(rel.ParentFK) == "" {
+			return fmt.Errorf("relation '%s': tree relation requires parent_fk", name)
+		}
+		switch rel.Direction {
+		case "", TreeDescendants, TreeAncestors:
+		default:
+			return fmt.Errorf("relation '%s': tree direction must be %s or %s", name, TreeDescendants, TreeAncestors)
+		}
+		if rel.Through != "" || rel.Polymorphic || rel.Where != "" || rel.ThroughWhere != "" {
+			return fmt.Errorf("relation '%s': tree relation does not support through, polymorphic or where", name)
+		}
+		if rel.MaxDepth < 0 {
+			return fmt.Errorf("relation '%s': max_depth must not be negative", name)
+		}
+	}
+	return nil
+}
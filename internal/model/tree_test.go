@@ -0,0 +1,199 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func treeFixture() (*Model, *DataPreset, *AliasMap) {
+	m, preset, aliasMap := stringFilterFixture()
+	m.Table = "departments"
+	m.Relations = map[string]*ModelRelation{
+		"subtree": {Type: "tree", Model: "Person", PK: "id", ParentFK: "parent_id"},
+	}
+	return m, preset, aliasMap
+}
+
+func TestBuildTreeQuery(t *testing.T) {
+	m, _, _ := treeFixture()
+	rel := m.Relations["subtree"]
+
+	sql, args, err := m.BuildTreeQuery(rel, IDList{int64(10), int64(20)}, 3, nil)
+	if err != nil {
+		t.Fatalf("BuildTreeQuery: %v", err)
+	}
+	for _, want := range []string{
+		"WITH RECURSIVE tree(root, id, parent, depth, path) AS (",
+		"FROM departments n WHERE n.parent_id = ANY($1)",
+		"JOIN tree t ON n.parent_id = t.id WHERE n.id <> ALL(t.path) AND t.depth < 3",
+		"SELECT root, id, parent, depth FROM tree",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL: %s", want, sql)
+		}
+	}
+	if !reflect.DeepEqual(args, []any{[]int64{10, 20}}) {
+		t.Fatalf("expected one array parameter, got %#v", args)
+	}
+
+	rel.Direction = TreeAncestors
+	sql, _, err = m.BuildTreeQuery(rel, IDList{int64(12)}, 0, nil)
+	if err != nil {
+		t.Fatalf("BuildTreeQuery ancestors: %v", err)
+	}
+	for _, want := range []string{
+		"FROM departments c JOIN departments n ON n.id = c.parent_id WHERE c.id = ANY($1)",
+		"JOIN tree t ON n.id = t.parent WHERE n.id <> ALL(t.path))",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL: %s", want, sql)
+		}
+	}
+}
+
+func TestTreeFilters(t *testing.T) {
+	m, preset, aliasMap := treeFixture()
+	filters := map[string]any{"subtree__descendants_of": float64(10)}
+
+	sb, err := m.BuildIndexQuery(aliasMap, filters, nil, preset, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, err := sb.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql: %v", err)
+	}
+	if !strings.Contains(sql, "main.id IN (WITH RECURSIVE tree") || !strings.Contains(sql, "SELECT id FROM tree)") {
+		t.Fatalf("expected subtree condition, got %s", sql)
+	}
+	if !reflect.DeepEqual(args, []any{[]int64{10}}) {
+		t.Fatalf("expected JSON number as int array, got %#v", args)
+	}
+
+	filters = map[string]any{"subtree__ancestors_of": []any{float64(11), float64(12)}}
+	sb, err = m.BuildIndexQuery(aliasMap, filters, nil, preset, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, args, _ = sb.ToSql()
+	if !strings.Contains(sql, "WHERE c.id = ANY($1)") || !reflect.DeepEqual(args, []any{[]int64{11, 12}}) {
+		t.Fatalf("expected ancestors condition, got %s %#v", sql, args)
+	}
+}
+
+func TestLoadModelsFromDir_TreeRelation(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "Node.yml", `
+table: nodes
+relations:
+  children:
+    type: tree
+    parent_fk: parent_id
+presets:
+  tree:
+    fields:
+      - source: id
+        type: int
+      - source: children
+        type: preset
+        preset: tree
+        max_depth: 2
+`)
+	reg, err := BuildRegistry(dir)
+	if err != nil {
+		t.Fatalf("BuildRegistry: %v", err)
+	}
+	rel := reg["Node"].Relations["children"]
+	if rel.Model != "Node" || rel.PK != "id" || rel.TreeDirection() != TreeDescendants {
+		t.Fatalf("unexpected tree defaults: %#v", rel)
+	}
+	f := reg["Node"].Presets["tree"].Fields[1]
+	if got := f.TreeDepth(rel); got != 2 {
+		t.Fatalf("field max_depth must apply, got %d", got)
+	}
+}
+
+func TestLoadModelsFromDir_TreeValidation(t *testing.T) {
+	prev := Registry
+	t.Cleanup(func() { Registry = prev })
+
+	cases := map[string]string{
+		"no parent_fk": `
+table: nodes
+relations:
+  children:
+    type: tree
+`,
+		"bad direction": `
+table: nodes
+relations:
+  children:
+    type: tree
+    parent_fk: parent_id
+    direction: sideways
+`,
+		"through": `
+table: nodes
+relations:
+  children:
+    type: tree
+    parent_fk: parent_id
+    through: Link
+`,
+		"parent_fk outside tree": `
+table: nodes
+relations:
+  items:
+    type: has_many
+    model: Node
+    parent_fk: parent_id
+`,
+	}
+	for name, src := range cases {
+		dir := t.TempDir()
+		write(t, dir, "Node.yml", src)
+		Registry = map[string]*Model{}
+		if err := LoadModelsFromDir(dir); err == nil || !strings.Contains(err.Error(), "tree") {
+			t.Fatalf("%s: expected tree validation error, got %v", name, err)
+		}
+	}
+}
+
+// Обход дерева идёт только по узлам, видимым по политике модели: скрытый
+// узел обрывает поддерево.
+func TestTreeAppliesPolicy(t *testing.T) {
+	m, preset, aliasMap := treeFixture()
+	m.Policies = &ModelPolicies{Filter: map[string]any{"org_id__eq": "{claims.org_id}"}}
+	rel := m.Relations["subtree"]
+	claims := map[string]any{"org_id": float64(7)}
+
+	sql, args, err := m.BuildTreeQuery(rel, IDList{int64(10)}, 0, claims)
+	if err != nil {
+		t.Fatalf("BuildTreeQuery: %v", err)
+	}
+	for _, want := range []string{
+		"WHERE n.parent_id = ANY($1) AND n.id IN (SELECT main.id FROM departments AS main WHERE (main.org_id = $2))",
+		"WHERE n.id <> ALL(t.path) AND n.id IN (SELECT main.id FROM departments AS main WHERE (main.org_id = $3)))",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in SQL: %s", want, sql)
+		}
+	}
+	if !reflect.DeepEqual(args, []any{[]int64{10}, int64(7), int64(7)}) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+
+	filters := map[string]any{"subtree__descendants_of": float64(10)}
+	sb, err := m.BuildIndexQuery(aliasMap.WithClaims(claims), filters, nil, preset, 0, 0)
+	if err != nil {
+		t.Fatalf("BuildIndexQuery: %v", err)
+	}
+	sql, _, _ = sb.ToSql()
+	if strings.Count(sql, "n.id IN (SELECT main.id FROM departments AS main WHERE (main.org_id = $") != 2 {
+		t.Fatalf("tree filter must walk only visible nodes: %s", sql)
+	}
+	if _, err := m.BuildIndexQuery(aliasMap.WithClaims(nil), filters, nil, preset, 0, 0); err == nil {
+		t.Fatal("tree filter over a policy without claims must fail")
+	}
+}
@@ -13,12 +13,22 @@ type Model struct {
 	Aliases      map[string]string         `yaml:"aliases"`      // short path aliases
 	PrimaryKeys  []string                  `yaml:"primary_keys"` // optional, e.g. ["id"] or ["part1","part2"]
 	Includes     StringList                `yaml:"include"`
+	Policies     *ModelPolicies            `yaml:"policies"`   // row-level фильтр из JWT claims
+	Access       *AccessRule               `yaml:"access"`     // роли, которым доступна модель
+	RateLimit    *RateLimit                `yaml:"rate_limit"` // лимиты запросов на субъекта
+	TimeoutMS    int                       `yaml:"timeout_ms"` // предельное время запроса к модели, мс
+	Search       *SearchConfig             `yaml:"search"`     // полнотекстовый поиск (__fts, q, rank)
 }
 
 // StringList unmarshals either a single string or a list of strings.
+// A key that is present but empty yields an empty non-nil list, so that
+// validation can tell it from a missing key.
 type StringList []string
 
 func (s *StringList) UnmarshalYAML(unmarshal func(any) error) error {
+	if *s == nil {
+		*s = StringList{}
+	}
 	var one string
 	if err := unmarshal(&one); err == nil {
 		for _, p := range strings.Split(one, ",") {
@@ -42,7 +52,7 @@ func (s *StringList) UnmarshalYAML(unmarshal func(any) error) error {
 
 // ModelRelation описывает связь между моделями в конфигурации
 type ModelRelation struct {
-	Type         string `yaml:"type"`          // has_one, has_many, belongs_to
+	Type         string `yaml:"type"`          // has_one, has_many, belongs_to, tree
 	Model        string `yaml:"model"`         // название связанной модели (логическое)
 	Table        string `yaml:"table"`         // имя таблицы в SQL
 	FK           string `yaml:"fk"`            // внешний ключ (обычно fk к текущей модели)
@@ -55,6 +65,10 @@ type ModelRelation struct {
 	MaxDepth     int    `yaml:"max_depth"`     // максимальная глубина рекурсии
 	Polymorphic  bool   `yaml:"polymorphic"`   // belongs_to polymorphic
 	TypeColumn   string `yaml:"type_column"`   // column with type discriminator (default <rel>_type)
+	Limit        int    `yaml:"limit"`         // has_many: строк на одного родителя (0 — без окна)
+	Offset       int    `yaml:"offset"`        // has_many: пропустить строк у каждого родителя
+	ParentFK     string `yaml:"parent_fk"`     // tree: колонка со ссылкой на родительскую строку той же таблицы
+	Direction    string `yaml:"direction"`     // tree: descendants (по умолчанию) или ancestors
 
 	// для runtime (не сериализуется)
 	_ModelRef   *Model `yaml:"-"`
@@ -66,13 +80,70 @@ type ModelRelation struct {
 type AliasMap struct {
 	PathToAlias map[string]string
 	AliasToPath map[string]string
+
+	// claims запроса для политик моделей, читаемых подзапросами и JOIN-ами;
+	// задаются на копии карты через WithClaims, кэш не меняется
+	claims map[string]any
+	// scoped: JOIN-ы получают политики присоединённых моделей. Не задаётся
+	// у карт подзапросов самих политик, чтобы политики не вкладывались
+	// друг в друга бесконечно.
+	scoped bool
+	// строка полнотекстового поиска запроса для сортировки rank: задаётся
+	// резолвером по фильтрам клиента, так что не зависит от того, как
+	// фильтры обёрнуты политикой
+	searchText string
+}
+
+// WithClaims возвращает копию карты с claims запроса: JOIN-ы, кванторы и
+// tree-фильтры применяют по ним политики читаемых моделей. Карты из кэша
+// общие для всех запросов, поэтому claims нельзя записывать в них напрямую.
+func (am *AliasMap) WithClaims(claims map[string]any) *AliasMap {
+	if am == nil {
+		return nil
+	}
+	cp := *am
+	cp.claims = claims
+	cp.scoped = true
+	return &cp
+}
+
+// WithSearchText возвращает копию карты со строкой поиска для сортировки rank.
+func (am *AliasMap) WithSearchText(q string) *AliasMap {
+	if am == nil {
+		return nil
+	}
+	cp := *am
+	cp.searchText = q
+	return &cp
+}
+
+// pathAlias возвращает алиас пути связи; nil-карта путей не знает.
+func (am *AliasMap) pathAlias(path string) (string, bool) {
+	if am == nil {
+		return "", false
+	}
+	alias, ok := am.PathToAlias[path]
+	return alias, ok
+}
+
+// Claims возвращает claims, заданные WithClaims.
+func (am *AliasMap) Claims() map[string]any {
+	if am == nil {
+		return nil
+	}
+	return am.claims
 }
 
 // DataPreset описывает структуру пресета в конфигурации
 type DataPreset struct {
-	Name    string  `yaml:"-"`
-	Extends string  `yaml:"extends" json:"extends"`
-	Fields  []Field `yaml:"fields"` // fields in this preset
+	Name    string      `yaml:"-"`
+	Extends string      `yaml:"extends" json:"extends"`
+	Fields  []Field     `yaml:"fields"` // fields in this preset
+	Access  *AccessRule `yaml:"access"` // роли, которым доступен пресет; наследуется через extends
+	// предельное время запроса с этим пресетом, мс; приоритетнее timeout_ms модели
+	TimeoutMS int `yaml:"timeout_ms"`
+	// корень и хвосты читают один снимок БД (REPEATABLE READ); наследуется через extends
+	Consistent bool `yaml:"consistent"`
 	// Предвычисленная карта алиасов, собранная ТОЛЬКО из полей этого пресета (NestedPreset-поля).
 	// Не включает пути из фильтров/сортировок; неизменяема после инициализации.
 	FieldsAliasMap *AliasMap `yaml:"-" json:"-"`
@@ -80,14 +151,18 @@ type DataPreset struct {
 
 // Preset описывает структуру поля пресета для SQL-запросов
 type Field struct {
-	Source       string `yaml:"source"`    // example: "id"
-	Formatter    string `yaml:"formatter"` // example"{surname} {name}[0].{patrname}[0]."
-	Alias        string `yaml:"alias"`     // optional override
-	Type         string `yaml:"type"`      // "int", "string", "array", "bool"
-	NestedPreset string `yaml:"preset"`    // name of another preset
-	Internal     bool   `yaml:"internal"`  // если true, то поле не будет включено в ответ
-	Localize     bool   `yaml:"localize"`  // если true, то поле будет локализовано
-	MaxDepth     int    `yaml:"max_depth"` // максимальная глубина рекурсии для циклических связей
+	Source       string     `yaml:"source"`     // example: "id"
+	Formatter    string     `yaml:"formatter"`  // example"{surname} {name}[0].{patrname}[0]."
+	Alias        string     `yaml:"alias"`      // optional override
+	Type         string     `yaml:"type"`       // "int", "string", "array", "bool"
+	NestedPreset string     `yaml:"preset"`     // name of another preset
+	Internal     bool       `yaml:"internal"`   // если true, то поле не будет включено в ответ
+	Localize     bool       `yaml:"localize"`   // если true, то поле будет локализовано
+	MaxDepth     int        `yaml:"max_depth"`  // максимальная глубина рекурсии для циклических связей
+	VisibleTo    StringList `yaml:"visible_to"` // роли, которым видно значение; остальным поле скрывается
+	Mask         string     `yaml:"mask"`       // вместо удаления скрытое значение заменяется этой строкой
+	Limit        int        `yaml:"limit"`      // has_many: строк на одного родителя, приоритетнее limit связи
+	Offset       int        `yaml:"offset"`     // has_many: пропустить строк у каждого родителя
 	// для runtime (не сериализуется)
 	_PresetRef *DataPreset `yaml:"-"`
 }
@@ -119,6 +194,7 @@ type JoinSpec struct {
 	Distinct   bool
 	Conditions []string
 	Where      string
+	WhereArgs  []any // параметры Where (политика присоединённой модели)
 }
 
 // GetPrimaryKeys возвращает список полей первичного ключа для модели.
@@ -15,6 +15,30 @@ var allowedModelKeys = map[string]bool{
 	"computable":   true,
 	"aggregatable": true,
 	"aliases":      true,
+	"policies":     true,
+	"access":       true,
+	"rate_limit":   true,
+	"timeout_ms":   true,
+	"search":       true,
+}
+
+var allowedAccessKeys = map[string]bool{
+	"roles": true,
+}
+
+var allowedRateLimitKeys = map[string]bool{
+	"rps":         true,
+	"burst":       true,
+	"concurrency": true,
+}
+
+var allowedSearchKeys = map[string]bool{
+	"config": true,
+	"fields": true,
+}
+
+var allowedPoliciesKeys = map[string]bool{
+	"filter": true,
 }
 
 var allowedRelationKeys = map[string]bool{
@@ -31,23 +55,34 @@ var allowedRelationKeys = map[string]bool{
 	"max_depth":     true,
 	"polymorphic":   true,
 	"type_column":   true,
+	"limit":         true,
+	"offset":        true,
+	"parent_fk":     true,
+	"direction":     true,
 }
 
 var allowedPresetKeys = map[string]bool{
-	"extends": true,
-	"fields":  true,
+	"extends":    true,
+	"fields":     true,
+	"access":     true,
+	"timeout_ms": true,
+	"consistent": true,
 }
 
 var allowedFieldKeys = map[string]bool{
-	"source":    true,
-	"type":      true,
-	"alias":     true,
-	"preset":    true,
-	"where":     true,
-	"internal":  true,
-	"formatter": true,
-	"localize":  true,
-	"max_depth": true,
+	"source":     true,
+	"type":       true,
+	"alias":      true,
+	"preset":     true,
+	"where":      true,
+	"internal":   true,
+	"formatter":  true,
+	"localize":   true,
+	"max_depth":  true,
+	"visible_to": true,
+	"mask":       true,
+	"limit":      true,
+	"offset":     true,
 }
 
 var allowedComputableKeys = map[string]bool{
@@ -101,6 +136,14 @@ func validateYAMLNode(node *yaml.Node, context string) error {
 			allowedKeys = allowedComputableKeys
 		case "aggregatable-entry":
 			allowedKeys = allowedAggregatableKeys
+		case "policies":
+			allowedKeys = allowedPoliciesKeys
+		case "access":
+			allowedKeys = allowedAccessKeys
+		case "rate_limit":
+			allowedKeys = allowedRateLimitKeys
+		case "search":
+			allowedKeys = allowedSearchKeys
 		case "aliases-map":
 			allowedKeys = nil
 		default:
@@ -147,6 +190,16 @@ func validateYAMLNode(node *yaml.Node, context string) error {
 				nextContext = "aggregatable-entry"
 			} else if context == "model" && key == "aliases" {
 				nextContext = "aliases-map"
+			} else if context == "model" && key == "policies" {
+				nextContext = "policies"
+			} else if (context == "model" || context == "preset") && key == "access" {
+				nextContext = "access"
+			} else if context == "model" && key == "rate_limit" {
+				nextContext = "rate_limit"
+			} else if context == "model" && key == "search" {
+				nextContext = "search"
+			} else if context == "policies" {
+				nextContext = "policy-filter" // свободная форма фильтров /api/index
 			} else {
 				nextContext = context
 			}
@@ -0,0 +1,333 @@
+package openapi
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"YrestAPI/internal/model"
+)
+
+// filterPath — путь, допустимый в filters/sorts, и тип его значения.
+type filterPath struct {
+	Path string
+	Type string
+	Many bool // путь идёт через has_many (не годится для unique_by)
+}
+
+var columnName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ownColumns — поля самой модели, известные из YAML: колонки пресетов,
+// computable, первичные ключи и FK связей belongs_to.
+func ownColumns(m *model.Model) map[string]string {
+	out := map[string]string{}
+	add := func(name, typ string) {
+		if _, ok := out[name]; !ok && columnName.MatchString(name) {
+			out[name] = typ
+		}
+	}
+	for _, pn := range sortedPresetNames(m) {
+		for _, f := range m.Presets[pn].Fields {
+			switch f.Type {
+			case "int", "string", "bool", "float", "UUID", "time", "datetime", "date":
+				add(f.Source, f.Type)
+			}
+		}
+	}
+	for name, comp := range m.Computable {
+		if comp != nil {
+			add(name, comp.Type)
+		}
+	}
+	for _, pk := range m.GetPrimaryKeys() {
+		add(pk, "int")
+	}
+	for relName, rel := range m.Relations {
+		if rel == nil || rel.Type != "belongs_to" || strings.TrimSpace(rel.FK) == "" {
+			continue
+		}
+		add(rel.FK, "int")
+		if rel.Polymorphic {
+			add(typeColumn(relName, rel), "string")
+		}
+	}
+	return out
+}
+
+// filterPaths перечисляет пути фильтрации модели: свои поля, поля моделей
+// прямых (не полиморфных) связей и алиасы модели. Более глубокие пути
+// и составные ключи _or_/_and_ тоже работают, но не перечисляются.
+func filterPaths(m *model.Model) []filterPath {
+	seen := map[string]bool{}
+	var out []filterPath
+	add := func(path, typ string, many bool) {
+		if !seen[path] {
+			seen[path] = true
+			out = append(out, filterPath{Path: path, Type: typ, Many: many})
+		}
+	}
+	for name, typ := range ownColumns(m) {
+		add(name, typ, false)
+	}
+	for relName, rel := range m.Relations {
+		if rel == nil || rel.Polymorphic || rel.Type == "tree" || rel.GetModelRef() == nil || !columnName.MatchString(relName) {
+			continue
+		}
+		for name, typ := range ownColumns(rel.GetModelRef()) {
+			add(relName+"."+name, typ, rel.Type == "has_many")
+		}
+	}
+	for alias := range m.Aliases {
+		if typ, many, ok := resolvePathType(m, model.ExpandAliasPath(m, alias)); ok {
+			add(alias, typ, many)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+func resolvePathType(m *model.Model, path string) (typ string, many bool, ok bool) {
+	segs := strings.Split(strings.TrimSpace(path), ".")
+	cur := m
+	for _, seg := range segs[:len(segs)-1] {
+		rel := cur.Relations[seg]
+		if rel == nil || rel.Polymorphic || rel.GetModelRef() == nil {
+			return "", false, false
+		}
+		many = many || rel.Type == "has_many"
+		cur = rel.GetModelRef()
+	}
+	typ, ok = ownColumns(cur)[segs[len(segs)-1]]
+	return typ, many, ok
+}
+
+// operatorsFor — операторы фильтра, осмысленные для типа поля.
+func operatorsFor(typ string) []string {
+	nullOps := []string{"null", "is_null", "not_null"}
+	switch typ {
+	case "int", "float", "date", "datetime", "time":
+		return append([]string{"eq", "in", "lt", "lte", "gt", "gte"}, nullOps...)
+	case "bool", "UUID":
+		return append([]string{"eq", "in"}, nullOps...)
+	default:
+		return append([]string{
+			"eq", "eq_cs", "in", "lt", "lte", "gt", "gte",
+			"cnt", "cnt_cs", "not_cnt", "not_cnt_cs",
+			"start", "start_cs", "end", "end_cs", "fts",
+		}, nullOps...)
+	}
+}
+
+func valueSchema(typ, op string) map[string]any {
+	switch op {
+	case "null", "is_null", "not_null":
+		return map[string]any{"type": "boolean"}
+	}
+	s := scalarSchema(typ)
+	delete(s, "nullable")
+	if op == "in" {
+		return map[string]any{"type": "array", "items": s}
+	}
+	return s
+}
+
+func filtersSchema(modelName string, paths []filterPath) map[string]any {
+	props := map[string]any{}
+	for _, p := range paths {
+		props[p.Path] = valueSchema(p.Type, "eq")
+		for _, op := range operatorsFor(p.Type) {
+			props[p.Path+"__"+op] = valueSchema(p.Type, op)
+		}
+	}
+	group := map[string]any{"oneOf": []any{
+		ref(modelName + ".Filters"),
+		map[string]any{"type": "array", "items": ref(modelName + ".Filters")},
+	}}
+	props["or"] = group
+	props["and"] = group
+	return map[string]any{
+		"type":                 "object",
+		"description":          "Keys are <path>__<operator>. Deeper relation paths and composite _or_/_and_ paths are accepted but not listed.",
+		"properties":           props,
+		"additionalProperties": true,
+	}
+}
+
+// addRelationFilters добавляет фильтры по связям целиком: кванторы
+// <связь>__any / __none / __all (объект фильтров дочерней модели) и
+// <связь>__count_* для has_one/has_many, а также <связь>__descendants_of /
+// __ancestors_of (один ключ узла или массив ключей) для tree-связей.
+func addRelationFilters(schema map[string]any, m *model.Model, registry map[string]*model.Model) {
+	props, _ := schema["properties"].(map[string]any)
+	if props == nil {
+		return
+	}
+	for relName, rel := range m.Relations {
+		if rel == nil || rel.Polymorphic || !columnName.MatchString(relName) {
+			continue
+		}
+		switch rel.Type {
+		case "has_one", "has_many":
+			target := rel.GetModelRef()
+			if target == nil || registry[target.Name] == nil {
+				continue
+			}
+			for _, op := range []string{"any", "none", "all"} {
+				props[relName+"__"+op] = ref(target.Name + ".Filters")
+			}
+			for _, op := range []string{"count_eq", "count_gt", "count_gte", "count_lt", "count_lte"} {
+				props[relName+"__"+op] = map[string]any{"type": "integer", "minimum": 0}
+			}
+		case "tree":
+			typ, ok := ownColumns(m)[rel.PK]
+			if !ok {
+				typ = "int"
+			}
+			item := scalarSchema(typ)
+			delete(item, "nullable")
+			for _, op := range []string{"descendants_of", "ancestors_of"} {
+				props[relName+"__"+op] = map[string]any{"oneOf": []any{
+					item,
+					map[string]any{"type": "array", "items": item},
+				}}
+			}
+		}
+	}
+}
+
+// addSearchFilter добавляет "__fts" — поиск по полям search модели.
+func addSearchFilter(schema map[string]any, m *model.Model) {
+	props, _ := schema["properties"].(map[string]any)
+	if props == nil || m.Search == nil {
+		return
+	}
+	props[model.SearchFilterKey] = map[string]any{"type": "string", "description": "websearch_to_tsquery text"}
+}
+
+// uniqueBySchema — пути для unique_by: без has_many.
+func uniqueBySchema(paths []filterPath) map[string]any {
+	var out []string
+	for _, p := range paths {
+		if !p.Many {
+			out = append(out, p.Path)
+		}
+	}
+	return stringEnum(out)
+}
+
+func stringEnum(values []string) map[string]any {
+	s := map[string]any{"type": "string"}
+	if len(values) > 0 {
+		s["enum"] = values
+	}
+	return s
+}
+
+func sortsSchema(paths []filterPath, rank bool) map[string]any {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = regexp.QuoteMeta(p.Path)
+	}
+	if rank {
+		quoted = append(quoted, model.RankSortKey)
+	}
+	return map[string]any{
+		"type": "array",
+		"items": map[string]any{
+			"type":    "string",
+			"pattern": "^(" + strings.Join(quoted, "|") + ")( +(ASC|DESC|asc|desc))?$",
+		},
+	}
+}
+
+func indexRequestSchema(m *model.Model, modelName string, presets []string, paths []filterPath) map[string]any {
+	nonNegative := map[string]any{"type": "integer", "minimum": 0}
+	props := map[string]any{
+		"model":      map[string]any{"type": "string", "enum": []string{modelName}},
+		"preset":     stringEnum(presets),
+		"filters":    ref(modelName + ".Filters"),
+		"sorts":      sortsSchema(paths, m.Search != nil),
+		"offset":     nonNegative,
+		"limit":      nonNegative,
+		"cursor":     map[string]any{"type": "boolean"},
+		"after":      map[string]any{"type": "string"},
+		"envelope":   map[string]any{"type": "boolean"},
+		"format":     map[string]any{"type": "string", "enum": []string{"csv", "xlsx", "json"}},
+		"explode":    map[string]any{"type": "boolean"},
+		"consistent": map[string]any{"type": "boolean"},
+		"unique_by":  uniqueBySchema(paths),
+	}
+	if m.Search != nil {
+		props["q"] = map[string]any{"type": "string", "description": "Full-text search over the model's search fields"}
+	}
+	return map[string]any{
+		"type":       "object",
+		"required":   []string{"model"},
+		"properties": props,
+	}
+}
+
+func showRequestSchema(m *model.Model, modelName string, presets []string) map[string]any {
+	cols := ownColumns(m)
+	pks := m.GetPrimaryKeys()
+	var id map[string]any
+	if len(pks) == 1 {
+		id = valueSchema(cols[pks[0]], "eq")
+	} else {
+		props := map[string]any{}
+		for _, pk := range pks {
+			props[pk] = valueSchema(cols[pk], "eq")
+		}
+		id = map[string]any{"type": "object", "properties": props, "required": pks}
+	}
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"model", "preset", "id"},
+		"properties": map[string]any{
+			"model":      map[string]any{"type": "string", "enum": []string{modelName}},
+			"preset":     stringEnum(presets),
+			"id":         id,
+			"consistent": map[string]any{"type": "boolean"},
+		},
+	}
+}
+
+func statsRequestSchema(m *model.Model, modelName string, paths []filterPath) map[string]any {
+	props := map[string]any{
+		"model":     map[string]any{"type": "string", "enum": []string{modelName}},
+		"preset":    map[string]any{"type": "string"},
+		"filters":   ref(modelName + ".Filters"),
+		"unique_by": uniqueBySchema(paths),
+	}
+	if len(m.Aggregatable) > 0 {
+		fields := make([]string, 0, len(m.Aggregatable))
+		fnSet := map[string]bool{}
+		for name, agg := range m.Aggregatable {
+			if agg == nil {
+				continue
+			}
+			fields = append(fields, name)
+			for _, fn := range agg.Functions {
+				fnSet[strings.ToLower(strings.TrimSpace(fn))] = true
+			}
+		}
+		sort.Strings(fields)
+		fns := make([]string, 0, len(fnSet))
+		for fn := range fnSet {
+			fns = append(fns, fn)
+		}
+		sort.Strings(fns)
+		props["aggregates"] = map[string]any{
+			"type": "object",
+			"additionalProperties": map[string]any{
+				"type":     "object",
+				"required": []string{"fn", "field"},
+				"properties": map[string]any{
+					"fn":    map[string]any{"type": "string", "enum": fns},
+					"field": map[string]any{"type": "string", "enum": fields},
+				},
+			},
+		}
+	}
+	return map[string]any{"type": "object", "required": []string{"model"}, "properties": props}
+}
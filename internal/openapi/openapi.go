@@ -0,0 +1,272 @@
+package openapi
+
+import (
+	"sort"
+	"strings"
+
+	"YrestAPI/internal/model"
+)
+
+// Options влияет на части документа, которые зависят от конфигурации сервиса.
+type Options struct {
+	BearerAuth bool // AUTH_ENABLED: все /api/* требуют Authorization: Bearer
+	APIKeyAuth bool // AUTH_API_KEYS_*: принимается заголовок X-API-Key
+}
+
+// Build генерирует OpenAPI 3.0 документ из реестра:
+//   - схема ответа на каждую пару модель+пресет ("Person.item"),
+//   - схема фильтров на каждую модель ("Person.Filters") с допустимыми путями и операторами,
+//   - схемы запросов /api/index, /api/show, /api/stats на каждую модель.
+func Build(registry map[string]*model.Model, opts Options) map[string]any {
+	names := make([]string, 0, len(registry))
+	for name, m := range registry {
+		if m != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	schemas := map[string]any{}
+	var indexRequests, showRequests, statsRequests, presetRefs []any
+	for _, name := range names {
+		m := registry[name]
+		presetNames := sortedPresetNames(m)
+		for _, pn := range presetNames {
+			schemas[presetSchemaName(name, pn)] = presetSchema(m, m.Presets[pn])
+			presetRefs = append(presetRefs, ref(presetSchemaName(name, pn)))
+		}
+
+		paths := filterPaths(m)
+		schemas[name+".Filters"] = filtersSchema(name, paths)
+		addRelationFilters(schemas[name+".Filters"].(map[string]any), m, registry)
+		addSearchFilter(schemas[name+".Filters"].(map[string]any), m)
+		schemas[name+".IndexRequest"] = indexRequestSchema(m, name, presetNames, paths)
+		schemas[name+".ShowRequest"] = showRequestSchema(m, name, presetNames)
+		schemas[name+".StatsRequest"] = statsRequestSchema(m, name, paths)
+		indexRequests = append(indexRequests, ref(name+".IndexRequest"))
+		showRequests = append(showRequests, ref(name+".ShowRequest"))
+		statsRequests = append(statsRequests, ref(name+".StatsRequest"))
+	}
+	schemas["Error"] = map[string]any{"type": "string", "description": "Plain-text error message"}
+
+	components := map[string]any{"schemas": schemas}
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "YrestAPI",
+			"version":     "1.0",
+			"description": "Generated from the loaded model registry. Response schemas are named <Model>.<preset>.",
+		},
+		"paths": map[string]any{
+			"/api/index": operation("List records shaped by a preset", indexRequests, map[string]any{
+				"type":  "array",
+				"items": oneOf(presetRefs),
+			}),
+			"/api/show": operation("Fetch one record by primary key", showRequests, oneOf(presetRefs)),
+			"/api/stats": operation("Count records (and aggregates) for the same filters", statsRequests, map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"count": map[string]any{"type": "integer", "format": "int64"}},
+				"required":             []string{"count"},
+				"additionalProperties": true,
+			}),
+		},
+		"components": components,
+	}
+	schemes := map[string]any{}
+	security := []any{}
+	if opts.BearerAuth {
+		schemes["bearerAuth"] = map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}
+		security = append(security, map[string]any{"bearerAuth": []string{}})
+	}
+	if opts.APIKeyAuth {
+		schemes["apiKeyAuth"] = map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"}
+		security = append(security, map[string]any{"apiKeyAuth": []string{}})
+	}
+	if len(schemes) > 0 {
+		// любая из схем: требования в security объединяются через OR
+		components["securitySchemes"] = schemes
+		doc["security"] = security
+	}
+	return doc
+}
+
+func presetSchemaName(modelName, presetName string) string {
+	return modelName + "." + presetName
+}
+
+func sortedPresetNames(m *model.Model) []string {
+	out := make([]string, 0, len(m.Presets))
+	for name, p := range m.Presets {
+		if p != nil {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func oneOf(refs []any) map[string]any {
+	if len(refs) == 0 {
+		return map[string]any{"type": "object"}
+	}
+	return map[string]any{"oneOf": refs}
+}
+
+func operation(summary string, requests []any, response map[string]any) map[string]any {
+	errorResponse := func(desc string) map[string]any {
+		return map[string]any{
+			"description": desc,
+			"content":     map[string]any{"text/plain": map[string]any{"schema": ref("Error")}},
+		}
+	}
+	return map[string]any{
+		"post": map[string]any{
+			"summary": summary,
+			"requestBody": map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": oneOf(requests)}},
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{"application/json": map[string]any{"schema": response}},
+				},
+				"400": errorResponse("Invalid request"),
+				"404": errorResponse("Model or record not found"),
+				"500": errorResponse("Resolver error"),
+			},
+		},
+	}
+}
+
+// presetSchema описывает элемент ответа для пресета: ключи — alias/source
+// не-internal полей, вложенные пресеты — ссылки на схемы связанных моделей.
+func presetSchema(m *model.Model, p *model.DataPreset) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	for _, f := range p.Fields {
+		if f.Internal {
+			continue
+		}
+		key := f.Alias
+		if strings.TrimSpace(key) == "" {
+			key = f.Source
+		}
+		var s map[string]any
+		switch f.Type {
+		case "preset":
+			s = relationSchema(m, f)
+		case "formatter":
+			s = map[string]any{"type": "string"}
+		case "nested_field":
+			s = map[string]any{"nullable": true, "description": "Copied from nested data: " + f.Source}
+		case "computable":
+			typ := f.Type
+			if comp := m.Computable[f.Source]; comp != nil && strings.TrimSpace(comp.Type) != "" {
+				typ = comp.Type
+			}
+			s = scalarSchema(typ)
+		default:
+			s = scalarSchema(f.Type)
+		}
+		if s == nil {
+			continue
+		}
+		if f.Localize {
+			// без перевода значение остаётся исходным
+			s = map[string]any{"anyOf": []any{map[string]any{"type": "string"}, s}, "nullable": true}
+		}
+		if _, dup := props[key]; !dup {
+			required = append(required, key)
+		}
+		props[key] = s
+	}
+	out := map[string]any{
+		"type":       "object",
+		"properties": props,
+		"x-preset":   p.Name,
+	}
+	if m.Name != "" {
+		out["x-model"] = m.Name
+	}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+func relationSchema(m *model.Model, f model.Field) map[string]any {
+	rel := m.Relations[f.Source]
+	if rel == nil {
+		return nil
+	}
+	many := rel.Type == "has_many" || rel.Type == "tree"
+	wrap := func(item map[string]any) map[string]any {
+		if many {
+			return map[string]any{"type": "array", "items": item}
+		}
+		if _, isRef := item["$ref"]; isRef {
+			return map[string]any{"nullable": true, "allOf": []any{item}}
+		}
+		item["nullable"] = true
+		return item
+	}
+	if strings.TrimSpace(f.Formatter) != "" {
+		return wrap(map[string]any{"type": "string"})
+	}
+	if rel.Polymorphic {
+		return wrap(map[string]any{
+			"type":                 "object",
+			"additionalProperties": true,
+			"description":          "Polymorphic: shaped by preset " + f.NestedPreset + " of the model named in " + typeColumn(f.Source, rel),
+		})
+	}
+	target := rel.GetModelRef()
+	if target == nil {
+		return nil
+	}
+	nested := f.GetPresetRef()
+	if nested == nil {
+		nested = target.Presets[f.NestedPreset]
+	}
+	if nested == nil {
+		return wrap(map[string]any{"type": "object", "additionalProperties": true})
+	}
+	return wrap(ref(presetSchemaName(target.Name, nested.Name)))
+}
+
+func typeColumn(relName string, rel *model.ModelRelation) string {
+	if strings.TrimSpace(rel.TypeColumn) != "" {
+		return rel.TypeColumn
+	}
+	return relName + "_type"
+}
+
+// scalarSchema отображает Field.Type в JSON Schema. Все колонки nullable.
+func scalarSchema(t string) map[string]any {
+	var s map[string]any
+	switch t {
+	case "int":
+		s = map[string]any{"type": "integer", "format": "int64"}
+	case "float":
+		s = map[string]any{"type": "number"}
+	case "bool":
+		s = map[string]any{"type": "boolean"}
+	case "UUID":
+		s = map[string]any{"type": "string", "format": "uuid"}
+	case "date":
+		s = map[string]any{"type": "string", "format": "date"}
+	case "datetime":
+		s = map[string]any{"type": "string", "format": "date-time"}
+	case "string", "time":
+		s = map[string]any{"type": "string"}
+	default:
+		s = map[string]any{}
+	}
+	s["nullable"] = true
+	return s
+}
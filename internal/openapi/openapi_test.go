@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+func loadTestRegistry(t *testing.T) map[string]*model.Model {
+	t.Helper()
+	if err := model.InitRegistry("../../test_db"); err != nil {
+		t.Fatalf("init registry: %v", err)
+	}
+	return model.Registry
+}
+
+func schemaOf(t *testing.T, doc map[string]any, name string) map[string]any {
+	t.Helper()
+	s, ok := doc["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+	if !ok {
+		t.Fatalf("schema %s missing", name)
+	}
+	return s
+}
+
+func TestBuildPresetSchemas(t *testing.T) {
+	doc := Build(loadTestRegistry(t), Options{})
+	if doc["openapi"] != "3.0.3" {
+		t.Fatalf("unexpected version: %v", doc["openapi"])
+	}
+
+	item := schemaOf(t, doc, "Person.item")
+	props := item["properties"].(map[string]any)
+	if _, leaked := props["first_name"]; leaked {
+		t.Fatal("internal field must not be in the schema")
+	}
+	if !reflect.DeepEqual(props["id"], map[string]any{"type": "integer", "format": "int64", "nullable": true}) {
+		t.Fatalf("unexpected id schema: %v", props["id"])
+	}
+	if props["full_name"].(map[string]any)["type"] != "string" {
+		t.Fatalf("formatter must be a string: %v", props["full_name"])
+	}
+	if !reflect.DeepEqual(item["required"], []string{"id", "last_name", "full_name"}) {
+		t.Fatalf("unexpected required: %v", item["required"])
+	}
+
+	withContacts := schemaOf(t, doc, "Person.with_contacts")["properties"].(map[string]any)
+	want := map[string]any{"nullable": true, "allOf": []any{map[string]any{"$ref": "#/components/schemas/Contact.item"}}}
+	if !reflect.DeepEqual(withContacts["email"], want) {
+		t.Fatalf("has_one must be a nullable ref: %v", withContacts["email"])
+	}
+
+	// все $ref указывают на существующие схемы
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	for _, m := range regexp.MustCompile(`"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(raw), -1) {
+		if _, ok := schemas[m[1]]; !ok {
+			t.Fatalf("dangling $ref %s", m[1])
+		}
+	}
+}
+
+func TestHasManyIsArray(t *testing.T) {
+	doc := Build(loadTestRegistry(t), Options{})
+	persons := schemaOf(t, doc, "Project.with_members")["properties"].(map[string]any)["persons"]
+	want := map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/Person.item"}}
+	if !reflect.DeepEqual(persons, want) {
+		t.Fatalf("has_many must be an array of refs: %v", persons)
+	}
+}
+
+func TestFilterAndRequestSchemas(t *testing.T) {
+	reg := loadTestRegistry(t)
+	doc := Build(reg, Options{BearerAuth: true})
+
+	filters := schemaOf(t, doc, "Person.Filters")["properties"].(map[string]any)
+	for _, key := range []string{"last_name", "last_name__cnt", "last_name__not_cnt_cs", "id__in", "id__gte", "contacts.kind__eq", "or", "and"} {
+		if _, ok := filters[key]; !ok {
+			t.Fatalf("filter key %s missing", key)
+		}
+	}
+	if _, ok := filters["id__cnt"]; ok {
+		t.Fatal("substring operators must not be listed for int fields")
+	}
+	if !reflect.DeepEqual(filters["id__in"], map[string]any{"type": "array", "items": map[string]any{"type": "integer", "format": "int64"}}) {
+		t.Fatalf("unexpected __in schema: %v", filters["id__in"])
+	}
+	if q, ok := filters["contacts__any"].(map[string]any); !ok || q["$ref"] == nil {
+		t.Fatalf("has_many quantifier must reference the child filters: %v", filters["contacts__any"])
+	}
+	if _, ok := filters["contacts__count_gte"]; !ok {
+		t.Fatal("has_many count filter missing")
+	}
+	deptFilters := schemaOf(t, doc, "Department.Filters")["properties"].(map[string]any)
+	if _, ok := deptFilters["subtree__descendants_of"]; !ok {
+		t.Fatal("tree filter missing")
+	}
+
+	req := schemaOf(t, doc, "Person.IndexRequest")["properties"].(map[string]any)
+	presets := req["preset"].(map[string]any)["enum"].([]string)
+	if len(presets) != len(reg["Person"].Presets) {
+		t.Fatalf("preset enum mismatch: %v", presets)
+	}
+	pattern := regexp.MustCompile(req["sorts"].(map[string]any)["items"].(map[string]any)["pattern"].(string))
+	if !pattern.MatchString("last_name DESC") || !pattern.MatchString("contacts.kind") || pattern.MatchString("last_name; DROP") {
+		t.Fatalf("unexpected sorts pattern: %s", pattern)
+	}
+	if !pattern.MatchString("rank DESC") || req["q"] == nil {
+		t.Fatalf("search request fields missing: %s %v", pattern, req["q"])
+	}
+	personFilters := schemaOf(t, doc, "Person.Filters")["properties"].(map[string]any)
+	if personFilters["__fts"] == nil || personFilters["last_name__fts"] == nil {
+		t.Fatal("full-text search filters missing")
+	}
+	for _, p := range req["unique_by"].(map[string]any)["enum"].([]string) {
+		if strings.HasPrefix(p, "contacts.") {
+			t.Fatalf("unique_by must not traverse has_many: %s", p)
+		}
+	}
+
+	if doc["security"] == nil || doc["components"].(map[string]any)["securitySchemes"] == nil {
+		t.Fatal("bearer security scheme missing")
+	}
+}
@@ -75,6 +75,22 @@ func MakeThroughChildRequest(
 	nestedPreset string, // пресет конечной модели (напр. "item")
 	parentIDs []any, // список PK родителя из главного селекта
 ) (IndexRequest, error) {
+	return makeThroughChildRequest(model.CurrentRegistry(), parent, rel, nestedPreset, nil, parentIDs, 0, 0)
+}
+
+// makeThroughChildRequest — как MakeThroughChildRequest, но nested (если не nil)
+// используется вместо поиска пресета по имени в конечной модели, а limit > 0
+// ограничивает строки каждого родителя окном (offset, offset+limit].
+// Имя промежуточной модели ищется в реестре запроса reg.
+func makeThroughChildRequest(
+	reg map[string]*model.Model,
+	parent *model.Model,
+	rel *model.ModelRelation,
+	nestedPreset string,
+	nested *model.DataPreset,
+	parentIDs []any,
+	offset, limit uint64,
+) (IndexRequest, error) {
 
 	through := rel.GetThroughRef() // промежуточная модель (напр. ProjectMember / PersonContact)
 	final := rel.GetModelRef()     // конечная модель (напр. Person / Contact)
@@ -103,7 +119,9 @@ func MakeThroughChildRequest(
 	}
 
 	// найти пресет конечной модели и положить указатель
-	nested := final.Presets[nestedPreset]
+	if nested == nil {
+		nested = final.Presets[nestedPreset]
+	}
 	if nested == nil {
 		return IndexRequest{}, fmt.Errorf("nested preset %q not found in model %q",
 			nestedPreset, final.Table)
@@ -134,7 +152,7 @@ func MakeThroughChildRequest(
 
 	// логическое имя промежуточной модели
 	throughModelName := ""
-	for name, ptr := range model.Registry {
+	for name, ptr := range reg {
 		if ptr == through {
 			throughModelName = name
 			break
@@ -148,12 +166,15 @@ func MakeThroughChildRequest(
 		Model:       throughModelName,
 		Preset:      "",
 		PresetObj:   synthetic,
-		Filters:     map[string]any{fk + "__in": parentIDs},
+		Filters:     map[string]any{fk + "__in": model.IDList(parentIDs)},
 		Sorts:       buildOrderSorts(rel.Order, unwrapKey+"."),
 		Offset:      0,
 		Limit:       maxLimit,
 		UnwrapField: unwrapKey, // просим развернуть контейнер до конечной модели
 	}
+	if limit > 0 {
+		req.Offset, req.Limit, req.PartitionBy = offset, limit, fk
+	}
 
 	// through_where (фильтр на промежуточной)
 	if strings.TrimSpace(rel.ThroughWhere) != "" {
@@ -0,0 +1,33 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/model"
+)
+
+// Authorize проверяет access модели и пресета (со всеми вложенными пресетами)
+// по ролям из JWT контекста. Вызывается до построения SQL; preset == nil —
+// проверяется только модель. Ошибка — *model.AccessError (403).
+func Authorize(ctx context.Context, m *model.Model, preset *model.DataPreset) error {
+	return model.CheckAccess(m, preset, auth.RolesFromContext(ctx))
+}
+
+// AuthorizeFields отклоняет фильтры клиента и поля сортировки, unique_by
+// или агрегатов, которые ссылаются на поля, скрытые visible_to от ролей
+// вызывающего. Фильтры политики сюда не передаются: они задаются сервером.
+func AuthorizeFields(ctx context.Context, m *model.Model, filters map[string]any, fields ...string) error {
+	return model.CheckFieldVisibility(m, auth.RolesFromContext(ctx), filters, fields...)
+}
+
+// presetNotFound — ошибка для неизвестного пресета. У моделей с access она
+// совпадает с отказом в доступе, чтобы по ответу нельзя было проверить,
+// существует ли скрытый пресет.
+func presetNotFound(m *model.Model, modelName, presetName string) error {
+	if m.UsesAccess() {
+		return &model.AccessError{}
+	}
+	return fmt.Errorf("preset not found: %s.%s", modelName, presetName)
+}
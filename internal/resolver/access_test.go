@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/model"
+)
+
+// Отказ должен случаться до SQL: db.Pool в тестах не инициализирован,
+// и любой запрос к базе завершился бы паникой.
+func TestResolveDeniesRestrictedPresetBeforeSQL(t *testing.T) {
+	origRegistry := model.Registry
+	t.Cleanup(func() { model.Registry = origRegistry })
+	model.Registry = map[string]*model.Model{
+		"Person": {
+			Name:  "Person",
+			Table: "people",
+			Presets: map[string]*model.DataPreset{
+				"item": {Name: "item", Fields: []model.Field{{Source: "id", Type: "int"}}},
+				"salary": {
+					Name:   "salary",
+					Access: &model.AccessRule{Roles: model.StringList{"hr"}},
+					Fields: []model.Field{{Source: "salary", Type: "float"}},
+				},
+			},
+		},
+	}
+	ctx := auth.WithClaims(context.Background(), map[string]any{"roles": []any{"staff"}})
+
+	var denied, unknown *model.AccessError
+	_, err := ResolvePage(ctx, IndexRequest{Model: "Person", Preset: "salary"})
+	if !errors.As(err, &denied) {
+		t.Fatalf("expected AccessError for hidden preset, got %v", err)
+	}
+	_, err = ResolvePage(ctx, IndexRequest{Model: "Person", Preset: "missing"})
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected AccessError for unknown preset, got %v", err)
+	}
+	if denied.Error() != unknown.Error() {
+		t.Fatalf("hidden and unknown presets must look the same: %q vs %q", denied.Error(), unknown.Error())
+	}
+	if _, err := ResolveCount(ctx, IndexRequest{Model: "Person", Preset: "salary"}); !errors.As(err, &denied) {
+		t.Fatalf("expected AccessError from ResolveCount, got %v", err)
+	}
+}
+
+// Фильтр, сортировка и unique_by по полю, скрытому visible_to, отклоняются
+// до SQL: иначе значение подбиралось бы по ответам.
+func TestResolveDeniesRedactedFieldsBeforeSQL(t *testing.T) {
+	origRegistry := model.Registry
+	t.Cleanup(func() { model.Registry = origRegistry })
+	model.Registry = map[string]*model.Model{
+		"Person": {
+			Name:  "Person",
+			Table: "people",
+			Presets: map[string]*model.DataPreset{
+				"item": {Name: "item", Fields: []model.Field{
+					{Source: "id", Type: "int"},
+					{Source: "salary", Type: "float", VisibleTo: model.StringList{"hr"}, Mask: "***"},
+				}},
+			},
+		},
+	}
+	ctx := auth.WithClaims(context.Background(), map[string]any{"roles": []any{"staff"}})
+
+	var denied *model.AccessError
+	if _, err := ResolvePage(ctx, IndexRequest{Model: "Person", Preset: "item", Filters: map[string]any{"salary__gt": 1000}}); !errors.As(err, &denied) {
+		t.Fatalf("filter: expected AccessError, got %v", err)
+	}
+	if _, err := ResolvePage(ctx, IndexRequest{Model: "Person", Preset: "item", Sorts: []string{"salary DESC"}}); !errors.As(err, &denied) {
+		t.Fatalf("sort: expected AccessError, got %v", err)
+	}
+	if _, err := ResolveDistinctValues(ctx, IndexRequest{Model: "Person", UniqueBy: "salary"}); !errors.As(err, &denied) {
+		t.Fatalf("unique_by: expected AccessError, got %v", err)
+	}
+	if _, err := ResolveCount(ctx, IndexRequest{Model: "Person", UniqueBy: "salary"}); !errors.As(err, &denied) {
+		t.Fatalf("unique_by count: expected AccessError, got %v", err)
+	}
+	if _, err := ResolveCount(ctx, IndexRequest{Model: "Person", Preset: "item", Filters: map[string]any{"salary__gt": 1000}}); !errors.As(err, &denied) {
+		t.Fatalf("count filter: expected AccessError, got %v", err)
+	}
+}
@@ -49,7 +49,7 @@ func TestFinalizeItems_BelongsToAllNilToNull(t *testing.T) {
 		},
 	}
 
-	if err := finalizeItems(stageModel, stageModel.Presets["list"], items); err != nil {
+	if err := finalizeItems(stageModel, stageModel.Presets["list"], items, nil); err != nil {
 		t.Fatalf("finalizeItems error: %v", err)
 	}
 
@@ -0,0 +1,96 @@
+package resolver
+
+import (
+	"context"
+	"sync"
+
+	"YrestAPI/internal/model"
+)
+
+var tailChunkSize = 5000 // ключей родителей в одном tail-запросе
+
+// SetTailChunkSize sets how many parent keys one tail query receives; larger
+// sets are split into chunks fetched concurrently. It is meant to be called
+// once at startup; non-positive values keep the default.
+func SetTailChunkSize(n int) {
+	if n > 0 {
+		tailChunkSize = n
+	}
+}
+
+// chunkIDs режет ключи родителей на куски не длиннее size.
+func chunkIDs(ids []any, size int) []model.IDList {
+	if size <= 0 || len(ids) <= size {
+		return []model.IDList{model.IDList(ids)}
+	}
+	out := make([]model.IDList, 0, (len(ids)+size-1)/size)
+	for start := 0; start < len(ids); start += size {
+		end := min(start+size, len(ids))
+		out = append(out, model.IDList(ids[start:end]))
+	}
+	return out
+}
+
+// resolveChunks выполняет дочерний запрос для каждого куска ключей родителей
+// и склеивает строки в порядке кусков. Куски идут параллельно (одновременность
+// ограничивают слоты хвостов); первая ошибка отменяет остальные. Каждый
+// родитель попадает ровно в один кусок, поэтому группировка по FK и окна
+// limit/offset на родителя не меняются.
+func resolveChunks(ctx context.Context, ids []any, build func(model.IDList) (IndexRequest, error)) ([]map[string]any, error) {
+	chunks := chunkIDs(ids, tailChunkSize)
+	if len(chunks) == 1 {
+		req, err := build(chunks[0])
+		if err != nil {
+			return nil, err
+		}
+		return Resolver(ctx, req)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]map[string]any, len(chunks))
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	for i, chunk := range chunks {
+		req, err := build(chunk)
+		if err != nil {
+			fail(err)
+			break
+		}
+		wg.Add(1)
+		go func(i int, req IndexRequest) {
+			defer wg.Done()
+			items, err := Resolver(ctx, req)
+			if err != nil {
+				fail(err)
+				return
+			}
+			results[i] = items
+		}(i, req)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	total := 0
+	for _, r := range results {
+		total += len(r)
+	}
+	out := make([]map[string]any, 0, total)
+	for _, r := range results {
+		out = append(out, r...)
+	}
+	return out, nil
+}
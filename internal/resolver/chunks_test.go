@@ -0,0 +1,40 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+func TestChunkIDs(t *testing.T) {
+	ids := []any{1, 2, 3, 4, 5}
+	chunks := chunkIDs(ids, 2)
+	if len(chunks) != 3 || len(chunks[0]) != 2 || len(chunks[2]) != 1 || chunks[2][0] != 5 {
+		t.Fatalf("unexpected chunks: %v", chunks)
+	}
+	if got := chunkIDs(ids, 10); len(got) != 1 || len(got[0]) != 5 {
+		t.Fatalf("small set must stay in one chunk: %v", got)
+	}
+}
+
+// Ошибка любого куска возвращается целиком; запросы до SQL не доходят:
+// db.Pool в тестах не инициализирован.
+func TestResolveChunksReturnsChunkError(t *testing.T) {
+	prevSize, prevRegistry := tailChunkSize, model.Registry
+	t.Cleanup(func() { tailChunkSize, model.Registry = prevSize, prevRegistry })
+	tailChunkSize = 2
+	model.Registry = map[string]*model.Model{}
+
+	var sizes []int
+	_, err := resolveChunks(context.Background(), []any{1, 2, 3, 4, 5}, func(chunk model.IDList) (IndexRequest, error) {
+		sizes = append(sizes, len(chunk))
+		return IndexRequest{Model: "Missing", Filters: map[string]any{"id__in": chunk}}, nil
+	})
+	if err == nil {
+		t.Fatal("expected error for unknown model")
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[2] != 1 {
+		t.Fatalf("unexpected chunk sizes: %v", sizes)
+	}
+}
@@ -0,0 +1,372 @@
+package resolver
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/metrics"
+	"YrestAPI/internal/model"
+)
+
+// CostBudget — пределы стоимости одного запроса: страницы /api/index, потока
+// или выгрузки целиком, конверта вместе с total, /api/stats. Нулевое поле
+// не ограничивает соответствующую составляющую.
+type CostBudget struct {
+	MaxJoins    int     // JOIN-ов в корневом SELECT (DetectJoins)
+	MaxTails    int     // has_one/has_many/полиморфных хвостов во всём дереве пресета (tree — по хвосту на уровень)
+	MaxDepth    int     // глубина вложенности пресетов
+	MaxFilters  int     // условий в filters, включая вложенные or/and
+	MaxLimit    uint64  // строк на страницу; limit 0 (без ограничения) тоже превышает
+	MaxPlanCost float64 // total cost из EXPLAIN; 0 — EXPLAIN не выполняется
+}
+
+func (b CostBudget) enabled() bool {
+	return b.MaxJoins > 0 || b.MaxTails > 0 || b.MaxDepth > 0 ||
+		b.MaxFilters > 0 || b.MaxLimit > 0 || b.MaxPlanCost > 0
+}
+
+var costBudget CostBudget
+
+// SetCostBudget sets the budget checked once per request before its first
+// query. It is meant to be called once at startup.
+func SetCostBudget(b CostBudget) {
+	costBudget = b
+}
+
+// QueryCost — оценка запроса по плану резолвера.
+type QueryCost struct {
+	Joins     int
+	Tails     int
+	Depth     int
+	Filters   int
+	Limit     uint64
+	Single    bool    // одна строка результата (count, агрегаты): limit не нужен
+	PlanCost  float64 // total cost из EXPLAIN, если задан MaxPlanCost
+	Unbounded string  // первая tree-связь пресета без max_depth (Model.relation)
+}
+
+// add складывает оценки запросов одного клиентского запроса (страница и
+// total, корни GraphQL-документа). Глубина — наибольшая; limit без предела
+// у любой части делает безлимитной всю сумму.
+func (c QueryCost) add(o QueryCost) QueryCost {
+	out := QueryCost{
+		Joins:    c.Joins + o.Joins,
+		Tails:    c.Tails + o.Tails,
+		Depth:    max(c.Depth, o.Depth),
+		Filters:  c.Filters + o.Filters,
+		Limit:    c.Limit + o.Limit,
+		Single:   c.Single && o.Single,
+		PlanCost: c.PlanCost + o.PlanCost,
+	}
+	out.Unbounded = cmp.Or(c.Unbounded, o.Unbounded)
+	if (!c.Single && c.Limit == 0) || (!o.Single && o.Limit == 0) {
+		out.Limit = 0
+	}
+	return out
+}
+
+// CostError — запрос превышает бюджет. Part называет составляющую
+// (joins, tails, depth, filters, limit, plan_cost).
+type CostError struct {
+	Part    string
+	Message string
+}
+
+func (e *CostError) Error() string { return "query too expensive: " + e.Message }
+
+// estimateCost оценивает корневой запрос: JOIN-ы считаются так же, как их
+// добавит BuildIndexQuery, хвосты и глубина — по дереву пресета. Без пресета
+// (unique_by, count) считаются только JOIN-ы фильтров и сортировок.
+func estimateCost(m *model.Model, preset *model.DataPreset, aliasMap *model.AliasMap, filters map[string]any, sorts []string, requestFilters map[string]any, limit uint64) (QueryCost, error) {
+	joinPreset := preset
+	if joinPreset == nil {
+		joinPreset = &model.DataPreset{}
+	}
+	joins, err := m.IndexJoins(aliasMap, filters, sorts, joinPreset)
+	if err != nil {
+		return QueryCost{}, err
+	}
+	tails, depth, unbounded := presetShape(m, preset, []*model.Model{m})
+	return QueryCost{
+		Joins:     len(joins),
+		Tails:     tails,
+		Depth:     depth,
+		Filters:   countFilterTerms(requestFilters),
+		Limit:     limit,
+		Unbounded: unbounded,
+	}, nil
+}
+
+// check сравнивает оценку с бюджетом и называет первую превышенную часть.
+func (c QueryCost) check(b CostBudget) error {
+	switch {
+	case b.MaxJoins > 0 && c.Joins > b.MaxJoins:
+		return &CostError{Part: "joins", Message: fmt.Sprintf("%d joins exceed the budget of %d; request fewer related fields, filters or sorts", c.Joins, b.MaxJoins)}
+	case (b.MaxTails > 0 || b.MaxDepth > 0) && c.Unbounded != "":
+		return &CostError{Part: "depth", Message: fmt.Sprintf("tree relation %s has no max_depth and cannot be bounded by the budget; set max_depth on the relation or the preset field", c.Unbounded)}
+	case b.MaxTails > 0 && c.Tails > b.MaxTails:
+		return &CostError{Part: "tails", Message: fmt.Sprintf("%d has_one/has_many relations exceed the budget of %d; use a smaller preset", c.Tails, b.MaxTails)}
+	case b.MaxDepth > 0 && c.Depth > b.MaxDepth:
+		return &CostError{Part: "depth", Message: fmt.Sprintf("preset nesting depth %d exceeds the budget of %d", c.Depth, b.MaxDepth)}
+	case b.MaxFilters > 0 && c.Filters > b.MaxFilters:
+		return &CostError{Part: "filters", Message: fmt.Sprintf("%d filter conditions exceed the budget of %d", c.Filters, b.MaxFilters)}
+	case b.MaxLimit > 0 && !c.Single && c.Limit == 0:
+		return &CostError{Part: "limit", Message: fmt.Sprintf("limit is required and must not exceed %d", b.MaxLimit)}
+	case b.MaxLimit > 0 && c.Limit > b.MaxLimit:
+		return &CostError{Part: "limit", Message: fmt.Sprintf("limit %d exceeds the budget of %d", c.Limit, b.MaxLimit)}
+	case b.MaxPlanCost > 0 && c.PlanCost > b.MaxPlanCost:
+		return &CostError{Part: "plan_cost", Message: fmt.Sprintf("planner cost %.0f exceeds the budget of %.0f; narrow the filters or lower the limit", c.PlanCost, b.MaxPlanCost)}
+	}
+	return nil
+}
+
+type costCheckedKey struct{}
+
+// needsCostCheck сообщает, что запрос с ctx ещё не сверен с бюджетом.
+// Хвосты входят в оценку корня, а CheckCost помечает контекст, чтобы чанки
+// потока, страница и total конверта не проверялись повторно по частям.
+func needsCostCheck(ctx context.Context) bool {
+	return costBudget.enabled() && queryKind(ctx) == metrics.QueryRoot && ctx.Value(costCheckedKey{}) == nil
+}
+
+// plannedQuery — собранный SQL запроса и его оценка.
+type plannedQuery struct {
+	m      *model.Model
+	preset *model.DataPreset
+	sql    string
+	args   []any
+	cost   QueryCost
+}
+
+// checkCost сверяет один запрос с бюджетом; EXPLAIN выполняется, только
+// если дешёвые проверки прошли.
+func checkCost(ctx context.Context, q plannedQuery) error {
+	if !needsCostCheck(ctx) {
+		return nil
+	}
+	return checkPlanned(ctx, []plannedQuery{q})
+}
+
+func checkPlanned(ctx context.Context, queries []plannedQuery) error {
+	if len(queries) == 0 {
+		return nil
+	}
+	var total QueryCost
+	for i, q := range queries {
+		if i == 0 {
+			total = q.cost
+		} else {
+			total = total.add(q.cost)
+		}
+	}
+	if err := total.check(costBudget); err != nil {
+		return err
+	}
+	if costBudget.MaxPlanCost > 0 {
+		for _, q := range queries {
+			plan, err := explainCost(ctx, q.sql, q.args)
+			if err != nil {
+				return err
+			}
+			total.PlanCost += plan
+		}
+	}
+	logger.Debug("query_cost", map[string]any{"queries": len(queries), "cost": total})
+	return total.check(costBudget)
+}
+
+// CheckCost сверяет с бюджетом запросы целиком до их выполнения: поток и
+// выгрузку — по общему limit, конверт — вместе с total, несколько запросов
+// (корни GraphQL-документа) — суммой. Возвращённый контекст помечен, и
+// резолверы с ним бюджет повторно не проверяют.
+func CheckCost(ctx context.Context, reqs ...IndexRequest) (context.Context, error) {
+	if !needsCostCheck(ctx) {
+		return ctx, nil
+	}
+	ctx = WithRegistry(ctx)
+	var queries []plannedQuery
+	for _, req := range reqs {
+		planned, err := requestQueries(ctx, req)
+		if err != nil {
+			return ctx, err
+		}
+		queries = append(queries, planned...)
+	}
+	if err := checkPlanned(ctx, queries); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, costCheckedKey{}, true), nil
+}
+
+// requestQueries собирает запросы, которые выполнит req: страницу (или
+// уникальные значения unique_by) и, с envelope, total; с CountOnly — только total.
+func requestQueries(ctx context.Context, req IndexRequest) ([]plannedQuery, error) {
+	var out []plannedQuery
+	if req.CountOnly {
+		q, err := countQuery(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return []plannedQuery{q}, nil
+	}
+	if strings.TrimSpace(req.UniqueBy) != "" {
+		q, err := distinctQuery(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	} else {
+		iq, err := prepareIndex(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		sb, err := iq.m.BuildIndexQuery(iq.aliasMap, iq.filters, iq.sorts, iq.preset, req.Offset, req.Limit)
+		if err != nil {
+			return nil, err
+		}
+		sqlStr, args, err := sb.ToSql()
+		if err != nil {
+			return nil, err
+		}
+		cost, err := estimateCost(iq.m, iq.preset, iq.aliasMap, iq.filters, iq.sorts, iq.requestFilters, req.Limit)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, plannedQuery{m: iq.m, preset: iq.preset, sql: sqlStr, args: args, cost: cost})
+	}
+	if req.Envelope {
+		q, err := countQuery(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	}
+	return out, nil
+}
+
+// CheckAggregateCost сверяет с бюджетом запрос /api/stats (count, агрегаты
+// или число уникальных значений): он возвращает одну строку, поэтому limit
+// не проверяется.
+func CheckAggregateCost(ctx context.Context, m *model.Model, preset *model.DataPreset, aliasMap *model.AliasMap, requestFilters, filters map[string]any, sorts []string, sqlStr string, args []any) error {
+	if !needsCostCheck(ctx) {
+		return nil
+	}
+	cost, err := estimateCost(m, preset, aliasMap, filters, sorts, requestFilters, 0)
+	if err != nil {
+		return err
+	}
+	cost.Single = true
+	return checkCost(ctx, plannedQuery{m: m, preset: preset, sql: sqlStr, args: args, cost: cost})
+}
+
+// presetShape считает хвосты (has_one/has_many/полиморфные связи) во всём
+// дереве пресета и глубину вложенности. Повторный заход в модель на пути
+// ограничен так же, как при построении карты алиасов: только для reentrant
+// связей и не больше max_depth посещений. Связь type: tree весит столько
+// хвостов и уровней, сколько уровней обходит (Field.TreeDepth); tree без
+// предела глубины возвращается в unbounded — оценить её нельзя.
+func presetShape(m *model.Model, p *model.DataPreset, stack []*model.Model) (tails, depth int, unbounded string) {
+	if m == nil || p == nil {
+		return 0, 0, ""
+	}
+	for i := range p.Fields {
+		f := &p.Fields[i]
+		if f.Type != "preset" {
+			continue
+		}
+		rel := m.Relations[f.Source]
+		if rel == nil {
+			continue
+		}
+		childDepth := 1
+		switch {
+		case rel.Type == "tree":
+			levels := f.TreeDepth(rel)
+			if levels == 0 && unbounded == "" {
+				unbounded = m.Name + "." + f.Source
+			}
+			tails += max(levels, 1)
+			childDepth = max(levels, 1)
+		case rel.Type != "belongs_to" || rel.Polymorphic:
+			tails++
+		}
+		if target := rel.GetModelRef(); target != nil && !rel.Polymorphic {
+			repeats := 0
+			for _, seen := range stack {
+				if seen == target {
+					repeats++
+				}
+			}
+			if repeats == 0 || (rel.Reentrant && repeats < f.EffectiveMaxDepth(rel)) {
+				nested := f.GetPresetRef()
+				if nested == nil && f.NestedPreset != "" {
+					nested = target.Presets[f.NestedPreset]
+				}
+				t, d, u := presetShape(target, nested, append(stack, target))
+				tails += t
+				childDepth += d
+				unbounded = cmp.Or(unbounded, u)
+			}
+		}
+		depth = max(depth, childDepth)
+	}
+	return tails, depth, unbounded
+}
+
+// countFilterTerms считает условия фильтра; группы or/and и кванторы над
+// связями раскрываются.
+func countFilterTerms(filters map[string]any) int {
+	n := 0
+	for key, val := range filters {
+		if key == "or" || key == "and" {
+			switch group := val.(type) {
+			case map[string]any:
+				n += countFilterTerms(group)
+				continue
+			case []any:
+				for _, item := range group {
+					if sub, ok := item.(map[string]any); ok {
+						n += countFilterTerms(sub)
+					}
+				}
+				continue
+			}
+		}
+		if sub, ok := val.(map[string]any); ok {
+			// квантор над связью: его вложенные условия тоже считаются
+			n += countFilterTerms(sub)
+		}
+		n++
+	}
+	return n
+}
+
+// explainCost возвращает total cost корневого узла плана PostgreSQL.
+func explainCost(ctx context.Context, sqlStr string, args []any) (float64, error) {
+	var plan string
+	if err := db.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+sqlStr, args...).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("explain: %w", err)
+	}
+	return parsePlanCost([]byte(plan))
+}
+
+func parsePlanCost(data []byte) (float64, error) {
+	var plans []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return 0, fmt.Errorf("explain: %w", err)
+	}
+	if len(plans) == 0 {
+		return 0, errors.New("explain: empty plan")
+	}
+	return plans[0].Plan.TotalCost, nil
+}
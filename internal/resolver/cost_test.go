@@ -0,0 +1,244 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+func costTestModels() *model.Model {
+	org := &model.Model{Name: "Org", Table: "orgs", Presets: map[string]*model.DataPreset{
+		"item": {Name: "item", Fields: []model.Field{{Source: "name", Type: "string"}}},
+	}}
+	contact := &model.Model{Name: "Contact", Table: "contacts", Presets: map[string]*model.DataPreset{
+		"item": {Name: "item", Fields: []model.Field{{Source: "value", Type: "string"}}},
+	}}
+	person := &model.Model{Name: "Person", Table: "people", Relations: map[string]*model.ModelRelation{
+		"org":      {Type: "belongs_to", Model: "Org"},
+		"contacts": {Type: "has_many", Model: "Contact"},
+		"parent":   {Type: "belongs_to", Model: "Person"},
+		"owner":    {Type: "belongs_to", Polymorphic: true},
+	}}
+	person.Relations["org"].SetModelRef(org)
+	person.Relations["contacts"].SetModelRef(contact)
+	person.Relations["parent"].SetModelRef(person)
+	person.Relations["parent"].Reentrant = true
+	person.Relations["parent"].MaxDepth = 2
+	person.Presets = map[string]*model.DataPreset{
+		"full": {Name: "full", Fields: []model.Field{
+			{Source: "id", Type: "int"},
+			{Source: "org", Type: "preset", NestedPreset: "item"},
+			{Source: "contacts", Type: "preset", NestedPreset: "item"},
+			{Source: "owner", Type: "preset", NestedPreset: "item"},
+			{Source: "parent", Type: "preset", NestedPreset: "full"},
+		}},
+	}
+	return person
+}
+
+func TestPresetShape(t *testing.T) {
+	person := costTestModels()
+	tails, depth, _ := presetShape(person, person.Presets["full"], []*model.Model{person})
+	// parent разворачивается до max_depth=2 посещений Person: на обоих
+	// уровнях есть contacts и owner, третий уровень parent не раскрывается
+	if tails != 4 || depth != 2 {
+		t.Fatalf("got tails=%d depth=%d", tails, depth)
+	}
+}
+
+func TestCountFilterTerms(t *testing.T) {
+	filters := map[string]any{
+		"name__cnt": "a",
+		"or": map[string]any{
+			"age__gt": 1,
+			"and": []any{
+				map[string]any{"id__in": []any{1, 2, 3}},
+				map[string]any{"org.name__eq": "x", "org.id__null": false},
+			},
+		},
+	}
+	if got := countFilterTerms(filters); got != 5 {
+		t.Fatalf("got %d terms", got)
+	}
+	// квантор — одно условие плюс его вложенные
+	filters["projects__any"] = map[string]any{"status__eq": "active", "members__count_gte": 2}
+	if got := countFilterTerms(filters); got != 8 {
+		t.Fatalf("quantifier: got %d terms", got)
+	}
+}
+
+func TestQueryCostCheck(t *testing.T) {
+	cost := QueryCost{Joins: 3, Tails: 2, Depth: 2, Filters: 4, Limit: 0}
+	cases := []struct {
+		budget CostBudget
+		part   string
+	}{
+		{CostBudget{MaxJoins: 2}, "joins"},
+		{CostBudget{MaxJoins: 3, MaxTails: 1}, "tails"},
+		{CostBudget{MaxDepth: 1}, "depth"},
+		{CostBudget{MaxFilters: 3}, "filters"},
+		{CostBudget{MaxLimit: 100}, "limit"},
+		{CostBudget{MaxJoins: 3, MaxTails: 2, MaxDepth: 2, MaxFilters: 4}, ""},
+	}
+	for _, tc := range cases {
+		err := cost.check(tc.budget)
+		var costErr *CostError
+		if tc.part == "" {
+			if err != nil {
+				t.Fatalf("budget %+v: unexpected %v", tc.budget, err)
+			}
+			continue
+		}
+		if !errors.As(err, &costErr) || costErr.Part != tc.part {
+			t.Fatalf("budget %+v: expected %s, got %v", tc.budget, tc.part, err)
+		}
+	}
+}
+
+// Отказ должен случаться до SQL: db.Pool в тестах не инициализирован.
+func TestResolveRejectsOverBudgetBeforeSQL(t *testing.T) {
+	origRegistry, origBudget := model.Registry, costBudget
+	t.Cleanup(func() { model.Registry, costBudget = origRegistry, origBudget })
+	model.Registry = map[string]*model.Model{
+		"Person": {
+			Name:    "Person",
+			Table:   "people",
+			Presets: map[string]*model.DataPreset{"item": {Name: "item", Fields: []model.Field{{Source: "id", Type: "int"}}}},
+		},
+	}
+	SetCostBudget(CostBudget{MaxLimit: 100})
+
+	var costErr *CostError
+	_, err := ResolvePage(context.Background(), IndexRequest{Model: "Person", Preset: "item", Limit: 500})
+	if !errors.As(err, &costErr) || costErr.Part != "limit" {
+		t.Fatalf("expected limit CostError, got %v", err)
+	}
+}
+
+func TestParsePlanCost(t *testing.T) {
+	got, err := parsePlanCost([]byte(`[{"Plan":{"Node Type":"Limit","Startup Cost":0.0,"Total Cost":1234.5}}]`))
+	if err != nil || got != 1234.5 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	if _, err := parsePlanCost([]byte(`[]`)); err == nil {
+		t.Fatal("expected error for empty plan")
+	}
+}
+
+func TestPresetShapeStopsOnNonReentrantCycle(t *testing.T) {
+	person := costTestModels()
+	person.Relations["parent"].Reentrant = false
+	tails, depth, _ := presetShape(person, person.Presets["full"], []*model.Model{person})
+	if tails != 2 || depth != 1 {
+		t.Fatalf("got tails=%d depth=%d", tails, depth)
+	}
+}
+
+func TestPresetShapeWeightsTrees(t *testing.T) {
+	node := &model.Model{Name: "Node", Table: "nodes", Relations: map[string]*model.ModelRelation{
+		"children": {Type: "tree", Model: "Node", ParentFK: "parent_id", PK: "id", MaxDepth: 5},
+	}}
+	node.Relations["children"].SetModelRef(node)
+	node.Presets = map[string]*model.DataPreset{
+		"tree": {Name: "tree", Fields: []model.Field{
+			{Source: "id", Type: "int"},
+			{Source: "children", Type: "preset", NestedPreset: "tree"},
+		}},
+		"shallow": {Name: "shallow", Fields: []model.Field{
+			{Source: "children", Type: "preset", NestedPreset: "tree", MaxDepth: 2},
+		}},
+	}
+	tails, depth, unbounded := presetShape(node, node.Presets["tree"], []*model.Model{node})
+	if tails != 5 || depth != 5 || unbounded != "" {
+		t.Fatalf("max_depth 5: got tails=%d depth=%d unbounded=%q", tails, depth, unbounded)
+	}
+	tails, depth, _ = presetShape(node, node.Presets["shallow"], []*model.Model{node})
+	if tails != 2 || depth != 2 {
+		t.Fatalf("field max_depth 2: got tails=%d depth=%d", tails, depth)
+	}
+
+	node.Relations["children"].MaxDepth = 0
+	_, _, unbounded = presetShape(node, node.Presets["tree"], []*model.Model{node})
+	if unbounded != "Node.children" {
+		t.Fatalf("unbounded tree not reported: %q", unbounded)
+	}
+	var costErr *CostError
+	err := QueryCost{Tails: 1, Depth: 1, Limit: 10, Unbounded: unbounded}.check(CostBudget{MaxTails: 10})
+	if !errors.As(err, &costErr) || costErr.Part != "depth" {
+		t.Fatalf("expected depth CostError for unbounded tree, got %v", err)
+	}
+	if err := (QueryCost{Limit: 10, Unbounded: unbounded}).check(CostBudget{MaxLimit: 10}); err != nil {
+		t.Fatalf("limit-only budget does not bound tree depth: %v", err)
+	}
+}
+
+func TestQueryCostAdd(t *testing.T) {
+	page := QueryCost{Joins: 2, Tails: 1, Depth: 2, Filters: 3, Limit: 50}
+	count := QueryCost{Joins: 1, Depth: 1, Filters: 3, Single: true}
+	got := page.add(count)
+	want := QueryCost{Joins: 3, Tails: 1, Depth: 2, Filters: 6, Limit: 50}
+	if got != want {
+		t.Fatalf("page+count: got %+v, want %+v", got, want)
+	}
+	if got := count.add(count); !got.Single || got.Limit != 0 {
+		t.Fatalf("two single-row queries stay single: %+v", got)
+	}
+	// безлимитная часть делает безлимитной всю сумму
+	if got := page.add(QueryCost{Limit: 0}); got.Limit != 0 || got.Single {
+		t.Fatalf("unbounded part: got %+v", got)
+	}
+	if err := count.check(CostBudget{MaxLimit: 10}); err != nil {
+		t.Fatalf("single-row query needs no limit: %v", err)
+	}
+}
+
+// Поток, конверт и unique_by сверяются с бюджетом целиком до первого SQL:
+// db.Pool в тестах не инициализирован.
+func TestCostCheckedOncePerRequestBeforeSQL(t *testing.T) {
+	origRegistry, origBudget := model.Registry, costBudget
+	t.Cleanup(func() { model.Registry, costBudget = origRegistry, origBudget })
+	model.Registry = map[string]*model.Model{
+		"Person": {
+			Name:    "Person",
+			Table:   "people",
+			Presets: map[string]*model.DataPreset{"item": {Name: "item", Fields: []model.Field{{Source: "id", Type: "int"}, {Source: "name", Type: "string"}}}},
+		},
+	}
+	expectPart := func(name, part string, err error) {
+		t.Helper()
+		var costErr *CostError
+		if !errors.As(err, &costErr) || costErr.Part != part {
+			t.Fatalf("%s: expected %s CostError, got %v", name, part, err)
+		}
+	}
+
+	SetCostBudget(CostBudget{MaxLimit: 100})
+	// поток без limit — безлимитный целиком, хотя каждый чанк в пределах
+	err := StreamIndex(context.Background(), IndexRequest{Model: "Person", Preset: "item"}, func([]map[string]any) error {
+		t.Fatal("over-budget stream must not emit")
+		return nil
+	})
+	expectPart("stream", "limit", err)
+	_, err = ResolveDistinctValues(context.Background(), IndexRequest{Model: "Person", UniqueBy: "name", Limit: 500})
+	expectPart("unique_by", "limit", err)
+
+	// конверт: страница и total вместе превышают бюджет фильтров
+	SetCostBudget(CostBudget{MaxFilters: 3})
+	filters := map[string]any{"name__cnt": "a", "id__gt": 1}
+	_, err = ResolveEnvelope(context.Background(), IndexRequest{Model: "Person", Preset: "item", Filters: filters, Limit: 10, Envelope: true})
+	expectPart("envelope", "filters", err)
+	_, err = ResolveCount(context.Background(), IndexRequest{Model: "Person", Preset: "item", Filters: map[string]any{"name__cnt": "a", "id__gt": 1, "id__lt": 9, "id__in": []any{1}}})
+	expectPart("count", "filters", err)
+
+	// CheckCost помечает контекст: вложенные резолверы бюджет не повторяют
+	SetCostBudget(CostBudget{MaxFilters: 2})
+	ctx, err := CheckCost(context.Background(), IndexRequest{Model: "Person", Preset: "item", Filters: filters, Limit: 10})
+	if err != nil {
+		t.Fatalf("within budget: %v", err)
+	}
+	if needsCostCheck(ctx) {
+		t.Fatal("checked context must not be re-checked")
+	}
+}
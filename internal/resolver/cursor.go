@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CursorError denotes an invalid cursor/after payload or a request that cannot
+// be paginated by keyset (missing limit, offset together with after).
+type CursorError struct {
+	Message string
+}
+
+func (e *CursorError) Error() string { return e.Message }
+
+// cursorPayload is the decoded form of an opaque cursor token. Sorts pins the
+// cursor to the ordering it was produced for; Values holds the sort key values
+// of the last row of the page in that order.
+type cursorPayload struct {
+	Sorts  []string  `json:"k"`
+	Values []*string `json:"v"`
+}
+
+// encodeCursor builds an opaque token from the keyset sorts and the last row's
+// key values. Values travel as strings: pgx sends string arguments in text
+// format for any column type, so numbers, timestamps and UUIDs round-trip
+// without type tags.
+func encodeCursor(sorts []string, values []any) (string, error) {
+	payload := cursorPayload{Sorts: sorts, Values: make([]*string, len(values))}
+	for i, v := range values {
+		s, ok, err := cursorValueString(v)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			payload.Values[i] = &s
+		}
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor parses a token and checks that it was issued for the same sorts.
+func decodeCursor(token string, sorts []string) ([]any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, &CursorError{Message: "invalid cursor"}
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, &CursorError{Message: "invalid cursor"}
+	}
+	if !reflect.DeepEqual(payload.Sorts, sorts) {
+		return nil, &CursorError{Message: "cursor does not match request sorts"}
+	}
+	values := make([]any, len(payload.Values))
+	for i, v := range payload.Values {
+		if v != nil {
+			values[i] = *v
+		}
+	}
+	return values, nil
+}
+
+// cursorValueString renders a scanned key value in PostgreSQL text input form.
+// The second result is false for SQL NULL.
+func cursorValueString(v any) (string, bool, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return val, true, nil
+	case []byte:
+		return string(val), true, nil
+	case int64:
+		return strconv.FormatInt(val, 10), true, nil
+	case int32:
+		return strconv.FormatInt(int64(val), 10), true, nil
+	case int16:
+		return strconv.FormatInt(int64(val), 10), true, nil
+	case int:
+		return strconv.Itoa(val), true, nil
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64), true, nil
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32), true, nil
+	case bool:
+		return strconv.FormatBool(val), true, nil
+	case time.Time:
+		return val.Format(time.RFC3339Nano), true, nil
+	case uuid.UUID:
+		return val.String(), true, nil
+	case [16]byte:
+		return uuid.UUID(val).String(), true, nil
+	case driver.Valuer:
+		dv, err := val.Value()
+		if err != nil {
+			return "", false, err
+		}
+		if dv == nil {
+			return "", false, nil
+		}
+		return cursorValueString(dv)
+	case fmt.Stringer:
+		return val.String(), true, nil
+	default:
+		return "", false, fmt.Errorf("cursor: unsupported sort value type %T", v)
+	}
+}
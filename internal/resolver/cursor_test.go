@@ -0,0 +1,50 @@
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	sorts := []string{"created_at DESC", "uuid DESC", "score DESC", "id DESC"}
+	ts := time.Date(2024, 5, 1, 10, 30, 0, 123000000, time.UTC)
+	id := uuid.MustParse("7f1b6a3e-4a43-4d2a-9d4e-6f1e3f8b2c11")
+
+	token, err := encodeCursor(sorts, []any{ts, [16]byte(id), nil, int64(42)})
+	if err != nil {
+		t.Fatalf("encodeCursor: %v", err)
+	}
+	got, err := decodeCursor(token, sorts)
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	want := []any{"2024-05-01T10:30:00.123Z", id.String(), nil, "42"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("values mismatch: got %#v, want %#v", got, want)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	sorts := []string{"id ASC"}
+	other, err := encodeCursor([]string{"id DESC"}, []any{int64(1)})
+	if err != nil {
+		t.Fatalf("encodeCursor: %v", err)
+	}
+	for name, token := range map[string]string{
+		"not base64":      "!!!",
+		"not json":        "bm90IGpzb24",
+		"different sorts": other,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeCursor(token, sorts)
+			var cursorErr *CursorError
+			if !errors.As(err, &cursorErr) {
+				t.Fatalf("expected CursorError, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/metrics"
+	"YrestAPI/internal/model"
+	"github.com/Masterminds/squirrel"
+)
+
+// IndexEnvelope is the /api/index response shape when "envelope": true.
+// NextCursor is null unless cursor pagination is active and more rows follow.
+type IndexEnvelope struct {
+	Items      any     `json:"items"`
+	Total      int64   `json:"total"`
+	Offset     uint64  `json:"offset"`
+	Limit      uint64  `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// ResolveEnvelope runs the page query and the total count in parallel. The
+// total uses the same filters through BuildCountQuery (or the distinct count
+// for unique_by), so it matches what /api/stats would return. With
+// consistent both run in one database snapshot.
+func ResolveEnvelope(ctx context.Context, req IndexRequest) (IndexEnvelope, error) {
+	ctx = WithRegistry(ctx)
+	ctx, cancelDeadline := withRequestDeadline(ctx, req)
+	defer cancelDeadline()
+	// страница и total укладываются в бюджет вместе — до первого запроса
+	ctx, err := CheckCost(ctx, req)
+	if err != nil {
+		return IndexEnvelope{}, err
+	}
+	// с consistent total и страница считаются по одному снимку
+	ctx, release, err := withSnapshot(ctx, req)
+	if err != nil {
+		return IndexEnvelope{}, err
+	}
+	defer release()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		items any
+		next  string
+		total int64
+		perr  error
+		cerr  error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if strings.TrimSpace(req.UniqueBy) != "" {
+			items, perr = ResolveDistinctValues(ctx, req)
+		} else {
+			var page IndexPage
+			page, perr = ResolvePage(ctx, req)
+			items, next = page.Items, page.NextCursor
+		}
+		if perr != nil {
+			cancel()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		total, cerr = ResolveCount(ctx, req)
+		if cerr != nil {
+			cancel()
+		}
+	}()
+	wg.Wait()
+
+	// Ошибка страницы важнее: count мог упасть лишь из-за отмены контекста.
+	if perr != nil {
+		return IndexEnvelope{}, perr
+	}
+	if cerr != nil {
+		return IndexEnvelope{}, cerr
+	}
+
+	env := IndexEnvelope{Items: items, Total: total, Offset: req.Offset, Limit: req.Limit}
+	if next != "" {
+		env.NextCursor = &next
+	}
+	return env, nil
+}
+
+// ResolveCount returns the number of root rows matching req.Filters, ignoring
+// sorts and pagination.
+func ResolveCount(ctx context.Context, req IndexRequest) (int64, error) {
+	ctx = WithRegistry(ctx)
+	q, err := countQuery(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	logger.Debug("sql", map[string]any{
+		"endpoint": "/api/index",
+		"sql":      q.sql,
+		"args":     q.args,
+	})
+
+	ctx, cancel := WithDeadline(ctx, q.m, q.preset)
+	defer cancel()
+	if err := checkCost(ctx, q); err != nil {
+		return 0, err
+	}
+	var total int64
+	started := time.Now()
+	err = db.QueryRow(ctx, q.sql, q.args...).Scan(&total)
+	metrics.ObserveQuery(metrics.QueryCount, time.Since(started))
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// countQuery строит COUNT для конверта: по пресету или, с unique_by, число
+// уникальных значений.
+func countQuery(ctx context.Context, req IndexRequest) (plannedQuery, error) {
+	m, ok := LookupModel(ctx, req.Model)
+	if !ok {
+		return plannedQuery{}, fmt.Errorf("resolver: model not found: %s", req.Model)
+	}
+	if err := Authorize(ctx, m, nil); err != nil {
+		return plannedQuery{}, err
+	}
+	requestFilters, err := req.requestFilters(m)
+	if err != nil {
+		return plannedQuery{}, err
+	}
+	if err := AuthorizeFields(ctx, m, requestFilters, model.ExpandAliasPath(m, strings.TrimSpace(req.UniqueBy))); err != nil {
+		return plannedQuery{}, err
+	}
+	filters, err := ApplyPolicy(ctx, m, requestFilters, "")
+	if err != nil {
+		return plannedQuery{}, err
+	}
+
+	var query squirrel.SelectBuilder
+	var preset *model.DataPreset
+	var aliasMap *model.AliasMap
+	var sorts []string
+	if uniqueBy := strings.TrimSpace(req.UniqueBy); uniqueBy != "" {
+		field := strings.TrimSpace(model.ExpandAliasPath(m, uniqueBy))
+		sorts = []string{field + " ASC"}
+		if aliasMap, err = m.CreateAliasMap(m, nil, filters, sorts); err != nil {
+			return plannedQuery{}, &model.DistinctValidationError{Message: err.Error()}
+		}
+		aliasMap = ScopeAliasMap(ctx, aliasMap)
+		if query, err = m.BuildDistinctCountQuery(aliasMap, filters, field); err != nil {
+			return plannedQuery{}, err
+		}
+	} else {
+		if req.Preset != "" {
+			preset = m.GetPreset(req.Preset)
+		} else {
+			preset = req.PresetObj
+		}
+		if preset == nil {
+			return plannedQuery{}, presetNotFound(m, req.Model, req.Preset)
+		}
+		if err := Authorize(ctx, m, preset); err != nil {
+			return plannedQuery{}, err
+		}
+		if aliasMap, err = m.CreateAliasMap(m, preset, filters, nil); err != nil {
+			return plannedQuery{}, fmt.Errorf("alias map error: %s", err)
+		}
+		aliasMap = ScopeAliasMap(ctx, aliasMap)
+		if query, err = m.BuildCountQuery(aliasMap, preset, filters); err != nil {
+			return plannedQuery{}, err
+		}
+	}
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return plannedQuery{}, err
+	}
+	q := plannedQuery{m: m, preset: preset, sql: sqlStr, args: args}
+	if costBudget.enabled() {
+		if q.cost, err = estimateCost(m, preset, aliasMap, filters, sorts, requestFilters, 0); err != nil {
+			return plannedQuery{}, err
+		}
+		q.cost.Single = true
+	}
+	return q, nil
+}
@@ -12,7 +12,8 @@ import (
 // finalizeItems:
 // 1) применяет formatter-поля к items
 // 2) удаляет все поля/поддеревья, помеченные internal: true
-func finalizeItems(m *model.Model, p *model.DataPreset, items []map[string]any) error {
+// 3) удаляет или маскирует поля с visible_to, недоступные ролям roles
+func finalizeItems(m *model.Model, p *model.DataPreset, items []map[string]any, roles []string) error {
 	if p == nil || len(items) == 0 {
 		return nil
 	}
@@ -30,10 +31,17 @@ func finalizeItems(m *model.Model, p *model.DataPreset, items []map[string]any)
 		applyLocalization(m, p, items) // или locale из запроса
 	}
 
+	// 0.8 скрываем поля visible_to до форматтеров, чтобы они не попали
+	// в вычисляемые строки; скрытые formatter-поля — после вычисления
+	var redactions []redaction
+	collectRedactions(m, p, "", roles, &redactions)
+	applyRedactions(items, redactions, false)
+
 	// 1 посчитать все formatter'ы до удаления internal
 	if err := applyAllFormatters(m, p, items, ""); err != nil {
 		return err
 	}
+	applyRedactions(items, redactions, true)
 
 	// 2) собрать маркеры internal: префиксы-деревья и точные ключи
 	var (
@@ -698,8 +706,8 @@ func collectInternalMarkers(m *model.Model, p *model.DataPreset, prefix string,
 			// belongs_to — как раньше
 			if rel.Type == "belongs_to" && rel.GetModelRef() != nil {
 				nestedModel := rel.GetModelRef()
-				var nested *model.DataPreset
-				if f.NestedPreset != "" {
+				nested := f.GetPresetRef()
+				if nested == nil && f.NestedPreset != "" {
 					nested = nestedModel.Presets[f.NestedPreset]
 				}
 				if nested != nil {
@@ -1150,8 +1158,12 @@ func stripPresetPrefixes(m *model.Model, p *model.DataPreset, items []map[string
 
 		// Рекурсивно спускаемся внутрь
 		nestedModel := rel.GetModelRef()
-		if nestedModel != nil && f.NestedPreset != "" {
-			if nested := nestedModel.Presets[f.NestedPreset]; nested != nil {
+		if nestedModel != nil {
+			nested := f.GetPresetRef()
+			if nested == nil && f.NestedPreset != "" {
+				nested = nestedModel.Presets[f.NestedPreset]
+			}
+			if nested != nil {
 				stripPresetPrefixes(nestedModel, nested, items, curPrefix)
 			}
 		}
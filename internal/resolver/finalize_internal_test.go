@@ -44,7 +44,7 @@ func TestFinalizeItems_RemovesInternalPresetAfterNestedField(t *testing.T) {
 		},
 	}
 
-	if err := finalizeItems(m, m.Presets["card"], items); err != nil {
+	if err := finalizeItems(m, m.Presets["card"], items, nil); err != nil {
 		t.Fatalf("finalizeItems error: %v", err)
 	}
 
@@ -93,7 +93,7 @@ func TestFinalizeItems_AppliesPresetAliasForBelongsTo(t *testing.T) {
 		},
 	}
 
-	if err := finalizeItems(m, m.Presets["card"], items); err != nil {
+	if err := finalizeItems(m, m.Presets["card"], items, nil); err != nil {
 		t.Fatalf("finalizeItems error: %v", err)
 	}
 
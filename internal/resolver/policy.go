@@ -0,0 +1,35 @@
+package resolver
+
+import (
+	"context"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/model"
+)
+
+// ApplyPolicy добавляет к фильтрам запроса row-level политику модели
+// (policies.filter) с claims из контекста. Для through-запросов (unwrap)
+// добавляется и политика конечной модели — по пути связи.
+// Ошибка всегда *model.PolicyError: запрос нужно отклонить (403).
+func ApplyPolicy(ctx context.Context, m *model.Model, filters map[string]any, unwrap string) (map[string]any, error) {
+	claims, _ := auth.ClaimsFromContext(ctx)
+	filters, err := m.ApplyPolicy(filters, claims, "")
+	if err != nil {
+		return nil, err
+	}
+	if unwrap == "" {
+		return filters, nil
+	}
+	if rel := m.Relations[unwrap]; rel != nil && rel.GetModelRef() != nil {
+		return rel.GetModelRef().ApplyPolicy(filters, claims, unwrap+".")
+	}
+	return filters, nil
+}
+
+// ScopeAliasMap возвращает копию карты алиасов с claims запроса: по ним
+// построители SQL применяют политики моделей, которые читаются подзапросами
+// (кванторы), а не через фильтры корня.
+func ScopeAliasMap(ctx context.Context, aliasMap *model.AliasMap) *model.AliasMap {
+	claims, _ := auth.ClaimsFromContext(ctx)
+	return aliasMap.WithClaims(claims)
+}
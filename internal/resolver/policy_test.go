@@ -0,0 +1,44 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/model"
+)
+
+func TestApplyPolicyUsesContextClaimsAndUnwrap(t *testing.T) {
+	contact := &model.Model{
+		Name:     "Contact",
+		Table:    "contacts",
+		Policies: &model.ModelPolicies{Filter: map[string]any{"org_id": "{claims.org}"}},
+	}
+	link := &model.ModelRelation{Type: "belongs_to", Model: "Contact"}
+	link.SetModelRef(contact)
+	through := &model.Model{
+		Name:      "PersonContact",
+		Table:     "person_contacts",
+		Relations: map[string]*model.ModelRelation{"contact": link},
+	}
+
+	ctx := auth.WithClaims(context.Background(), map[string]any{"org": "acme"})
+	got, err := ApplyPolicy(ctx, through, map[string]any{"person_id__in": []any{1}}, "contact")
+	if err != nil {
+		t.Fatalf("ApplyPolicy: %v", err)
+	}
+	want := map[string]any{"and": []any{
+		map[string]any{"contact.org_id__eq_cs": "acme"},
+		map[string]any{"person_id__in": []any{1}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected filters: %#v", got)
+	}
+
+	var policyErr *model.PolicyError
+	if _, err := ApplyPolicy(context.Background(), contact, nil, ""); !errors.As(err, &policyErr) {
+		t.Fatalf("expected PolicyError without claims, got %v", err)
+	}
+}
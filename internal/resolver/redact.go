@@ -0,0 +1,98 @@
+package resolver
+
+import (
+	"strings"
+
+	"YrestAPI/internal/model"
+)
+
+// redaction — поле пресета, скрытое от вызывающего (visible_to).
+type redaction struct {
+	path      string // dotted-путь в item (с учётом belongs_to-контейнеров)
+	mask      string // "" — удалить поле, иначе заменить значение
+	formatter bool   // formatter-поле: скрывается после вычисления форматтеров
+}
+
+// collectRedactions собирает поля, недоступные ролям roles, по пресету
+// и вложенным belongs_to. has_many/has_one и through скрываются при
+// финализации своего хвоста.
+func collectRedactions(m *model.Model, p *model.DataPreset, prefix string, roles []string, out *[]redaction) {
+	if m == nil || p == nil {
+		return
+	}
+	for i := range p.Fields {
+		f := &p.Fields[i]
+		rel := m.Relations[f.Source]
+		if !f.VisibleFor(roles) {
+			key := f.Source
+			// belongs_to-контейнер до applyPresetAliases лежит под source,
+			// остальные поля к этому моменту уже под alias
+			if !(f.Type == "preset" && rel != nil && rel.Type == "belongs_to") && strings.TrimSpace(f.Alias) != "" {
+				key = f.Alias
+			}
+			*out = append(*out, redaction{
+				path:      prefixFor(prefix, key),
+				mask:      f.Mask,
+				formatter: f.Type == "formatter",
+			})
+			continue
+		}
+		if f.Type != "preset" || rel == nil || rel.Type != "belongs_to" || rel.GetModelRef() == nil {
+			continue
+		}
+		nestedModel := rel.GetModelRef()
+		nested := f.GetPresetRef()
+		if nested == nil && f.NestedPreset != "" {
+			nested = nestedModel.Presets[f.NestedPreset]
+		}
+		collectRedactions(nestedModel, nested, prefixFor(prefix, f.Source), roles, out)
+	}
+}
+
+// applyRedactions удаляет или маскирует поля из list; formatters выбирает,
+// какую группу применять (до или после вычисления форматтеров).
+func applyRedactions(items []map[string]any, list []redaction, formatters bool) {
+	for _, r := range list {
+		if r.formatter != formatters {
+			continue
+		}
+		for i := range items {
+			if r.mask == "" {
+				deleteExactPath(items[i], r.path)
+			} else {
+				maskExactPath(items[i], r.path, r.mask)
+			}
+		}
+	}
+}
+
+// maskExactPath заменяет значение по dotted-пути на mask, если ключ есть;
+// массивы на пути обходятся поэлементно.
+func maskExactPath(root map[string]any, path, mask string) {
+	segs := strings.Split(path, ".")
+	var walk func(cur any, idx int)
+	walk = func(cur any, idx int) {
+		switch node := cur.(type) {
+		case map[string]any:
+			key := segs[idx]
+			if idx == len(segs)-1 {
+				if _, ok := node[key]; ok {
+					node[key] = mask
+				}
+				return
+			}
+			if next, ok := node[key]; ok {
+				walk(next, idx+1)
+			}
+		case []any:
+			for _, it := range node {
+				walk(it, idx)
+			}
+		case []map[string]any:
+			for i := range node {
+				walk(node[i], idx)
+			}
+		}
+	}
+	walk(root, 0)
+}
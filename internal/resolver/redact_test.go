@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+func redactFixture() (*model.Model, []map[string]any) {
+	passport := &model.Model{Table: "passports", Presets: map[string]*model.DataPreset{
+		"item": {Name: "item", Fields: []model.Field{
+			{Type: "string", Source: "number", VisibleTo: model.StringList{"hr"}, Mask: "***"},
+			{Type: "string", Source: "country"},
+		}},
+	}}
+	m := &model.Model{
+		Table: "employees",
+		Relations: map[string]*model.ModelRelation{
+			"passport": {Type: "belongs_to"},
+		},
+		Presets: map[string]*model.DataPreset{
+			"card": {Name: "card", Fields: []model.Field{
+				{Type: "string", Source: "name"},
+				{Type: "float", Source: "salary", Alias: "pay", VisibleTo: model.StringList{"hr", "admin"}},
+				{Type: "formatter", Source: "{name}: {pay}", Alias: "label"},
+				{Type: "formatter", Source: "{name}!", Alias: "shout", VisibleTo: model.StringList{"admin"}, Mask: "hidden"},
+				{Type: "preset", Source: "passport", NestedPreset: "item"},
+			}},
+		},
+	}
+	m.Relations["passport"].SetModelRef(passport)
+	items := []map[string]any{{
+		"name":     "Ann",
+		"salary":   1000.0,
+		"passport": map[string]any{"number": "AB123", "country": "NL"},
+	}}
+	return m, items
+}
+
+func TestFinalizeItems_RedactsFieldsByRole(t *testing.T) {
+	m, items := redactFixture()
+	if err := finalizeItems(m, m.Presets["card"], items, []string{"staff"}); err != nil {
+		t.Fatalf("finalizeItems error: %v", err)
+	}
+	item := items[0]
+	if _, ok := item["pay"]; ok {
+		t.Fatalf("hidden field not removed: %+v", item)
+	}
+	if _, ok := item["salary"]; ok {
+		t.Fatalf("hidden field left under source key: %+v", item)
+	}
+	if got := item["label"]; got != "Ann: " {
+		t.Fatalf("formatter must not see hidden field, got %q", got)
+	}
+	if got := item["shout"]; got != "hidden" {
+		t.Fatalf("formatter field not masked: %v", got)
+	}
+	passport := item["passport"].(map[string]any)
+	if passport["number"] != "***" || passport["country"] != "NL" {
+		t.Fatalf("nested field not masked: %+v", passport)
+	}
+}
+
+func TestFinalizeItems_KeepsFieldsForAllowedRoles(t *testing.T) {
+	m, items := redactFixture()
+	if err := finalizeItems(m, m.Presets["card"], items, []string{"hr"}); err != nil {
+		t.Fatalf("finalizeItems error: %v", err)
+	}
+	item := items[0]
+	if item["pay"] != 1000.0 || item["label"] != "Ann: 1000" {
+		t.Fatalf("allowed field redacted: %+v", item)
+	}
+	if item["shout"] != "hidden" {
+		t.Fatalf("admin-only formatter shown to hr: %v", item["shout"])
+	}
+	if item["passport"].(map[string]any)["number"] != "AB123" {
+		t.Fatalf("allowed nested field masked: %+v", item["passport"])
+	}
+}
@@ -0,0 +1,40 @@
+package resolver
+
+import (
+	"context"
+
+	"YrestAPI/internal/model"
+)
+
+type registryKey struct{}
+
+// WithRegistry фиксирует текущий реестр моделей на время запроса. Корень,
+// хвосты, полиморфные связи и чанки потока ищут модели в одном снимке, даже
+// если реестр подменят посреди запроса (SIGHUP). Повторный вызов сохраняет
+// уже зафиксированный реестр.
+func WithRegistry(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(registryKey{}).(map[string]*model.Model); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, registryKey{}, model.CurrentRegistry())
+}
+
+// WithRegistryMap — WithRegistry с уже взятым снимком реестра: например,
+// тем, по которому собрана схема GraphQL.
+func WithRegistryMap(ctx context.Context, reg map[string]*model.Model) context.Context {
+	return context.WithValue(ctx, registryKey{}, reg)
+}
+
+// registryFrom возвращает реестр запроса, а без WithRegistry — текущий.
+func registryFrom(ctx context.Context) map[string]*model.Model {
+	if reg, ok := ctx.Value(registryKey{}).(map[string]*model.Model); ok {
+		return reg
+	}
+	return model.CurrentRegistry()
+}
+
+// LookupModel ищет модель в реестре, зафиксированном WithRegistry.
+func LookupModel(ctx context.Context, name string) (*model.Model, bool) {
+	m, ok := registryFrom(ctx)[name]
+	return m, ok && m != nil
+}
@@ -0,0 +1,36 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+// Реестр, зафиксированный в начале запроса, не меняется после SwapRegistry:
+// хвосты и чанки ищут модели там же, где корень.
+func TestWithRegistryKeepsSnapshotAcrossSwap(t *testing.T) {
+	origRegistry := model.Registry
+	t.Cleanup(func() { model.Registry = origRegistry })
+
+	oldOrder := &model.Model{Name: "Order", Table: "orders"}
+	model.SwapRegistry(map[string]*model.Model{"Order": oldOrder})
+	ctx := WithRegistry(context.Background())
+
+	newOrder := &model.Model{Name: "Order", Table: "orders_v2"}
+	model.SwapRegistry(map[string]*model.Model{"Order": newOrder, "Item": {Name: "Item"}})
+
+	if m, ok := LookupModel(ctx, "Order"); !ok || m != oldOrder {
+		t.Fatalf("request must keep the registry captured at start, got %+v", m)
+	}
+	if _, ok := LookupModel(ctx, "Item"); ok {
+		t.Fatal("models added by a later swap must not leak into the request")
+	}
+	// повторный WithRegistry не перезаписывает снимок
+	if m, _ := LookupModel(WithRegistry(ctx), "Order"); m != oldOrder {
+		t.Fatal("nested WithRegistry must keep the outer snapshot")
+	}
+	if m, _ := LookupModel(context.Background(), "Order"); m != newOrder {
+		t.Fatal("without WithRegistry lookups use the current registry")
+	}
+}
@@ -5,36 +5,34 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
+	"YrestAPI/internal/auth"
 	"YrestAPI/internal/db"
 	"YrestAPI/internal/logger"
+	"YrestAPI/internal/metrics"
 	"YrestAPI/internal/model"
+	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 )
 
 // ResolveDistinctValues returns a scalar unique-value list and deliberately
 // bypasses presets and relation-tail hydration.
 func ResolveDistinctValues(ctx context.Context, req IndexRequest) ([]any, error) {
-	m, ok := model.Registry[req.Model]
-	if !ok {
-		return nil, fmt.Errorf("resolver: model not found: %s", req.Model)
-	}
-	filters := model.NormalizeFiltersWithAliases(m, req.Filters)
-	field := strings.TrimSpace(model.ExpandAliasPath(m, req.UniqueBy))
-	aliasMap, err := m.CreateAliasMap(m, nil, filters, []string{field + " ASC"})
-	if err != nil {
-		return nil, &model.DistinctValidationError{Message: err.Error()}
-	}
-	query, err := m.BuildDistinctValuesQuery(aliasMap, filters, field, req.Offset, req.Limit)
+	ctx = WithRegistry(ctx)
+	q, err := distinctQuery(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	sqlStr, args, err := query.ToSql()
-	if err != nil {
+	ctx, cancel := WithDeadline(ctx, q.m, nil)
+	defer cancel()
+	if err := checkCost(ctx, q); err != nil {
 		return nil, err
 	}
-	logger.Debug("sql", map[string]any{"endpoint": "/api/index", "sql": sqlStr, "args": args})
-	rows, err := db.Pool.Query(ctx, sqlStr, args...)
+	logger.Debug("sql", map[string]any{"endpoint": "/api/index", "sql": q.sql, "args": q.args})
+	started := time.Now()
+	defer func() { metrics.ObserveQuery(metrics.QueryCount, time.Since(started)) }()
+	rows, err := db.Query(ctx, q.sql, q.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,10 +62,97 @@ func ResolveDistinctValues(ctx context.Context, req IndexRequest) ([]any, error)
 	return values, nil
 }
 
+// distinctQuery строит запрос уникальных значений unique_by.
+func distinctQuery(ctx context.Context, req IndexRequest) (plannedQuery, error) {
+	m, ok := LookupModel(ctx, req.Model)
+	if !ok {
+		return plannedQuery{}, fmt.Errorf("resolver: model not found: %s", req.Model)
+	}
+	if err := Authorize(ctx, m, nil); err != nil {
+		return plannedQuery{}, err
+	}
+	requestFilters, err := req.requestFilters(m)
+	if err != nil {
+		return plannedQuery{}, err
+	}
+	field := strings.TrimSpace(model.ExpandAliasPath(m, req.UniqueBy))
+	if err := AuthorizeFields(ctx, m, requestFilters, field); err != nil {
+		return plannedQuery{}, err
+	}
+	filters, err := ApplyPolicy(ctx, m, requestFilters, "")
+	if err != nil {
+		return plannedQuery{}, err
+	}
+	sorts := []string{field + " ASC"}
+	aliasMap, err := m.CreateAliasMap(m, nil, filters, sorts)
+	if err != nil {
+		return plannedQuery{}, &model.DistinctValidationError{Message: err.Error()}
+	}
+	aliasMap = ScopeAliasMap(ctx, aliasMap)
+	query, err := m.BuildDistinctValuesQuery(aliasMap, filters, field, req.Offset, req.Limit)
+	if err != nil {
+		return plannedQuery{}, err
+	}
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return plannedQuery{}, err
+	}
+	q := plannedQuery{m: m, sql: sqlStr, args: args}
+	if costBudget.enabled() {
+		if q.cost, err = estimateCost(m, nil, aliasMap, filters, sorts, requestFilters, req.Limit); err != nil {
+			return plannedQuery{}, err
+		}
+	}
+	return q, nil
+}
+
+type tailQueryKey struct{}
+
+// withTailQuery помечает контекст дочернего резолвера: его SELECT учитывается
+// в метриках как tail-запрос, а не root.
+func withTailQuery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tailQueryKey{}, true)
+}
+
+func queryKind(ctx context.Context) string {
+	if tail, _ := ctx.Value(tailQueryKey{}).(bool); tail {
+		return metrics.QueryTail
+	}
+	return metrics.QueryRoot
+}
+
 // Главный резолвер
 func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
-	// 0) модель и aliasMap
-	m, ok := model.Registry[req.Model]
+	page, err := ResolvePage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ResolvePage resolves one /api/index page. In keyset mode (Cursor or After
+// set) the page also carries NextCursor when more rows may follow.
+func ResolvePage(ctx context.Context, req IndexRequest) (IndexPage, error) {
+	items, next, err := resolve(ctx, req)
+	if err != nil {
+		return IndexPage{}, err
+	}
+	return IndexPage{Items: items, NextCursor: next}, nil
+}
+
+// indexQuery — модель, пресет, фильтры и карта алиасов корневого запроса
+// /api/index: общая подготовка резолвера и оценки стоимости.
+type indexQuery struct {
+	m              *model.Model
+	preset         *model.DataPreset
+	requestFilters map[string]any // фильтры клиента, без политики
+	filters        map[string]any
+	sorts          []string
+	aliasMap       *model.AliasMap
+}
+
+func prepareIndex(ctx context.Context, req IndexRequest) (*indexQuery, error) {
+	m, ok := LookupModel(ctx, req.Model)
 	if !ok {
 		return nil, fmt.Errorf("resolver: model not found: %s", req.Model)
 	}
@@ -79,10 +164,26 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 		preset = req.PresetObj
 	}
 	if preset == nil {
-		return nil, fmt.Errorf("preset not found: %s.%s", req.Model, req.Preset)
+		return nil, presetNotFound(m, req.Model, req.Preset)
+	}
+	if err := Authorize(ctx, m, preset); err != nil {
+		return nil, err
+	}
+	requestFilters, err := req.requestFilters(m)
+	if err != nil {
+		return nil, err
 	}
-	filters := model.NormalizeFiltersWithAliases(m, req.Filters)
 	sorts := model.NormalizeSortsWithAliases(m, req.Sorts)
+	if queryKind(ctx) == metrics.QueryRoot {
+		// фильтры и сортировки хвостов строит резолвер, а не клиент
+		if err := AuthorizeFields(ctx, m, requestFilters, sorts...); err != nil {
+			return nil, err
+		}
+	}
+	filters, err := ApplyPolicy(ctx, m, requestFilters, req.UnwrapField)
+	if err != nil {
+		return nil, err
+	}
 
 	aliasMap, err := m.CreateAliasMap(m, preset, filters, sorts)
 	if err != nil {
@@ -93,16 +194,71 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 		})
 		return nil, fmt.Errorf("alias map error: %s", err)
 	}
+	// строка поиска для rank берётся из фильтров клиента: политика
+	// оборачивает их в "and", и по итоговым фильтрам её не найти
+	searchText, _ := m.SearchText(requestFilters)
+	aliasMap = ScopeAliasMap(ctx, aliasMap).WithSearchText(searchText)
+	return &indexQuery{m: m, preset: preset, requestFilters: requestFilters, filters: filters, sorts: sorts, aliasMap: aliasMap}, nil
+}
+
+func resolve(ctx context.Context, req IndexRequest) ([]map[string]any, string, error) {
+	// 0) модель и aliasMap; реестр фиксируется один раз на весь запрос
+	ctx = WithRegistry(ctx)
+	q, err := prepareIndex(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+	m, preset, filters, sorts, aliasMap := q.m, q.preset, q.filters, q.sorts, q.aliasMap
+	ctx, cancel := WithDeadline(ctx, m, preset)
+	defer cancel()
+	if queryKind(ctx) == metrics.QueryRoot {
+		snapCtx, release, err := withSnapshot(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		defer release()
+		ctx = withTailSlots(snapCtx)
+	}
 
 	// 1) главный SELECT
-	sb, err := m.BuildIndexQuery(aliasMap, filters, sorts, preset, req.Offset, req.Limit)
+	keyset := req.Cursor || req.After != ""
+	var after []any
+	if keyset {
+		if req.Limit == 0 {
+			return nil, "", &CursorError{Message: "limit is required for cursor pagination"}
+		}
+		if req.Offset > 0 && req.After != "" {
+			return nil, "", &CursorError{Message: "offset cannot be combined with after"}
+		}
+		for _, s := range sorts {
+			if model.IsRankSort(s) && m.Search != nil {
+				return nil, "", &CursorError{Message: "rank sort cannot be combined with cursor pagination"}
+			}
+		}
+		sorts = m.KeysetSorts(sorts)
+		if req.After != "" {
+			if after, err = decodeCursor(req.After, sorts); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	var sb squirrel.SelectBuilder
+	var keys []model.KeysetColumn
+	switch {
+	case keyset:
+		sb, keys, err = m.BuildKeysetIndexQuery(aliasMap, filters, sorts, preset, after, req.Offset, req.Limit)
+	case req.PartitionBy != "":
+		sb, err = m.BuildPartitionedIndexQuery(aliasMap, filters, sorts, preset, req.PartitionBy, req.Offset, req.Limit)
+	default:
+		sb, err = m.BuildIndexQuery(aliasMap, filters, sorts, preset, req.Offset, req.Limit)
+	}
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	sqlStr, args, err := sb.ToSql()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	//
 	logger.Debug("sql", map[string]any{
@@ -111,36 +267,65 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 		"args":     args,
 	})
 
-	rows, err := db.Pool.Query(ctx, sqlStr, args...)
+	// 1.1) бюджет стоимости — только для корневого запроса, хвосты входят в
+	// оценку; поток и конверт уже проверены целиком в CheckCost
+	if needsCostCheck(ctx) {
+		cost, err := estimateCost(m, preset, aliasMap, filters, sorts, q.requestFilters, req.Limit)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := checkCost(ctx, plannedQuery{sql: sqlStr, args: args, cost: cost}); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// слот хвоста держится только на время SQL и чтения строк
+	releaseSlot, err := acquireTailSlot(ctx, req.Model)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	started := time.Now()
+	rows, err := db.Query(ctx, sqlStr, args...)
+	if err != nil {
+		releaseSlot()
+		return nil, "", err
 	}
-	defer rows.Close()
 
 	// функция, восстанавливающая поля из aliasMap
-	items, err := m.ScanFlatRows(rows, preset, aliasMap)
+	items, lastKeys, err := m.ScanKeysetRows(rows, preset, aliasMap, len(keys))
+	rows.Close()
+	releaseSlot()
+	metrics.ObserveQuery(queryKind(ctx), time.Since(started))
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	// курсор следующей страницы — только если страница заполнена целиком
+	nextCursor := ""
+	if keyset && uint64(len(items)) == req.Limit {
+		if nextCursor, err = encodeCursor(sorts, lastKeys); err != nil {
+			return nil, "", err
+		}
 	}
 	if len(items) == 0 {
-		return items, nil
+		return items, nextCursor, nil
 	}
 
 	// 4) определяем хвосты из пресета (рекурсивно по belongs_to)
 	polyTails := collectPolyTails(m, preset, "")
 	tails := collectTails(m, preset /*prefix*/, "")
-	if len(tails) == 0 && len(polyTails) == 0 {
+	treeTails := collectTreeTails(m, preset, "")
+	if len(tails) == 0 && len(polyTails) == 0 && len(treeTails) == 0 {
 		// ⬅️ Хвостов нет — сразу финализируем и выходим
-		if err := finalizeItems(m, preset, items); err != nil {
+		if err := finalizeItems(m, preset, items, auth.RolesFromContext(ctx)); err != nil {
 			logger.Error("resolver_finalize_error", map[string]any{
 				"model":  req.Model,
 				"preset": req.Preset,
 				"error":  err.Error(),
 				"items":  items,
 			})
-			return nil, fmt.Errorf("resolver: finalize: %w", err)
+			return nil, "", fmt.Errorf("resolver: finalize: %w", err)
 		}
-		return items, nil
+		return items, nextCursor, nil
 	}
 
 	// 3) Собираем parentIDs отдельно для КАЖДОГО хвоста, используя rel.PK
@@ -173,6 +358,17 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var rerr error
+	// первая ошибка хвоста отменяет остальные: их результат уже не нужен
+	tailCtx, cancelTails := context.WithCancel(ctx)
+	defer cancelTails()
+	fail := func(err error) {
+		mu.Lock()
+		if rerr == nil {
+			rerr = err
+			cancelTails()
+		}
+		mu.Unlock()
+	}
 
 	for _, t := range tails {
 		t := t // avoid capturing loop variable in goroutine
@@ -187,21 +383,18 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 
 			childModel := t.Rel.GetModelRef()
 			if childModel == nil {
-				mu.Lock()
-				rerr = fmt.Errorf("tail '%s': child model '%s' not found", t.FieldAlias, t.Rel.Model)
-				mu.Unlock()
+				fail(fmt.Errorf("tail '%s': child model '%s' not found", t.FieldAlias, t.Rel.Model))
 				return
 			}
 
 			// вложенный пресет берём ПО ССЫЛКЕ МОДЕЛИ СВЯЗИ
-			var childPreset *model.DataPreset
-			if t.NestedPreset != "" {
+			// (синтетические пресеты, напр. из /graphql, приходят готовой ссылкой)
+			childPreset := t.PresetRef
+			if childPreset == nil && t.NestedPreset != "" {
 				childPreset = childModel.Presets[t.NestedPreset]
 				if childPreset == nil {
-					mu.Lock()
-					rerr = fmt.Errorf("tail '%s': nested preset '%s' not found in model '%s'",
-						t.FieldAlias, t.NestedPreset, childModel.Table)
-					mu.Unlock()
+					fail(fmt.Errorf("tail '%s': nested preset '%s' not found in model '%s'",
+						t.FieldAlias, t.NestedPreset, childModel.Table))
 					return
 				}
 			}
@@ -210,43 +403,45 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 			childFilters := map[string]any{}
 			fk := t.Rel.FK
 
-			// Лимит 1 для has_one, иначе maxLimit
+			// Лимит 1 для has_one, иначе maxLimit; с limit/offset связи —
+			// окно строк на каждого родителя
 			limit := uint64(maxLimit)
+			offset := uint64(0)
+			partitionBy := ""
+			if t.Limit > 0 {
+				limit, offset, partitionBy = t.Limit, t.Offset, fk
+			}
 			if t.Rel.Where != "" {
 				if key, val, ok := parseCondition(t.Rel.Where); ok {
 					childFilters[key] = val
 				}
 			}
 
-			// рекурсивный вызов того же Resolver
-			var childReq IndexRequest
-			if t.Rel.Through == "" {
-				// прямой has_
-				synthetic := makeSyntheticPreset(childPreset, fk)
-				childFilters[fk+"__in"] = ids
-				childReq = IndexRequest{
-					Model:     t.Rel.Model,
-					Preset:    "",
-					Filters:   childFilters,
-					Sorts:     buildOrderSorts(t.Rel.Order /*prefix*/, ""),
-					Offset:    0,
-					Limit:     limit, // has_one отберём первый после группировки
-					PresetObj: synthetic,
+			// рекурсивный вызов того же Resolver — по куску ключей родителей
+			build := func(chunk model.IDList) (IndexRequest, error) {
+				if t.Rel.Through != "" {
+					return makeThroughChildRequest(registryFrom(ctx), m, t.Rel, t.NestedPreset, childPreset, chunk, t.Offset, t.Limit)
 				}
-			} else {
-				childReq, err = MakeThroughChildRequest(m, t.Rel, t.NestedPreset, ids)
-				if err != nil {
-					mu.Lock()
-					rerr = fmt.Errorf("tail '%s': %w", t.FieldAlias, err)
-					mu.Unlock()
-					return
+				// прямой has_
+				filters := make(map[string]any, len(childFilters)+1)
+				for k, v := range childFilters {
+					filters[k] = v
 				}
-			}
-			childItems, err := Resolver(ctx, childReq)
+				filters[fk+"__in"] = chunk
+				return IndexRequest{
+					Model:       t.Rel.Model,
+					Preset:      "",
+					Filters:     filters,
+					Sorts:       buildOrderSorts(t.Rel.Order /*prefix*/, ""),
+					Offset:      offset,
+					Limit:       limit, // has_one отберём первый после группировки
+					PresetObj:   makeSyntheticPreset(childPreset, fk),
+					PartitionBy: partitionBy,
+				}, nil
+			}
+			childItems, err := resolveChunks(withTailQuery(tailCtx), ids, build)
 			if err != nil {
-				mu.Lock()
-				rerr = fmt.Errorf("tail '%s': %w", t.FieldAlias, err)
-				mu.Unlock()
+				fail(fmt.Errorf("tail '%s': %w", t.FieldAlias, err))
 				return
 			}
 			// сгруппируем дочерние по FK (он указывает на родителя)
@@ -262,6 +457,24 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 		}(key, ids)
 	}
 
+	// tree-поля: по одному рекурсивному запросу на поле, параллельно с хвостами
+	treeByTail := make(map[string]map[any]any, len(treeTails))
+	for _, t := range treeTails {
+		t := t
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values, err := resolveTreeTail(tailCtx, t, items)
+			if err != nil {
+				fail(fmt.Errorf("tree '%s': %w", t.FieldAlias, err))
+				return
+			}
+			mu.Lock()
+			treeByTail[prefixFor(strings.TrimSpace(t.TargetPath), t.FieldAlias)] = values
+			mu.Unlock()
+		}()
+	}
+
 	wg.Wait()
 	if rerr != nil {
 		logger.Error("resolver_tail_error", map[string]any{
@@ -270,7 +483,7 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 			"error":  rerr.Error(),
 			"items":  items,
 		})
-		return nil, rerr
+		return nil, "", rerr
 	}
 
 	// 5) Собираем итоговые элементы, склеивая хвосты по алиасам
@@ -327,6 +540,21 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 		}
 	}
 
+	for i := range items {
+		for _, t := range treeTails {
+			ctx := getTargetContext(items[i], t.TargetPath)
+			if ctx == nil {
+				continue
+			}
+			values := treeByTail[prefixFor(strings.TrimSpace(t.TargetPath), t.FieldAlias)]
+			if v, ok := values[treeKey(ctx[t.Rel.PK])]; ok {
+				ctx[t.FieldAlias] = v
+			} else {
+				ctx[t.FieldAlias] = []map[string]any{}
+			}
+		}
+	}
+
 	// 5.5) Полиморфные belongs_to: группы запросов по типу
 	for _, t := range polyTails {
 		typeCol := t.Rel.TypeColumn
@@ -359,7 +587,7 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 
 		typeGrouped := map[string]map[any]map[string]any{}
 		for typ, ids := range byType {
-			childModel := model.Registry[typ]
+			childModel, _ := LookupModel(ctx, typ)
 			if childModel == nil {
 				continue
 			}
@@ -367,18 +595,16 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 			if childPreset == nil {
 				continue
 			}
-			filters := map[string]any{
-				t.Rel.PK + "__in": ids,
-			}
-			childReq := IndexRequest{
-				Model:   typ,
-				Preset:  childPreset.Name,
-				Filters: filters,
-				Limit:   uint64(len(ids)),
-			}
-			childItems, err := Resolver(ctx, childReq)
+			childItems, err := resolveChunks(withTailQuery(ctx), ids, func(chunk model.IDList) (IndexRequest, error) {
+				return IndexRequest{
+					Model:   typ,
+					Preset:  childPreset.Name,
+					Filters: map[string]any{t.Rel.PK + "__in": chunk},
+					Limit:   uint64(len(chunk)),
+				}, nil
+			})
 			if err != nil {
-				return nil, fmt.Errorf("polymorphic tail '%s': %w", t.FieldAlias, err)
+				return nil, "", fmt.Errorf("polymorphic tail '%s': %w", t.FieldAlias, err)
 			}
 			g := map[any]map[string]any{}
 			for _, row := range childItems {
@@ -430,8 +656,8 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 	}
 
 	// 8) финализация formatter/computed уже ПОСЛЕ склейки
-	if err := finalizeItems(m, preset, items); err != nil {
-		return nil, fmt.Errorf("resolver: finalize: %w", err)
+	if err := finalizeItems(m, preset, items, auth.RolesFromContext(ctx)); err != nil {
+		return nil, "", fmt.Errorf("resolver: finalize: %w", err)
 	}
 
 	// 9) for Through: развернём вложенные preset-поля
@@ -439,17 +665,20 @@ func Resolver(ctx context.Context, req IndexRequest) ([]map[string]any, error) {
 		// fk ты уже знаешь (это Source первого поля синтетического пресета)
 		fk := req.PresetObj.Fields[0].Source
 		items = unwrapThrough(items, fk, req.UnwrapField)
-		return items, nil
+		return items, nextCursor, nil
 	}
-	return items, nil
+	return items, nextCursor, nil
 }
 
 type TailSpec struct {
 	FieldAlias   string // ключ в JSON (имя поля пресета)
 	RelKey       string // ключ связи в родительской модели
 	Rel          *model.ModelRelation
-	NestedPreset string // как в YAML (Model.Preset или Preset)
+	NestedPreset string            // как в YAML (Model.Preset или Preset)
+	PresetRef    *model.DataPreset // ссылка на вложенный пресет, если поле её несёт
 	LimitOne     bool
+	Offset       uint64 // has_many: окно строк на одного родителя (Limit 0 — без окна)
+	Limit        uint64
 	TargetPath   string // если задано, кладём результат в TargetPathCtx[FieldAlias]
 	Formatter    string // возможно мусорное поле,
 	// так как форматтеры на has_one/has_many считаются в дочерних вызовах резолвера
@@ -558,12 +787,16 @@ func collectTails(m *model.Model, p *model.DataPreset, prefix string) []TailSpec
 			if strings.TrimSpace(alias) == "" {
 				alias = f.Source
 			}
+			offset, limit := f.TailWindow(rel)
 			out = append(out, TailSpec{
 				FieldAlias:   alias,
 				RelKey:       f.Source,
 				Rel:          rel,
 				NestedPreset: f.NestedPreset,
+				PresetRef:    f.GetPresetRef(),
 				LimitOne:     rel.Type == "has_one",
+				Offset:       offset,
+				Limit:        limit,
 				TargetPath:   prefix,                         // писать в контекст текущей ветки пресета
 				Formatter:    strings.TrimSpace(f.Formatter), // не используется здесь, но сохраняем
 			})
@@ -0,0 +1,38 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+// q у модели без search — 400 до SQL, а не пустой фильтр.
+func TestSearchWithoutConfigIsFilterError(t *testing.T) {
+	origRegistry := model.Registry
+	t.Cleanup(func() { model.Registry = origRegistry })
+	model.Registry = map[string]*model.Model{
+		"Person": {
+			Name:  "Person",
+			Table: "people",
+			Presets: map[string]*model.DataPreset{
+				"item": {Name: "item", Fields: []model.Field{{Source: "id", Type: "int"}}},
+			},
+		},
+	}
+	ctx := context.Background()
+	req := IndexRequest{Model: "Person", Preset: "item", Q: "data"}
+
+	var filterErr *model.FilterError
+	if _, err := ResolvePage(ctx, req); !errors.As(err, &filterErr) {
+		t.Fatalf("index: expected FilterError, got %v", err)
+	}
+	if _, err := ResolveCount(ctx, req); !errors.As(err, &filterErr) {
+		t.Fatalf("count: expected FilterError, got %v", err)
+	}
+	req.UniqueBy = "id"
+	if _, err := ResolveDistinctValues(ctx, req); !errors.As(err, &filterErr) {
+		t.Fatalf("unique_by: expected FilterError, got %v", err)
+	}
+}
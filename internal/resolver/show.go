@@ -0,0 +1,128 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"YrestAPI/internal/model"
+)
+
+// ErrNotFound is returned by ResolveOne when no row matches the primary key.
+var ErrNotFound = errors.New("record not found")
+
+// ShowValidationError denotes an invalid client-provided primary key payload.
+type ShowValidationError struct {
+	Message string
+}
+
+func (e *ShowValidationError) Error() string { return e.Message }
+
+// ShowRequest fetches a single record by primary key.
+// ID is a scalar for single-column keys or a map {column: value} for
+// composite primary_keys.
+type ShowRequest struct {
+	Model      string `json:"model"`
+	Preset     string `json:"preset"`
+	ID         any    `json:"id"`
+	Consistent bool   `json:"consistent"`
+}
+
+// ResolveOne resolves one record through the regular Resolver pipeline and
+// unwraps it. A missing row is reported as ErrNotFound instead of an empty list.
+func ResolveOne(ctx context.Context, req ShowRequest) (map[string]any, error) {
+	ctx = WithRegistry(ctx)
+	m, ok := LookupModel(ctx, req.Model)
+	if !ok {
+		return nil, fmt.Errorf("resolver: model not found: %s", req.Model)
+	}
+	filters, err := primaryKeyFilters(m, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := Resolver(ctx, IndexRequest{
+		Model:      req.Model,
+		Preset:     req.Preset,
+		Filters:    filters,
+		Limit:      1,
+		Consistent: req.Consistent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrNotFound
+	}
+	return items[0], nil
+}
+
+// primaryKeyFilters turns a show id into __in filters over the model primary
+// keys. __in is used instead of __eq so that string keys (UUID, codes) are
+// compared as-is rather than through LOWER(CAST(...)).
+func primaryKeyFilters(m *model.Model, id any) (map[string]any, error) {
+	keys := m.GetPrimaryKeys()
+	if id == nil {
+		return nil, &ShowValidationError{Message: "id is required"}
+	}
+
+	if composite, ok := id.(map[string]any); ok {
+		filters := make(map[string]any, len(keys))
+		for _, key := range keys {
+			v, ok := composite[key]
+			if !ok || v == nil {
+				return nil, &ShowValidationError{Message: fmt.Sprintf("id: primary key column %q is required", key)}
+			}
+			if !isScalarKey(v) {
+				return nil, &ShowValidationError{Message: fmt.Sprintf("id: primary key column %q must be a scalar", key)}
+			}
+			filters[key+"__in"] = []any{keyValue(v)}
+		}
+		if len(composite) != len(keys) {
+			extra := make([]string, 0)
+			for k := range composite {
+				if _, ok := filters[k+"__in"]; !ok {
+					extra = append(extra, k)
+				}
+			}
+			sort.Strings(extra)
+			return nil, &ShowValidationError{Message: fmt.Sprintf("id: unknown primary key columns: %s", strings.Join(extra, ", "))}
+		}
+		return filters, nil
+	}
+
+	if len(keys) > 1 {
+		return nil, &ShowValidationError{Message: fmt.Sprintf("id: model has composite primary key (%s), pass an object", strings.Join(keys, ", "))}
+	}
+	if !isScalarKey(id) {
+		return nil, &ShowValidationError{Message: "id must be a scalar"}
+	}
+	return map[string]any{keys[0] + "__in": []any{keyValue(id)}}, nil
+}
+
+// keyValue turns a json.Number id (the handler decodes with UseNumber) into
+// int64 so bigint keys keep full precision; other numbers are passed as text
+// and cast by PostgreSQL to the key type.
+func keyValue(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	return n.String()
+}
+
+func isScalarKey(v any) bool {
+	switch vv := v.(type) {
+	case string:
+		return strings.TrimSpace(vv) != ""
+	case float64, float32, int, int64, int32, bool, json.Number:
+		return true
+	default:
+		return false
+	}
+}
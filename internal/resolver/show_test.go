@@ -0,0 +1,75 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+func TestPrimaryKeyFilters_SingleKey(t *testing.T) {
+	m := &model.Model{Table: "people"}
+	got, err := primaryKeyFilters(m, float64(5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{"id__in": []any{float64(5)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("filters mismatch: got %#v, want %#v", got, want)
+	}
+}
+
+func TestPrimaryKeyFilters_JSONNumberKeepsBigint(t *testing.T) {
+	m := &model.Model{Table: "people"}
+	got, err := primaryKeyFilters(m, json.Number("9007199254740993"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{"id__in": []any{int64(9007199254740993)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("filters mismatch: got %#v, want %#v", got, want)
+	}
+}
+
+func TestPrimaryKeyFilters_CompositeKey(t *testing.T) {
+	m := &model.Model{Table: "person_contacts", PrimaryKeys: []string{"person_id", "contact_id"}}
+	got, err := primaryKeyFilters(m, map[string]any{"person_id": float64(1), "contact_id": float64(2)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"person_id__in":  []any{float64(1)},
+		"contact_id__in": []any{float64(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("filters mismatch: got %#v, want %#v", got, want)
+	}
+}
+
+func TestPrimaryKeyFilters_Rejects(t *testing.T) {
+	single := &model.Model{Table: "people"}
+	composite := &model.Model{Table: "person_contacts", PrimaryKeys: []string{"person_id", "contact_id"}}
+	cases := []struct {
+		name string
+		m    *model.Model
+		id   any
+	}{
+		{"missing id", single, nil},
+		{"empty string", single, "  "},
+		{"array id", single, []any{1, 2}},
+		{"scalar for composite", composite, float64(1)},
+		{"missing composite column", composite, map[string]any{"person_id": float64(1)}},
+		{"unknown composite column", composite, map[string]any{"person_id": float64(1), "contact_id": float64(2), "kind": "x"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := primaryKeyFilters(tc.m, tc.id)
+			var validationErr *ShowValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected ShowValidationError, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package resolver
+
+import (
+	"context"
+
+	"YrestAPI/internal/db"
+)
+
+// withSnapshot открывает общий снимок БД для запроса с consistent: true или
+// с пресетом, помеченным consistent. Все запросы с возвращённым контекстом —
+// корень, хвосты, count конверта, чанки потока — читают одни и те же данные.
+// Снимок не открывается для недоступных моделей и пресетов: ошибку вернёт
+// сам резолвер.
+func withSnapshot(ctx context.Context, req IndexRequest) (context.Context, func(), error) {
+	noop := func() {}
+	if db.InSnapshot(ctx) {
+		return ctx, noop, nil
+	}
+	m, ok := LookupModel(ctx, req.Model)
+	if !ok {
+		return ctx, noop, nil
+	}
+	preset := req.PresetObj
+	if preset == nil && req.Preset != "" {
+		preset = m.GetPreset(req.Preset)
+	}
+	if !req.Consistent && (preset == nil || !preset.Consistent) {
+		return ctx, noop, nil
+	}
+	if Authorize(ctx, m, preset) != nil {
+		return ctx, noop, nil
+	}
+	return db.BeginSnapshot(ctx)
+}
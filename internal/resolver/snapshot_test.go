@@ -0,0 +1,44 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/model"
+)
+
+// Снимок открывается только по запросу и только для доступных пресетов:
+// db.Pool в тестах не инициализирован, и BeginSnapshot завершился бы паникой.
+func TestWithSnapshotSkipsWhenNotNeeded(t *testing.T) {
+	origRegistry := model.Registry
+	t.Cleanup(func() { model.Registry = origRegistry })
+	model.Registry = map[string]*model.Model{
+		"Order": {
+			Name:  "Order",
+			Table: "orders",
+			Presets: map[string]*model.DataPreset{
+				"item": {Name: "item"},
+				"audit": {
+					Name:       "audit",
+					Consistent: true,
+					Access:     &model.AccessRule{Roles: model.StringList{"auditor"}},
+				},
+			},
+		},
+	}
+	ctx := auth.WithClaims(context.Background(), map[string]any{"roles": []any{"staff"}})
+	cases := []IndexRequest{
+		{Model: "Order", Preset: "item"},
+		{Model: "Order", Preset: "audit"},
+		{Model: "Missing", Preset: "item", Consistent: true},
+	}
+	for _, req := range cases {
+		got, release, err := withSnapshot(ctx, req)
+		if err != nil || db.InSnapshot(got) {
+			t.Fatalf("%+v: expected no snapshot, got err=%v", req, err)
+		}
+		release()
+	}
+}
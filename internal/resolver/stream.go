@@ -0,0 +1,70 @@
+package resolver
+
+import "context"
+
+var streamChunkSize = uint64(500) // корневых строк на один чанк потоковой выдачи
+
+// SetStreamChunkSize sets how many root rows StreamIndex resolves per chunk.
+// It is meant to be called once at startup; non-positive values are ignored.
+func SetStreamChunkSize(n int64) {
+	if n > 0 {
+		streamChunkSize = uint64(n)
+	}
+}
+
+// StreamIndex resolves req chunk by chunk and passes every finalized chunk to
+// emit. Root rows are paged by keyset cursor and tails are hydrated per chunk,
+// so memory is bounded by the chunk size rather than by the result size.
+// req.Limit caps the total number of streamed rows (0 means no cap).
+// With consistent every chunk reads the same database snapshot.
+func StreamIndex(ctx context.Context, req IndexRequest, emit func([]map[string]any) error) error {
+	// все чанки видят один реестр, а с consistent — и один снимок; дедлайн
+	// у каждого чанка свой (ResolvePage), иначе QUERY_TIMEOUT_MS обрывал бы
+	// длинную выгрузку посередине
+	ctx = WithRegistry(ctx)
+	// бюджет стоимости — на весь поток: limit запроса, а не размер чанка
+	ctx, err := CheckCost(ctx, req)
+	if err != nil {
+		return err
+	}
+	ctx, release, err := withSnapshot(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer release()
+	chunk := req
+	chunk.Cursor = true
+	chunk.Envelope = false
+	remaining := req.Limit
+	for {
+		size := streamChunkSize
+		if req.Limit > 0 && remaining < size {
+			size = remaining
+		}
+		chunk.Limit = size
+
+		page, err := ResolvePage(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		if len(page.Items) > 0 {
+			if err := emit(page.Items); err != nil {
+				return err
+			}
+		}
+		if req.Limit > 0 {
+			remaining -= uint64(len(page.Items))
+			if remaining == 0 {
+				return nil
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk.After = page.NextCursor
+		chunk.Offset = 0
+	}
+}
@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/metrics"
+)
+
+// Пределы одновременных tail-запросов. Слот занимается только на время
+// SQL хвоста и чтения его строк, вложенные хвосты берут свои слоты уже
+// после освобождения родительского, поэтому глубокие пресеты не могут
+// заблокировать друг друга.
+var (
+	tailGlobalSlots     chan struct{} // на весь процесс; nil — без ограничения
+	tailRequestCapacity int           // на один корневой запрос; 0 — без ограничения
+)
+
+// SetTailConcurrency sets how many tail queries may run at once per root
+// request and across the process. Non-positive values disable the limit.
+// It is meant to be called once at startup.
+func SetTailConcurrency(perRequest, global int) {
+	tailRequestCapacity = max(perRequest, 0)
+	tailGlobalSlots = nil
+	if global > 0 {
+		tailGlobalSlots = make(chan struct{}, global)
+	}
+}
+
+type tailSlotsKey struct{}
+
+// withTailSlots заводит семафор хвостов корневого запроса; хвосты
+// получают его через контекст.
+func withTailSlots(ctx context.Context) context.Context {
+	if tailRequestCapacity <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, tailSlotsKey{}, make(chan struct{}, tailRequestCapacity))
+}
+
+// acquireTailSlot ждёт слот запроса и глобальный слот для tail-запроса.
+// Для корневого запроса ничего не делает. Время ожидания попадает в
+// метрики, а при заметной очереди — в лог.
+func acquireTailSlot(ctx context.Context, modelName string) (func(), error) {
+	if queryKind(ctx) != metrics.QueryTail {
+		return func() {}, nil
+	}
+	reqSlots, _ := ctx.Value(tailSlotsKey{}).(chan struct{})
+	globalSlots := tailGlobalSlots
+
+	started := time.Now()
+	queued := false
+	acquire := func(slots chan struct{}) error {
+		if slots == nil {
+			return nil
+		}
+		select {
+		case slots <- struct{}{}:
+			return nil
+		default:
+		}
+		queued = true
+		select {
+		case slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := acquire(reqSlots); err != nil {
+		return nil, err
+	}
+	if err := acquire(globalSlots); err != nil {
+		if reqSlots != nil {
+			<-reqSlots
+		}
+		return nil, err
+	}
+
+	wait := time.Since(started)
+	metrics.ObserveTailWait(wait)
+	if queued {
+		logger.Info("tail_queued", map[string]any{
+			"model":   modelName,
+			"wait_ms": wait.Milliseconds(),
+		})
+	}
+	return func() {
+		if globalSlots != nil {
+			<-globalSlots
+		}
+		if reqSlots != nil {
+			<-reqSlots
+		}
+	}, nil
+}
@@ -0,0 +1,74 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireTailSlot(t *testing.T) {
+	t.Cleanup(func() { SetTailConcurrency(0, 0) })
+	SetTailConcurrency(1, 2)
+
+	// корневой запрос слотов не занимает
+	root := withTailSlots(context.Background())
+	for i := 0; i < 3; i++ {
+		release, err := acquireTailSlot(root, "Person")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+	}
+
+	tail := withTailQuery(root)
+	release, err := acquireTailSlot(tail, "Contact")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// второй хвост того же запроса ждёт, другой запрос получает слот сразу
+	other := withTailQuery(withTailSlots(context.Background()))
+	releaseOther, err := acquireTailSlot(other, "Contact")
+	if err != nil {
+		t.Fatalf("other request must not wait: %v", err)
+	}
+	releaseOther()
+
+	waitCtx, cancel := context.WithTimeout(tail, 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquireTailSlot(waitCtx, "Contact"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline while queued, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		r, err := acquireTailSlot(tail, "Contact")
+		if err == nil {
+			r()
+		}
+		done <- err
+	}()
+	release()
+	if err := <-done; err != nil {
+		t.Fatalf("queued tail must get the released slot: %v", err)
+	}
+}
+
+func TestAcquireTailSlotGlobal(t *testing.T) {
+	t.Cleanup(func() { SetTailConcurrency(0, 0) })
+	SetTailConcurrency(0, 1)
+
+	a := withTailQuery(withTailSlots(context.Background()))
+	release, err := acquireTailSlot(a, "Contact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	b, cancel := context.WithTimeout(withTailQuery(withTailSlots(context.Background())), 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquireTailSlot(b, "Contact"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("global limit must apply across requests, got %v", err)
+	}
+}
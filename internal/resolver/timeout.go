@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"YrestAPI/internal/metrics"
+	"YrestAPI/internal/model"
+)
+
+var queryTimeout time.Duration // общий предел из QUERY_TIMEOUT_MS, 0 — без ограничения
+
+// SetQueryTimeout sets the default request deadline used when neither the
+// preset nor the model declares timeout_ms. It is meant to be called once
+// at startup; non-positive values disable the default.
+func SetQueryTimeout(d time.Duration) {
+	queryTimeout = max(d, 0)
+}
+
+type deadlineKey struct{}
+
+// WithDeadline ограничивает корневой запрос временем из timeout_ms пресета,
+// модели или QUERY_TIMEOUT_MS. Хвосты дедлайн не переопределяют: они
+// наследуют его от корня, так что предел действует на весь запрос. Так же
+// страница и count конверта: дедлайн, однажды заданный для запроса,
+// повторно не выводится. Чанк потока — отдельный корневой запрос со своим
+// дедлайном. Более ранний дедлайн вызывающего сохраняется.
+func WithDeadline(ctx context.Context, m *model.Model, p *model.DataPreset) (context.Context, context.CancelFunc) {
+	if queryKind(ctx) != metrics.QueryRoot || ctx.Value(deadlineKey{}) != nil {
+		return ctx, func() {}
+	}
+	d := m.QueryTimeout(p, queryTimeout)
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return context.WithValue(ctx, deadlineKey{}, true), cancel
+}
+
+// withRequestDeadline — WithDeadline для составного запроса (конверт): модель и пресет берутся из req, недоступные пропускаются —
+// ошибку вернёт сам резолвер.
+func withRequestDeadline(ctx context.Context, req IndexRequest) (context.Context, context.CancelFunc) {
+	m, ok := LookupModel(ctx, req.Model)
+	if !ok {
+		return ctx, func() {}
+	}
+	preset := req.PresetObj
+	if preset == nil && req.Preset != "" {
+		preset = m.GetPreset(req.Preset)
+	}
+	return WithDeadline(ctx, m, preset)
+}
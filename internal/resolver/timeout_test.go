@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/model"
+)
+
+func TestWithDeadline(t *testing.T) {
+	orig := queryTimeout
+	t.Cleanup(func() { queryTimeout = orig })
+	SetQueryTimeout(time.Minute)
+
+	m := &model.Model{TimeoutMS: 30000}
+	p := &model.DataPreset{TimeoutMS: 2000}
+	cases := []struct {
+		name   string
+		m      *model.Model
+		p      *model.DataPreset
+		expect time.Duration
+	}{
+		{"preset", m, p, 2 * time.Second},
+		{"model", m, nil, 30 * time.Second},
+		{"global", &model.Model{}, nil, time.Minute},
+	}
+	for _, tc := range cases {
+		ctx, cancel := WithDeadline(context.Background(), tc.m, tc.p)
+		deadline, ok := ctx.Deadline()
+		cancel()
+		if left := time.Until(deadline); !ok || left > tc.expect || left < tc.expect-time.Second {
+			t.Fatalf("%s: deadline in %v, want about %v", tc.name, left, tc.expect)
+		}
+	}
+
+	// хвосты наследуют дедлайн корня и не продлевают его
+	if _, ok := func() (time.Time, bool) {
+		ctx, cancel := WithDeadline(withTailQuery(context.Background()), m, p)
+		defer cancel()
+		return ctx.Deadline()
+	}(); ok {
+		t.Fatal("tail query must not get its own deadline")
+	}
+
+	// более ранний дедлайн вызывающего сохраняется
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	ctx, cancel := WithDeadline(parent, m, nil)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); time.Until(deadline) > time.Second {
+		t.Fatal("caller deadline must win when it is earlier")
+	}
+
+	// повторный WithDeadline (чанк потока, count конверта) не продлевает
+	// дедлайн, уже заданный запросу
+	outer, cancelOuter := WithDeadline(context.Background(), m, p)
+	defer cancelOuter()
+	outerDeadline, _ := outer.Deadline()
+	time.Sleep(10 * time.Millisecond)
+	inner, cancelInner := WithDeadline(outer, m, p)
+	defer cancelInner()
+	if innerDeadline, _ := inner.Deadline(); !innerDeadline.Equal(outerDeadline) {
+		t.Fatalf("nested call must keep the request deadline: %v != %v", innerDeadline, outerDeadline)
+	}
+
+	SetQueryTimeout(0)
+	ctx, cancel = WithDeadline(context.Background(), &model.Model{}, nil)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("no timeout configured: expected no deadline")
+	}
+}
@@ -0,0 +1,309 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/metrics"
+	"YrestAPI/internal/model"
+	"github.com/google/uuid"
+)
+
+// TreeTailSpec — поле пресета со связью type: tree. В отличие от has_many
+// поддерево (или цепочка родителей) приходит одним WITH RECURSIVE запросом
+// на всех родителей сразу, а не вызовом резолвера на каждый уровень.
+type TreeTailSpec struct {
+	FieldAlias   string
+	RelKey       string
+	Rel          *model.ModelRelation
+	NestedPreset string
+	PresetRef    *model.DataPreset
+	MaxDepth     int    // 0 — без ограничения
+	TargetPath   string // контекст ветки пресета, как у TailSpec
+}
+
+// treeEdge — строка рекурсивного запроса: узел id с родителем parent на
+// расстоянии depth от исходной строки root.
+type treeEdge struct {
+	Root, ID, Parent any
+	Depth            int
+}
+
+// Собираем tree-поля из пресета (рекурсивно по belongs_to)
+func collectTreeTails(m *model.Model, p *model.DataPreset, prefix string) []TreeTailSpec {
+	out := []TreeTailSpec{}
+	if p == nil {
+		return out
+	}
+	for i := range p.Fields {
+		f := &p.Fields[i]
+		if f.Type != "preset" {
+			continue
+		}
+		rel, ok := m.Relations[f.Source]
+		if !ok || rel == nil {
+			continue
+		}
+		switch {
+		case rel.Type == "tree":
+			alias := f.Alias
+			if strings.TrimSpace(alias) == "" {
+				alias = f.Source
+			}
+			out = append(out, TreeTailSpec{
+				FieldAlias:   alias,
+				RelKey:       f.Source,
+				Rel:          rel,
+				NestedPreset: f.NestedPreset,
+				PresetRef:    f.GetPresetRef(),
+				MaxDepth:     f.TreeDepth(rel),
+				TargetPath:   prefix,
+			})
+		case rel.Type == "belongs_to" && !rel.Polymorphic && rel.GetModelRef() != nil:
+			nested := f.GetPresetRef()
+			if nested == nil && f.NestedPreset != "" {
+				nested = rel.GetModelRef().Presets[f.NestedPreset]
+			}
+			if nested != nil {
+				out = append(out, collectTreeTails(rel.GetModelRef(), nested, prefixFor(prefix, f.Source))...)
+			}
+		}
+	}
+	return out
+}
+
+// resolveTreeTail догружает tree-поле для строк items: один рекурсивный
+// запрос рёбер, затем строки узлов по пресету поля. Возвращает значение поля
+// для каждого ключа родителя (ключи нормализованы treeKey).
+func resolveTreeTail(ctx context.Context, t TreeTailSpec, items []map[string]any) (map[any]any, error) {
+	nodeModel := t.Rel.GetModelRef()
+	if nodeModel == nil {
+		return nil, fmt.Errorf("tree '%s': model '%s' not found", t.FieldAlias, t.Rel.Model)
+	}
+	nested := t.PresetRef
+	if nested == nil {
+		nested = nodeModel.Presets[t.NestedPreset]
+	}
+	if nested == nil {
+		return nil, fmt.Errorf("tree '%s': nested preset '%s' not found in model '%s'", t.FieldAlias, t.NestedPreset, nodeModel.Name)
+	}
+
+	pk := t.Rel.PK
+	seen := map[any]struct{}{}
+	roots := make(model.IDList, 0, len(items))
+	for _, it := range items {
+		c := getTargetContext(it, t.TargetPath)
+		if c == nil || c[pk] == nil {
+			continue
+		}
+		if _, ok := seen[treeKey(c[pk])]; !ok {
+			seen[treeKey(c[pk])] = struct{}{}
+			roots = append(roots, c[pk])
+		}
+	}
+	if len(roots) == 0 {
+		return nil, nil
+	}
+
+	edges, err := queryTreeEdges(ctx, nodeModel, t, roots)
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs := make([]any, 0, len(edges))
+	seenNode := map[any]struct{}{}
+	for _, e := range edges {
+		if _, ok := seenNode[e.ID]; !ok {
+			seenNode[e.ID] = struct{}{}
+			nodeIDs = append(nodeIDs, e.ID)
+		}
+	}
+
+	// строки узлов: пресет поля без самой tree-связи (её раскрываем здесь)
+	// и с ключом узла для склейки
+	nodePreset, addedPK := treeNodePreset(nested, t.RelKey, pk)
+	var rows []map[string]any
+	if len(nodeIDs) > 0 {
+		rows, err = resolveChunks(withTailQuery(ctx), nodeIDs, func(chunk model.IDList) (IndexRequest, error) {
+			return IndexRequest{
+				Model:     nodeModel.Name,
+				Filters:   map[string]any{pk + "__in": chunk},
+				Sorts:     buildOrderSorts(t.Rel.Order, ""),
+				Limit:     uint64(len(chunk)),
+				PresetObj: nodePreset,
+			}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Rel.TreeDirection() == model.TreeAncestors {
+		return foldAncestors(edges, rows, pk, addedPK), nil
+	}
+	return foldDescendants(roots, edges, rows, t.FieldAlias, pk, addedPK, t.MaxDepth), nil
+}
+
+// queryTreeEdges выполняет WITH RECURSIVE запрос связи; слот хвоста держится
+// только на время SQL и чтения строк.
+func queryTreeEdges(ctx context.Context, m *model.Model, t TreeTailSpec, roots model.IDList) ([]treeEdge, error) {
+	claims, _ := auth.ClaimsFromContext(ctx)
+	sqlStr, args, err := m.BuildTreeQuery(t.Rel, roots, t.MaxDepth, claims)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withTailQuery(ctx)
+	releaseSlot, err := acquireTailSlot(ctx, m.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseSlot()
+	started := time.Now()
+	rows, err := db.Query(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var edges []treeEdge
+	for rows.Next() {
+		var root, id, parent any
+		var depth int32
+		if err := rows.Scan(&root, &id, &parent, &depth); err != nil {
+			return nil, err
+		}
+		edges = append(edges, treeEdge{Root: treeKey(root), ID: treeKey(id), Parent: treeKey(parent), Depth: int(depth)})
+	}
+	metrics.ObserveQuery(metrics.QueryTail, time.Since(started))
+	return edges, rows.Err()
+}
+
+// treeNodePreset копирует пресет узла без поля relKey и добавляет ключ pk,
+// если пресет его не выбирает; addedPK сообщает, что ключ нужно убрать из
+// ответа после склейки.
+func treeNodePreset(orig *model.DataPreset, relKey, pk string) (*model.DataPreset, bool) {
+	fields := make([]model.Field, 0, len(orig.Fields)+1)
+	hasPK := false
+	for _, f := range orig.Fields {
+		if f.Type == "preset" && f.Source == relKey {
+			continue
+		}
+		if f.Source == pk && (strings.TrimSpace(f.Alias) == "" || f.Alias == pk) && f.Type != "preset" {
+			hasPK = true
+		}
+		fields = append(fields, f)
+	}
+	if !hasPK {
+		fields = append(fields, model.Field{Source: pk, Type: "int", Alias: pk})
+	}
+	return &model.DataPreset{
+		Name:           orig.Name,
+		Fields:         fields,
+		FieldsAliasMap: orig.FieldsAliasMap,
+	}, !hasPK
+}
+
+// foldDescendants раскладывает узлы по родителям и собирает для каждого
+// корня вложенные массивы alias. Порядок детей — порядок строк узлов
+// (order связи). Узлы, скрытые политиками, отсекаются вместе с поддеревом.
+func foldDescendants(roots model.IDList, edges []treeEdge, rows []map[string]any, alias, pk string, addedPK bool, maxDepth int) map[any]any {
+	byID := make(map[any]map[string]any, len(rows))
+	for _, row := range rows {
+		byID[treeKey(row[pk])] = row
+	}
+	parentOf := make(map[any]any, len(edges))
+	for _, e := range edges {
+		parentOf[e.ID] = e.Parent
+	}
+	children := map[any][]any{}
+	for _, row := range rows {
+		id := treeKey(row[pk])
+		if parent, ok := parentOf[id]; ok {
+			children[parent] = append(children[parent], id)
+		}
+	}
+
+	var build func(parent any, depth int, path map[any]bool) []map[string]any
+	build = func(parent any, depth int, path map[any]bool) []map[string]any {
+		out := []map[string]any{}
+		if maxDepth > 0 && depth > maxDepth {
+			return out
+		}
+		for _, id := range children[parent] {
+			if path[id] {
+				continue // цикл в данных
+			}
+			node := maps.Clone(byID[id])
+			path[id] = true
+			node[alias] = build(id, depth+1, path)
+			delete(path, id)
+			if addedPK {
+				delete(node, pk)
+			}
+			out = append(out, node)
+		}
+		return out
+	}
+
+	out := make(map[any]any, len(roots))
+	for _, r := range roots {
+		key := treeKey(r)
+		out[key] = build(key, 1, map[any]bool{key: true})
+	}
+	return out
+}
+
+// foldAncestors собирает для каждого корня цепочку родителей: от самого
+// верхнего предка до прямого родителя. Скрытый политиками предок обрывает
+// цепочку — всё, что выше него, тоже не отдаётся.
+func foldAncestors(edges []treeEdge, rows []map[string]any, pk string, addedPK bool) map[any]any {
+	byID := make(map[any]map[string]any, len(rows))
+	for _, row := range rows {
+		byID[treeKey(row[pk])] = row
+	}
+	chains := map[any][]treeEdge{}
+	for _, e := range edges {
+		chains[e.Root] = append(chains[e.Root], e)
+	}
+	out := make(map[any]any, len(chains))
+	for root, chain := range chains {
+		sort.Slice(chain, func(i, j int) bool { return chain[i].Depth < chain[j].Depth })
+		list := make([]map[string]any, 0, len(chain))
+		for _, e := range chain {
+			row, ok := byID[e.ID]
+			if !ok {
+				break
+			}
+			node := maps.Clone(row)
+			if addedPK {
+				delete(node, pk)
+			}
+			list = append(list, node)
+		}
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+		out[root] = list
+	}
+	return out
+}
+
+// treeKey приводит ключ узла к сравнимому виду: целые — к int64, UUID из
+// pgx ([16]byte) — к строке, как их отдаёт ScanFlatRows.
+func treeKey(v any) any {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case [16]byte:
+		return uuid.UUID(x).String()
+	}
+	return v
+}
@@ -0,0 +1,102 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"YrestAPI/internal/model"
+)
+
+// Дерево: 10 → 11, 12; 11 → 13. Строки узлов уже отсортированы по order связи.
+func treeRows() []map[string]any {
+	return []map[string]any{
+		{"id": int32(12), "name": "Frontend"},
+		{"id": int32(11), "name": "Platform"},
+		{"id": int32(13), "name": "Storage"},
+	}
+}
+
+func TestFoldDescendants(t *testing.T) {
+	edges := []treeEdge{
+		{Root: int64(10), ID: int64(11), Parent: int64(10), Depth: 1},
+		{Root: int64(10), ID: int64(12), Parent: int64(10), Depth: 1},
+		{Root: int64(10), ID: int64(13), Parent: int64(11), Depth: 2},
+	}
+	out := foldDescendants(model.IDList{int32(10), int32(12)}, edges, treeRows(), "children", "id", false, 0)
+
+	want := []map[string]any{
+		{"id": int32(12), "name": "Frontend", "children": []map[string]any{}},
+		{"id": int32(11), "name": "Platform", "children": []map[string]any{
+			{"id": int32(13), "name": "Storage", "children": []map[string]any{}},
+		}},
+	}
+	if !reflect.DeepEqual(out[int64(10)], want) {
+		t.Fatalf("unexpected subtree: %#v", out[int64(10)])
+	}
+	if got := out[int64(12)]; !reflect.DeepEqual(got, []map[string]any{}) {
+		t.Fatalf("leaf must get an empty array, got %#v", got)
+	}
+
+	// max_depth 1 отрезает внуков, даже если строки узлов пришли
+	limited := foldDescendants(model.IDList{int64(10)}, edges, treeRows(), "children", "id", true, 1)
+	for _, node := range limited[int64(10)].([]map[string]any) {
+		if _, ok := node["id"]; ok {
+			t.Fatalf("added pk must be removed: %#v", node)
+		}
+		if kids := node["children"].([]map[string]any); len(kids) != 0 {
+			t.Fatalf("max_depth 1 must stop at children, got %#v", kids)
+		}
+	}
+}
+
+func TestFoldDescendantsPrunesHiddenNodes(t *testing.T) {
+	edges := []treeEdge{
+		{Root: int64(10), ID: int64(11), Parent: int64(10), Depth: 1},
+		{Root: int64(10), ID: int64(13), Parent: int64(11), Depth: 2},
+	}
+	// узел 11 скрыт политикой — вместе с ним пропадает и 13
+	rows := []map[string]any{{"id": int32(13), "name": "Storage"}}
+	out := foldDescendants(model.IDList{int64(10)}, edges, rows, "children", "id", false, 0)
+	if got := out[int64(10)]; !reflect.DeepEqual(got, []map[string]any{}) {
+		t.Fatalf("hidden node must prune its subtree, got %#v", got)
+	}
+}
+
+func TestFoldAncestors(t *testing.T) {
+	edges := []treeEdge{
+		{Root: int64(13), ID: int64(11), Parent: int64(10), Depth: 1},
+		{Root: int64(13), ID: int64(10), Parent: nil, Depth: 2},
+	}
+	rows := []map[string]any{
+		{"id": int32(10), "name": "Engineering"},
+		{"id": int32(11), "name": "Platform"},
+	}
+	out := foldAncestors(edges, rows, "id", false)
+	want := []map[string]any{
+		{"id": int32(10), "name": "Engineering"},
+		{"id": int32(11), "name": "Platform"},
+	}
+	if !reflect.DeepEqual(out[int64(13)], want) {
+		t.Fatalf("ancestors must go from the root down, got %#v", out[int64(13)])
+	}
+
+	// скрытый прямой родитель обрывает цепочку
+	out = foldAncestors(edges, rows[:1], "id", false)
+	if got := out[int64(13)]; !reflect.DeepEqual(got, []map[string]any{}) {
+		t.Fatalf("hidden parent must cut the chain, got %#v", got)
+	}
+}
+
+func TestTreeNodePreset(t *testing.T) {
+	orig := &model.DataPreset{Name: "tree", Fields: []model.Field{
+		{Source: "name", Type: "string"},
+		{Source: "subtree", Type: "preset", NestedPreset: "tree", Alias: "children"},
+	}}
+	p, added := treeNodePreset(orig, "subtree", "id")
+	if !added || len(p.Fields) != 2 || p.Fields[1].Source != "id" {
+		t.Fatalf("expected tree field dropped and pk added, got %#v", p.Fields)
+	}
+	if len(orig.Fields) != 2 || orig.Fields[1].Source != "subtree" {
+		t.Fatalf("original preset must stay intact")
+	}
+}
@@ -1,6 +1,11 @@
 package resolver
 
-import "YrestAPI/internal/model"
+import (
+	"fmt"
+	"strings"
+
+	"YrestAPI/internal/model"
+)
 
 var maxLimit = uint64(1000) // максимальный лимит для запросов
 type IndexRequest struct {
@@ -11,9 +16,43 @@ type IndexRequest struct {
 	Offset        uint64                 `json:"offset"`
 	Limit         uint64                 `json:"limit"`
 	UniqueBy      string                 `json:"unique_by"`
-	ThroughFor    string                 `json:"-"` // имя связи в промежуточной модели, которую нужно вернуть (напр. "contact")
-	ThroughPreset string                 `json:"-"` // пресет конечной модели для этой связи (напр. "item")
+	Cursor        bool                   `json:"cursor"`     // keyset-пагинация с первой страницы
+	After         string                 `json:"after"`      // непрозрачный курсор предыдущей страницы (включает keyset-режим)
+	Envelope      bool                   `json:"envelope"`   // ответ {items,total,offset,limit,next_cursor} вместо массива
+	Format        string                 `json:"format"`     // выгрузка: "csv" или "xlsx" (альтернатива заголовку Accept)
+	Explode       bool                   `json:"explode"`    // выгрузка: строка на каждый элемент has_many вместо склейки
+	Consistent    bool                   `json:"consistent"` // корень и хвосты читают один снимок БД
+	Q             string                 `json:"q"`          // полнотекстовый поиск по полям search модели
+	ThroughFor    string                 `json:"-"`          // имя связи в промежуточной модели, которую нужно вернуть (напр. "contact")
+	ThroughPreset string                 `json:"-"`          // пресет конечной модели для этой связи (напр. "item")
 	// служебные (только для внутренних вызовов)
 	PresetObj   *model.DataPreset `json:"-"` // синтетический пресет (если задан — имеет приоритет над Preset)
 	UnwrapField string            `json:"-"` // какое preset-поле развернуть в конце (например "contact")
+	PartitionBy string            `json:"-"` // Offset/Limit действуют на каждую группу строк с одинаковым значением этой колонки
+	CountOnly   bool              `json:"-"` // запрос — только total (ResolveCount), так его и оценивает CheckCost
+}
+
+// IndexPage — результат ResolvePage: элементы страницы и курсор следующей
+// (пустой, если страница последняя или keyset-режим не запрошен).
+type IndexPage struct {
+	Items      []map[string]any
+	NextCursor string
+}
+
+// requestFilters возвращает filters запроса, нормализованные по модели;
+// непустой q добавляется фильтром "__fts" — поиском по полям search модели.
+// q у модели без search — ошибка клиента, а не пустой фильтр.
+func (r IndexRequest) requestFilters(m *model.Model) (map[string]any, error) {
+	if strings.TrimSpace(r.Q) == "" {
+		return model.NormalizeFiltersWithAliases(m, r.Filters), nil
+	}
+	if m.Search == nil {
+		return nil, &model.FilterError{Message: fmt.Sprintf("model %s has no search config for q", m.Name)}
+	}
+	out := make(map[string]any, len(r.Filters)+1)
+	for k, v := range r.Filters {
+		out[k] = v
+	}
+	out[model.SearchFilterKey] = r.Q
+	return model.NormalizeFiltersWithAliases(m, out), nil
 }
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"YrestAPI/internal/auth"
+)
+
+func TestWithAuth_APIKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.yml")
+	body := "keys:\n  - id: batch\n    hash: " + auth.HashAPIKey("s3cret") + "\n    claims:\n      sub: batch-job\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write api keys: %v", err)
+	}
+	store, err := auth.NewAPIKeyStore(path, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore failed: %v", err)
+	}
+
+	var subject any
+	h := withAuth(&authenticators{apiKeys: store}, func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := auth.ClaimsFromContext(r.Context())
+		subject = claims["sub"]
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name    string
+		header  string
+		value   string
+		status  int
+		subject any
+	}{
+		{"x-api-key", "X-API-Key", "s3cret", http.StatusOK, "batch-job"},
+		{"authorization", "Authorization", "ApiKey s3cret", http.StatusOK, "batch-job"},
+		{"wrong key", "X-API-Key", "nope", http.StatusUnauthorized, nil},
+		{"bearer without jwt", "Authorization", "Bearer abc", http.StatusUnauthorized, nil},
+		{"no credentials", "", "", http.StatusUnauthorized, nil},
+	}
+	for _, tc := range cases {
+		subject = nil
+		req := httptest.NewRequest(http.MethodPost, "/api/index", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		w := httptest.NewRecorder()
+		h(w, req)
+		if w.Code != tc.status || subject != tc.subject {
+			t.Fatalf("%s: got status %d subject %v", tc.name, w.Code, subject)
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 var registryReadyFunc = func() bool {
-	return len(model.Registry) > 0
+	return len(model.CurrentRegistry()) > 0
 }
 
 var dbReadyFunc = func(ctx context.Context) bool {
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"net/http"
+
+	"YrestAPI/internal/db"
+	"YrestAPI/internal/metrics"
+	"YrestAPI/internal/model"
+)
+
+// metricsHandler отдаёт метрики в текстовом формате Prometheus: счётчики
+// запросов и SQL из пакета metrics плюс снимки пула pgx и кеша алиасов.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	metrics.WriteAll(w)
+
+	cache := model.GetAliasCacheStats()
+	metrics.WriteCounter(w, "yrest_alias_cache_hits_total", "Alias map cache hits.", float64(cache.Hits))
+	metrics.WriteCounter(w, "yrest_alias_cache_misses_total", "Alias map cache misses.", float64(cache.Misses))
+	metrics.WriteCounter(w, "yrest_alias_cache_evictions_total", "Alias map cache entries removed by TTL or registry reload.", float64(cache.Evictions))
+	metrics.WriteGauge(w, "yrest_alias_cache_entries", "Alias maps currently cached.", float64(cache.Entries))
+	metrics.WriteGauge(w, "yrest_alias_cache_bytes", "Estimated size of cached alias maps.", float64(cache.Bytes))
+	metrics.WriteGauge(w, "yrest_alias_cache_max_bytes", "ALIAS_CACHE_MAX_BYTES, 0 means unlimited.", float64(cache.MaxBytes))
+
+	if db.Pool == nil {
+		return
+	}
+	st := db.Pool.Stat()
+	metrics.WriteGauge(w, "yrest_db_pool_acquired_conns", "Connections currently in use.", float64(st.AcquiredConns()))
+	metrics.WriteGauge(w, "yrest_db_pool_idle_conns", "Idle connections in the pool.", float64(st.IdleConns()))
+	metrics.WriteGauge(w, "yrest_db_pool_constructing_conns", "Connections being established.", float64(st.ConstructingConns()))
+	metrics.WriteGauge(w, "yrest_db_pool_total_conns", "Total connections in the pool.", float64(st.TotalConns()))
+	metrics.WriteGauge(w, "yrest_db_pool_max_conns", "Maximum pool size.", float64(st.MaxConns()))
+	metrics.WriteCounter(w, "yrest_db_pool_acquire_total", "Successful connection acquires.", float64(st.AcquireCount()))
+	metrics.WriteCounter(w, "yrest_db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.", st.AcquireDuration().Seconds())
+	metrics.WriteCounter(w, "yrest_db_pool_empty_acquire_total", "Acquires that waited because the pool was empty.", float64(st.EmptyAcquireCount()))
+	metrics.WriteCounter(w, "yrest_db_pool_canceled_acquire_total", "Acquires canceled by context.", float64(st.CanceledAcquireCount()))
+}
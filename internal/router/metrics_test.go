@@ -0,0 +1,45 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandler_PrometheusText(t *testing.T) {
+	// запрос через withLogging попадает в счётчики
+	withLogging(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusNotFound)
+	})(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/show", nil))
+
+	w := httptest.NewRecorder()
+	metricsHandler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d, want %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`yrest_requests_total{endpoint="/api/show",model="",preset="",status="404"}`,
+		`yrest_http_errors_total{path="/api/show",status="404"} `,
+		"# TYPE yrest_request_duration_seconds histogram",
+		"# TYPE yrest_alias_cache_hits_total counter",
+		"yrest_alias_cache_bytes ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics output missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsHandler_MethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	metricsHandler(w, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status=%d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
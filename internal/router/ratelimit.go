@@ -0,0 +1,353 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/config"
+	"YrestAPI/internal/graphql"
+	"YrestAPI/internal/handler"
+	"YrestAPI/internal/logger"
+	"YrestAPI/internal/model"
+)
+
+// rateLimitIdleTTL — через сколько простоя состояние субъекта удаляется.
+const rateLimitIdleTTL = 5 * time.Minute
+
+// rateLimiter ограничивает частоту (token bucket) и число одновременных
+// запросов на субъекта: sub из claims (JWT или API-ключ), иначе IP клиента.
+// Общие лимиты берутся из env, лимиты модели — из rate_limit в её YAML;
+// запрос к модели должен пройти оба.
+type rateLimiter struct {
+	global     model.RateLimit
+	trustProxy bool
+	now        func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	inflight  map[string]int
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateScope — один проверяемый лимит: общий (key = субъект) или модели
+// (key = субъект + модель).
+type rateScope struct {
+	key   string
+	limit model.RateLimit
+}
+
+func newRateLimiter(cfg config.RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		global: model.RateLimit{
+			RPS:         cfg.RPS,
+			Burst:       int(cfg.Burst),
+			Concurrency: int(cfg.Concurrency),
+		},
+		trustProxy: cfg.TrustProxy,
+		now:        time.Now,
+		buckets:    map[string]*rateBucket{},
+		inflight:   map[string]int{},
+	}
+}
+
+// withRateLimit ставится после withAuth, чтобы субъект брался из claims.
+// При превышении лимита отвечает 429 с Retry-After в секундах. Модель для
+// её rate_limit берётся из поля "model" JSON-тела.
+func withRateLimit(l *rateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return rateLimitModels(l, peekModelName, next)
+}
+
+// withGraphQLRateLimit — withRateLimit для /graphql: лимиты моделей берутся
+// по корневым полям документа, а не по полю "model".
+func withGraphQLRateLimit(l *rateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return rateLimitModels(l, peekGraphQLModels, next)
+}
+
+// modelPeeker возвращает модели запроса для их лимитов; ошибка — только
+// тело больше maxPeekBodyBytes.
+type modelPeeker func(w http.ResponseWriter, r *http.Request) ([]string, error)
+
+func rateLimitModels(l *rateLimiter, peek modelPeeker, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		subject := l.subject(r)
+		var scopes []rateScope
+		if l.global.Enabled() {
+			scopes = append(scopes, rateScope{key: subject, limit: l.global})
+		}
+		// тело читается, только если хоть одной модели задан rate_limit
+		if model.HasRateLimits(model.CurrentRegistry()) {
+			names, err := peek(w, r)
+			if err != nil {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			for _, name := range names {
+				if m, ok := model.LookupModel(name); ok && m.RateLimit.Enabled() {
+					scopes = append(scopes, rateScope{key: subject + "|" + name, limit: *m.RateLimit})
+				}
+			}
+		}
+		if len(scopes) == 0 {
+			next(w, r)
+			return
+		}
+
+		release, retryAfter, ok := l.admit(scopes)
+		if !ok {
+			logger.Warn("rate_limited", map[string]any{
+				"path":        r.URL.Path,
+				"subject":     subject,
+				"retry_after": retryAfter,
+			})
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+		next(w, r)
+	}
+}
+
+// withAuthFailureLimit ставится перед withAuth: неудачная аутентификация
+// (401) списывает токен общего лимита RPS/Burst с IP клиента, а исчерпавший
+// его IP получает 429 ещё до проверки токена или ключа. withRateLimit за
+// withAuth считает уже по sub и неудачные попытки не видит.
+func withAuthFailureLimit(l *rateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil || l.global.RPS <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope := rateScope{key: "auth|" + l.clientIP(r), limit: model.RateLimit{RPS: l.global.RPS, Burst: l.global.Burst}}
+		if retryAfter, ok := l.hasToken(scope); !ok {
+			logger.Warn("auth_rate_limited", map[string]any{
+				"path":        r.URL.Path,
+				"subject":     scope.key,
+				"retry_after": retryAfter,
+			})
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next(sw, r)
+		if sw.status == http.StatusUnauthorized {
+			l.charge(scope)
+		}
+	}
+}
+
+// hasToken проверяет, что в ведре scope есть токен, не списывая его.
+func (l *rateLimiter) hasToken(s rateScope) (int, bool) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	if tokens := l.refill(s, now).tokens; tokens < 1 {
+		return max(int(math.Ceil((1-tokens)/s.limit.RPS)), 1), false
+	}
+	return 0, true
+}
+
+// charge списывает токен из ведра scope.
+func (l *rateLimiter) charge(s rateScope) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(s, now).tokens--
+}
+
+// admit атомарно проверяет все scopes и, если все пропускают запрос, списывает
+// токены и занимает слоты. Возвращает функцию освобождения слотов либо
+// Retry-After в секундах.
+func (l *rateLimiter) admit(scopes []rateScope) (func(), int, bool) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	retryAfter := 0
+	for _, s := range scopes {
+		if s.limit.Concurrency > 0 && l.inflight[s.key] >= s.limit.Concurrency {
+			retryAfter = max(retryAfter, 1)
+		}
+		if s.limit.RPS > 0 {
+			if tokens := l.refill(s, now).tokens; tokens < 1 {
+				wait := int(math.Ceil((1 - tokens) / s.limit.RPS))
+				retryAfter = max(retryAfter, wait, 1)
+			}
+		}
+	}
+	if retryAfter > 0 {
+		return nil, retryAfter, false
+	}
+
+	var held []string
+	for _, s := range scopes {
+		if s.limit.RPS > 0 {
+			l.buckets[s.key].tokens--
+		}
+		if s.limit.Concurrency > 0 {
+			l.inflight[s.key]++
+			held = append(held, s.key)
+		}
+	}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, key := range held {
+			if l.inflight[key]--; l.inflight[key] <= 0 {
+				delete(l.inflight, key)
+			}
+		}
+	}, 0, true
+}
+
+// refill пополняет ведро scope на время, прошедшее с прошлого запроса.
+// Параметры берутся из текущего лимита, так что перезагрузка YAML
+// применяется к уже существующим ведрам.
+func (l *rateLimiter) refill(s rateScope, now time.Time) *rateBucket {
+	burst := float64(s.limit.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, s.limit.RPS)
+	}
+	b := l.buckets[s.key]
+	if b == nil {
+		b = &rateBucket{tokens: burst, last: now}
+		l.buckets[s.key] = b
+		return b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*s.limit.RPS)
+		b.last = now
+	}
+	return b
+}
+
+// sweep раз в rateLimitIdleTTL удаляет ведра субъектов, которые давно не
+// обращались: к этому моменту они всё равно были бы полными.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitIdleTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= rateLimitIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// subject определяет, на кого считается лимит: sub из claims, иначе IP.
+// X-Forwarded-For учитывается только при RATE_LIMIT_TRUST_PROXY, иначе
+// клиент мог бы подставлять произвольный адрес.
+func (l *rateLimiter) subject(r *http.Request) string {
+	if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+		if sub, ok := claims["sub"]; ok && sub != nil {
+			if s := strings.TrimSpace(fmt.Sprint(sub)); s != "" {
+				return "sub:" + s
+			}
+		}
+	}
+	return l.clientIP(r)
+}
+
+// clientIP — субъект по адресу клиента, без учёта claims.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	if l.trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			if ip := strings.TrimSpace(strings.Split(fwd, ",")[0]); ip != "" {
+				return "ip:" + ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// maxPeekBodyBytes ограничивает тело, которое withRateLimit читает ради
+// поля "model". Тело больше лимита отклоняется целиком: иначе модельный
+// лимит обходился бы длинным телом с "model" в конце.
+const maxPeekBodyBytes = 1 << 20
+
+// peekBody читает тело POST-запроса и возвращает его на место для
+// обработчика. Ошибка — только если тело больше maxPeekBodyBytes.
+func peekBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPeekBodyBytes))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return body, nil
+}
+
+// peekModelName читает поле "model" из JSON-тела POST-запроса; невалидный
+// JSON оставляется обработчику.
+func peekModelName(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	body, err := peekBody(w, r)
+	if err != nil || body == nil {
+		return nil, err
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Model == "" {
+		return nil, nil
+	}
+	return []string{req.Model}, nil
+}
+
+// peekGraphQLModels возвращает модели корневых полей GraphQL-запроса (GET
+// ?query= или JSON-тело POST). Документ, который не разбирается, лимитов
+// моделей не получает: обработчик отклонит его без SQL.
+func peekGraphQLModels(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if raw := q.Get("variables"); raw != "" && json.Unmarshal([]byte(raw), &req.Variables) != nil {
+			return nil, nil
+		}
+	} else {
+		body, err := peekBody(w, r)
+		if err != nil || body == nil {
+			return nil, err
+		}
+		if json.Unmarshal(body, &req) != nil {
+			return nil, nil
+		}
+	}
+	if req.Query == "" {
+		return nil, nil
+	}
+	return handler.GraphQLRootModels(r, req), nil
+}
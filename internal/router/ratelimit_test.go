@@ -0,0 +1,229 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"YrestAPI/internal/auth"
+	"YrestAPI/internal/config"
+	"YrestAPI/internal/graphql"
+	"YrestAPI/internal/model"
+)
+
+func rateLimitRequest(h http.HandlerFunc, sub, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/index", strings.NewReader(body))
+	if sub != "" {
+		req = req.WithContext(auth.WithClaims(req.Context(), map[string]any{"sub": sub}))
+	}
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+func TestWithRateLimit_RPS(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newRateLimiter(config.RateLimitConfig{RPS: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+	h := withRateLimit(l, func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 2; i++ {
+		if w := rateLimitRequest(h, "alice", "{}"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: got %d", i, w.Code)
+		}
+	}
+	w := rateLimitRequest(h, "alice", "{}")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 429 with Retry-After 1, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := rateLimitRequest(h, "bob", "{}"); w.Code != http.StatusOK {
+		t.Fatalf("other subject must have its own bucket, got %d", w.Code)
+	}
+
+	now = now.Add(time.Second)
+	if w := rateLimitRequest(h, "alice", "{}"); w.Code != http.StatusOK {
+		t.Fatalf("token must refill after a second, got %d", w.Code)
+	}
+}
+
+func TestWithRateLimit_Concurrency(t *testing.T) {
+	l := newRateLimiter(config.RateLimitConfig{Concurrency: 1})
+	entered := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	h := withRateLimit(l, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-unblock
+	})
+
+	done := make(chan int)
+	go func() { done <- rateLimitRequest(h, "alice", "{}").Code }()
+	<-entered
+
+	if w := rateLimitRequest(h, "alice", "{}"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("second in-flight request: got %d", w.Code)
+	}
+	close(unblock)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("first request: got %d", code)
+	}
+	if w := rateLimitRequest(h, "alice", "{}"); w.Code != http.StatusOK {
+		t.Fatalf("slot must be released, got %d", w.Code)
+	}
+}
+
+func TestWithRateLimit_PerModel(t *testing.T) {
+	prev := model.Registry
+	defer func() { model.Registry = prev }()
+	model.Registry = map[string]*model.Model{
+		"Report": {Name: "Report", RateLimit: &model.RateLimit{RPS: 1}},
+		"Person": {Name: "Person"},
+	}
+
+	now := time.Unix(1700000000, 0)
+	l := newRateLimiter(config.RateLimitConfig{})
+	l.now = func() time.Time { return now }
+	var got string
+	h := withRateLimit(l, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+	})
+
+	body := `{"model":"Report","preset":"item"}`
+	if w := rateLimitRequest(h, "alice", body); w.Code != http.StatusOK || got != body {
+		t.Fatalf("first request: got %d, body %q", w.Code, got)
+	}
+	if w := rateLimitRequest(h, "alice", body); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("model limit must apply, got %d", w.Code)
+	}
+	if w := rateLimitRequest(h, "alice", `{"model":"Person"}`); w.Code != http.StatusOK {
+		t.Fatalf("model without rate_limit must pass, got %d", w.Code)
+	}
+}
+
+func TestRateLimiterSubject(t *testing.T) {
+	l := newRateLimiter(config.RateLimitConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/api/meta", nil)
+	req.RemoteAddr = "10.0.0.7:5123"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	if got := l.subject(req); got != "ip:10.0.0.7" {
+		t.Fatalf("untrusted proxy: got %q", got)
+	}
+	l.trustProxy = true
+	if got := l.subject(req); got != "ip:203.0.113.9" {
+		t.Fatalf("trusted proxy: got %q", got)
+	}
+	req = req.WithContext(auth.WithClaims(req.Context(), map[string]any{"sub": "apikey:batch"}))
+	if got := l.subject(req); got != "sub:apikey:batch" {
+		t.Fatalf("claims subject: got %q", got)
+	}
+}
+
+func TestWithRateLimit_BodyTooLarge(t *testing.T) {
+	prev := model.Registry
+	defer func() { model.Registry = prev }()
+	model.Registry = map[string]*model.Model{"Person": {Name: "Person"}}
+
+	l := newRateLimiter(config.RateLimitConfig{RPS: 100})
+	var got int
+	h := withRateLimit(l, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = len(body)
+	})
+
+	// без rate_limit у моделей тело не читается и не ограничивается
+	body := `{"filters":{"name__in":["` + strings.Repeat("x", maxPeekBodyBytes) + `"]},"model":"Report"}`
+	if w := rateLimitRequest(h, "alice", body); w.Code != http.StatusOK || got != len(body) {
+		t.Fatalf("large body without model limits must pass untouched, got %d (%d bytes)", w.Code, got)
+	}
+
+	model.Registry["Report"] = &model.Model{Name: "Report", RateLimit: &model.RateLimit{RPS: 1}}
+	got = -1
+	if w := rateLimitRequest(h, "alice", body); w.Code != http.StatusRequestEntityTooLarge || got != -1 {
+		t.Fatalf("oversized body must be rejected before the handler, got %d", w.Code)
+	}
+}
+
+// /graphql не несёт поля "model": лимит модели берётся по корневым полям.
+func TestWithGraphQLRateLimit_PerModel(t *testing.T) {
+	prev := model.CurrentRegistry()
+	defer model.SwapRegistry(prev)
+	item := map[string]*model.DataPreset{"item": {Name: "item", Fields: []model.Field{{Source: "id", Type: "int"}}}}
+	model.SwapRegistry(map[string]*model.Model{
+		"Report": {Name: "Report", Table: "reports", Presets: item, RateLimit: &model.RateLimit{RPS: 1}},
+		"Person": {Name: "Person", Table: "people", Presets: item},
+	})
+
+	now := time.Unix(1700000000, 0)
+	l := newRateLimiter(config.RateLimitConfig{})
+	l.now = func() time.Time { return now }
+	h := withGraphQLRateLimit(l, func(w http.ResponseWriter, r *http.Request) {})
+	send := func(root string) int {
+		body, _ := json.Marshal(map[string]string{"query": "{ " + root + " { id } }"})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+		req = req.WithContext(auth.WithClaims(req.Context(), map[string]any{"sub": "alice"}))
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w.Code
+	}
+
+	reports := graphql.RootFieldName("Report")
+	if code := send(reports); code != http.StatusOK {
+		t.Fatalf("first request: got %d", code)
+	}
+	if code := send(reports); code != http.StatusTooManyRequests {
+		t.Fatalf("model limit must apply to GraphQL roots, got %d", code)
+	}
+	if code := send(graphql.RootFieldName("Person")); code != http.StatusOK {
+		t.Fatalf("model without rate_limit must pass, got %d", code)
+	}
+}
+
+// Неудачная аутентификация лимитируется по IP ещё до withAuth: subject по
+// claims у таких запросов нет.
+func TestWithAuthFailureLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := newRateLimiter(config.RateLimitConfig{RPS: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+	h := withAuthFailureLimit(l, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "s3cret" {
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		}
+	})
+	send := func(key, addr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/index", nil)
+		req.RemoteAddr = addr
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		if code := send("s3cret", "10.0.0.7:5123"); code != http.StatusOK {
+			t.Fatalf("successful auth must not be charged, got %d", code)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if code := send("nope", "10.0.0.7:5123"); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d within burst: got %d", i, code)
+		}
+	}
+	if code := send("s3cret", "10.0.0.7:5123"); code != http.StatusTooManyRequests {
+		t.Fatalf("IP with exhausted auth failures must get 429, got %d", code)
+	}
+	if code := send("nope", "10.0.0.8:5123"); code != http.StatusUnauthorized {
+		t.Fatalf("other IP must have its own bucket, got %d", code)
+	}
+	now = now.Add(time.Second)
+	if code := send("s3cret", "10.0.0.7:5123"); code != http.StatusOK {
+		t.Fatalf("token must refill after a second, got %d", code)
+	}
+}
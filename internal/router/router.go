@@ -5,26 +5,36 @@ import (
 	"YrestAPI/internal/config"
 	"YrestAPI/internal/handler"
 	"YrestAPI/internal/logger"
+	"YrestAPI/internal/metrics"
+	"YrestAPI/internal/openapi"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // InitRoutes инициализирует маршруты для API
 func InitRoutes(cfg *config.Config) error {
-	var validator *auth.JWTValidator
-	var err error
-	if cfg.Auth.Enabled {
-		validator, err = auth.NewJWTValidator(cfg.Auth.JWT)
-		if err != nil {
-			return err
-		}
+	authn, err := newAuthenticators(cfg.Auth)
+	if err != nil {
+		return err
 	}
+	openAPIOpts := openapi.Options{}
+	if authn != nil {
+		openAPIOpts.BearerAuth = authn.jwt != nil
+		openAPIOpts.APIKeyAuth = authn.apiKeys != nil
+	}
+	limiter := newRateLimiter(cfg.RateLimit)
 
-	http.HandleFunc("/api/index", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuth(validator, handler.IndexHandler))))
-	http.HandleFunc("/api/stats", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuth(validator, handler.StatsHandler))))
-	http.HandleFunc("/api/count", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuth(validator, handler.CountHandler))))
+	http.HandleFunc("/api/index", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuthFailureLimit(limiter, withAuth(authn, withRateLimit(limiter, handler.IndexHandler))))))
+	http.HandleFunc("/api/show", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuthFailureLimit(limiter, withAuth(authn, withRateLimit(limiter, handler.ShowHandler))))))
+	http.HandleFunc("/api/stats", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuthFailureLimit(limiter, withAuth(authn, withRateLimit(limiter, handler.StatsHandler))))))
+	http.HandleFunc("/api/count", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuthFailureLimit(limiter, withAuth(authn, withRateLimit(limiter, handler.CountHandler))))))
+	http.HandleFunc("/graphql", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuthFailureLimit(limiter, withAuth(authn, withGraphQLRateLimit(limiter, handler.GraphQLHandler))))))
+	http.HandleFunc("/api/openapi.json", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuthFailureLimit(limiter, withAuth(authn, withRateLimit(limiter, handler.NewOpenAPIHandler(openAPIOpts)))))))
+	http.HandleFunc("/api/meta", withCORS(cfg.CORS.AllowOrigin, cfg.CORS.AllowCredentials, withLogging(withAuthFailureLimit(limiter, withAuth(authn, withRateLimit(limiter, handler.MetaHandler))))))
 	http.HandleFunc("/healthz", withLogging(healthzHandler))
 	http.HandleFunc("/readyz", withLogging(readyzHandler))
+	http.HandleFunc("/metrics", withLogging(metricsHandler))
 	http.HandleFunc("/debug/logs", withLogging(withDebugToken(cfg.Debug.LogsToken, logsHandler)))
 	// Добавьте другие обработчики по мере необходимости
 	return nil
@@ -40,10 +50,20 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Flush keeps streaming responses (NDJSON) working through the logging wrapper.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func withLogging(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
-		next(sw, r)
+		started := time.Now()
+		ctx, labels := metrics.WithRequest(r.Context())
+		next(sw, r.WithContext(ctx))
+		metrics.ObserveRequest(r.URL.Path, labels, sw.status, time.Since(started))
 		level := "info"
 		if sw.status >= 500 {
 			level = "error"
@@ -66,12 +86,75 @@ func withLogging(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func withAuth(validator *auth.JWTValidator, next http.HandlerFunc) http.HandlerFunc {
-	if validator == nil {
+// authenticators — включённые способы аутентификации: JWT (AUTH_ENABLED)
+// и/или API-ключи (AUTH_API_KEYS_PATH / AUTH_API_KEYS_TABLE).
+type authenticators struct {
+	jwt     *auth.JWTValidator
+	apiKeys *auth.APIKeyStore
+}
+
+// newAuthenticators возвращает nil, если ни один способ не включён.
+func newAuthenticators(cfg config.AuthConfig) (*authenticators, error) {
+	a := &authenticators{}
+	if cfg.Enabled {
+		validator, err := auth.NewJWTValidator(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		if interval := time.Duration(cfg.JWT.JWKSRefreshSec) * time.Second; interval > 0 {
+			go validator.WatchKeys(interval, nil)
+		}
+		a.jwt = validator
+	}
+	if cfg.APIKeys.Path != "" || cfg.APIKeys.Table != "" {
+		store, err := auth.NewAPIKeyStore(cfg.APIKeys.Path, cfg.APIKeys.Table)
+		if err != nil {
+			return nil, err
+		}
+		if interval := time.Duration(cfg.APIKeys.RefreshSec) * time.Second; interval > 0 {
+			go store.Watch(interval, nil)
+		}
+		a.apiKeys = store
+	}
+	if a.jwt == nil && a.apiKeys == nil {
+		return nil, nil
+	}
+	return a, nil
+}
+
+func withAuth(a *authenticators, next http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
 		return next
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
+		apiKey := strings.TrimSpace(r.Header.Get("X-API-Key"))
+		if apiKey == "" && strings.HasPrefix(strings.ToLower(authHeader), "apikey ") {
+			apiKey = strings.TrimSpace(authHeader[len("ApiKey "):])
+		}
+		if apiKey != "" {
+			if a.apiKeys == nil {
+				http.Error(w, "API key authentication is not enabled", http.StatusUnauthorized)
+				return
+			}
+			claims, err := a.apiKeys.Authenticate(apiKey)
+			if err != nil {
+				logger.Warn("auth_failed", map[string]any{
+					"path":   r.URL.Path,
+					"method": "api_key",
+					"error":  err.Error(),
+				})
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			next(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+			return
+		}
+		if a.jwt == nil {
+			http.Error(w, "X-API-Key header is required", http.StatusUnauthorized)
+			return
+		}
+
 		if authHeader == "" {
 			http.Error(w, "Authorization header is required", http.StatusUnauthorized)
 			return
@@ -82,7 +165,7 @@ func withAuth(validator *auth.JWTValidator, next http.HandlerFunc) http.HandlerF
 		}
 
 		token := strings.TrimSpace(authHeader[len("Bearer "):])
-		claims, err := validator.ValidateToken(token)
+		claims, err := a.jwt.ValidateToken(token)
 		if err != nil {
 			logger.Warn("auth_failed", map[string]any{
 				"path":  r.URL.Path,